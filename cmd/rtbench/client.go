@@ -0,0 +1,231 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	wsbus "superaib/pkg/websocket"
+
+	"github.com/gorilla/websocket"
+)
+
+// benchClient: Hal WebSocket oo la simulate gareeyay (SDK-ga v1 JSON protocol-ka ayuu ku hadlaa)
+type benchClient struct {
+	id       int
+	run      *bench
+	conn     *websocket.Conn
+	channels []string
+
+	writeMu sync.Mutex
+	closed  atomic.Bool
+}
+
+// serverFrame: Qaybaha frame-ka server-ka ee bench-ku u baahan yahay (reply iyo event)
+type serverFrame struct {
+	Type      string          `json:"type"`
+	Ref       string          `json:"ref"`
+	Status    string          `json:"status"`
+	Channel   string          `json:"channel"`
+	EventType string          `json:"event_type"`
+	Payload   json.RawMessage `json:"payload"`
+}
+
+// benchPayload: Event-ka la daabaco; run-ka waxaa lagu kala saaraa events-kii orod hore (replay)
+type benchPayload struct {
+	Run    string `json:"run"`
+	SentAt int64  `json:"sent_at"` // UnixNano (isla mashiinka, sidaas darteed clock skew ma jiro)
+	Pad    string `json:"pad,omitempty"`
+}
+
+type replyError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// pendingRef: Frame la diray oo reply sugaya
+type pendingRef struct {
+	sentAt  time.Time
+	channel string
+	publish bool
+	done    chan error // subscribe kaliya
+}
+
+func dialClient(run *bench, id int) (*benchClient, error) {
+	dialer := websocket.Dialer{
+		HandshakeTimeout: run.cfg.dialTimeout,
+		Subprotocols:     []string{wsbus.SubprotocolJSON},
+	}
+	conn, resp, err := dialer.Dial(run.wsURL, nil)
+	if err != nil {
+		if resp != nil {
+			return nil, fmt.Errorf("handshake rejected with HTTP %d", resp.StatusCode)
+		}
+		return nil, err
+	}
+	if resp != nil && resp.StatusCode != http.StatusSwitchingProtocols {
+		conn.Close()
+		return nil, fmt.Errorf("unexpected handshake status %d", resp.StatusCode)
+	}
+	c := &benchClient{id: id, run: run, conn: conn}
+	go c.readLoop()
+	return c, nil
+}
+
+func (c *benchClient) write(frame map[string]interface{}) error {
+	data, err := json.Marshal(frame)
+	if err != nil {
+		return err
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(c.run.cfg.writeTimeout))
+	return c.conn.WriteMessage(websocket.TextMessage, data)
+}
+
+// subscribe: SUBSCRIBE + sug reply-ga (publish-ka ma bilaabmo inta subscribers-ku diyaar yihiin)
+func (c *benchClient) subscribe(channel string) error {
+	ref := c.run.nextRef()
+	done := make(chan error, 1)
+	c.run.pending.Store(ref, &pendingRef{sentAt: time.Now(), done: done})
+
+	err := c.write(map[string]interface{}{
+		"v":       wsbus.ProtocolVersion,
+		"ref":     ref,
+		"action":  "SUBSCRIBE",
+		"channel": channel,
+	})
+	if err != nil {
+		c.run.pending.Delete(ref)
+		return err
+	}
+
+	select {
+	case err := <-done:
+		if err == nil {
+			c.channels = append(c.channels, channel)
+		}
+		return err
+	case <-time.After(c.run.cfg.replyTimeout):
+		c.run.pending.Delete(ref)
+		return fmt.Errorf("subscribe %s: no reply within %s", channel, c.run.cfg.replyTimeout)
+	}
+}
+
+// publish: BROADCAST (reply-ga wuxuu cabiraa publish latency = DB write + fan-out)
+func (c *benchClient) publish(channel string) {
+	ref := c.run.nextRef()
+	now := time.Now()
+	c.run.pending.Store(ref, &pendingRef{sentAt: now, channel: channel, publish: true})
+
+	err := c.write(map[string]interface{}{
+		"v":       wsbus.ProtocolVersion,
+		"ref":     ref,
+		"action":  "BROADCAST",
+		"channel": channel,
+		"event":   c.run.cfg.eventType,
+		"payload": benchPayload{Run: c.run.id, SentAt: now.UnixNano(), Pad: c.run.pad},
+	})
+	if err != nil {
+		c.run.pending.Delete(ref)
+		c.run.counters.writeErrors.Add(1)
+		return
+	}
+	c.run.counters.published.Add(1)
+}
+
+func (c *benchClient) readLoop() {
+	defer c.markClosed()
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		c.run.counters.framesIn.Add(1)
+
+		var frame serverFrame
+		if err := json.Unmarshal(data, &frame); err != nil {
+			continue
+		}
+		if frame.Type == "reply" {
+			c.handleReply(&frame)
+			continue
+		}
+		c.handleEvent(&frame)
+	}
+}
+
+func (c *benchClient) handleReply(frame *serverFrame) {
+	v, ok := c.run.pending.LoadAndDelete(frame.Ref)
+	if !ok {
+		return
+	}
+	p := v.(*pendingRef)
+
+	var failure error
+	if frame.Status != wsbus.ReplyOK {
+		var re replyError
+		_ = json.Unmarshal(frame.Payload, &re)
+		if re.Code == "" {
+			re.Code = "unknown"
+		}
+		failure = fmt.Errorf("%s: %s", re.Code, re.Message)
+		c.run.counters.replyError(re.Code)
+	}
+
+	if p.done != nil {
+		p.done <- failure
+		return
+	}
+	if p.publish {
+		if failure != nil {
+			c.run.counters.publishRejected.Add(1)
+			return
+		}
+		c.run.publishLatency.record(time.Since(p.sentAt))
+		c.run.counters.publishAccepted.Add(1)
+		c.run.counters.expected.Add(int64(c.run.subscribersOf(p.channel)))
+	}
+}
+
+func (c *benchClient) handleEvent(frame *serverFrame) {
+	switch frame.EventType {
+	case c.run.cfg.eventType:
+		var p benchPayload
+		if json.Unmarshal(frame.Payload, &p) != nil || p.Run != c.run.id {
+			return // orod hore (replay) ama client kale
+		}
+		c.run.deliveryLatency.record(time.Since(time.Unix(0, p.SentAt)))
+		c.run.counters.delivered.Add(1)
+	case "error":
+		var re replyError
+		_ = json.Unmarshal(frame.Payload, &re)
+		c.run.counters.replyError(re.Code)
+	case "reconnect", "disconnect":
+		c.run.counters.serverCloses.Add(1)
+	}
+}
+
+func (c *benchClient) markClosed() {
+	if c.closed.CompareAndSwap(false, true) && !c.run.stopping.Load() {
+		c.run.counters.disconnects.Add(1)
+	}
+}
+
+func (c *benchClient) close() {
+	c.writeMu.Lock()
+	_ = c.conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, "bench finished"),
+		time.Now().Add(time.Second))
+	c.writeMu.Unlock()
+	c.conn.Close()
+}
+
+// refs-ka waa string (protocol-ka), tirada run-ka oo dhan waa unique
+func (b *bench) nextRef() string {
+	return strconv.FormatInt(b.refSeq.Add(1), 36)
+}
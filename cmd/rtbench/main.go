@@ -0,0 +1,369 @@
+// rtbench: Load test-ka Realtime-ka (RealtimeHandler + DB write-ka event kasta).
+//
+// Wuxuu furaa N WebSocket clients oo la simulate gareeyay, wuxuu ku qaybiyaa M channels,
+// wuxuu daabacaa BROADCAST frames rate go'an, kadibna wuxuu soo warramaa:
+//   - publish latency: BROADCAST → reply ok (DB insert + fan-out)
+//   - delivery latency: BROADCAST → event-ka subscriber-ka gaaray
+//   - drops: deliveries la filayay (subscribers × publishes la aqbalay) − kuwa yimid
+//
+// Offline ayuu gebi ahaanba u shaqeeyaa (local instance):
+//
+//	go run ./cmd/rtbench -url http://localhost:8080 -project <id> -api-key <key> \
+//	    -clients 500 -channels 10 -rate 200 -duration 30s
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+type benchConfig struct {
+	baseURL            string
+	projectID          string
+	apiKey             string
+	accessToken        string
+	dashboardToken     string
+	clients            int
+	channels           int
+	subsPerClient      int
+	rate               float64
+	duration           time.Duration
+	drain              time.Duration
+	payloadBytes       int
+	channelPrefix      string
+	eventType          string
+	connectConcurrency int
+	dialTimeout        time.Duration
+	replyTimeout       time.Duration
+	writeTimeout       time.Duration
+	maxSamples         int
+	jsonOutput         bool
+}
+
+// benchCounters: Dhammaan counters-ka waa atomic (read loops badan ayaa wada qora)
+type benchCounters struct {
+	connectFailures atomic.Int64
+	subscribeFails  atomic.Int64
+	published       atomic.Int64 // frames la qoray
+	writeErrors     atomic.Int64
+	skipped         atomic.Int64 // rate-ka lama gaarin (publisher-ku wuu dib u dhacay)
+	publishAccepted atomic.Int64
+	publishRejected atomic.Int64
+	expected        atomic.Int64
+	delivered       atomic.Int64
+	framesIn        atomic.Int64
+	disconnects     atomic.Int64
+	serverCloses    atomic.Int64
+
+	errMu  sync.Mutex
+	errors map[string]int64
+}
+
+func (c *benchCounters) replyError(code string) {
+	c.errMu.Lock()
+	c.errors[code]++
+	c.errMu.Unlock()
+}
+
+type bench struct {
+	cfg   benchConfig
+	id    string
+	wsURL string
+	pad   string
+
+	clients   []*benchClient
+	byChannel map[string][]*benchClient // channel → clients-ka subscribe gareeyay (read-only publish-ka inta uu socdo)
+
+	pending  sync.Map // ref → *pendingRef
+	refSeq   atomic.Int64
+	stopping atomic.Bool
+
+	counters        benchCounters
+	publishLatency  *latencyRecorder
+	deliveryLatency *latencyRecorder
+}
+
+func main() {
+	cfg := parseFlags()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	b, err := newBench(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		os.Exit(2)
+	}
+	if err := b.run(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func parseFlags() benchConfig {
+	var cfg benchConfig
+	flag.StringVar(&cfg.baseURL, "url", "http://localhost:8080", "server base URL (http/https or ws/wss)")
+	flag.StringVar(&cfg.projectID, "project", "", "project ID or reference (required)")
+	flag.StringVar(&cfg.apiKey, "api-key", "", "project API key (required)")
+	flag.StringVar(&cfg.accessToken, "access-token", "", "optional auth-user JWT sent as access_token")
+	flag.StringVar(&cfg.dashboardToken, "dashboard-token", "", "optional project owner (dashboard) JWT; enables the server metrics snapshot")
+	flag.IntVar(&cfg.clients, "clients", 100, "number of simulated WebSocket clients (N)")
+	flag.IntVar(&cfg.channels, "channels", 10, "number of channels (M); clients are spread round-robin")
+	flag.IntVar(&cfg.subsPerClient, "subs-per-client", 1, "channels each client subscribes to")
+	flag.Float64Var(&cfg.rate, "rate", 50, "target publishes per second across all clients")
+	flag.DurationVar(&cfg.duration, "duration", 30*time.Second, "publishing duration")
+	flag.DurationVar(&cfg.drain, "drain", 5*time.Second, "time to wait for in-flight deliveries after publishing stops")
+	flag.IntVar(&cfg.payloadBytes, "payload", 128, "approximate payload size in bytes")
+	flag.StringVar(&cfg.channelPrefix, "channel-prefix", "rtbench", "channel name prefix (channels are <prefix>-<i>)")
+	flag.StringVar(&cfg.eventType, "event", "rtbench", "event type used for published frames")
+	flag.IntVar(&cfg.connectConcurrency, "connect-concurrency", 50, "parallel WebSocket handshakes during ramp-up")
+	flag.DurationVar(&cfg.dialTimeout, "dial-timeout", 10*time.Second, "WebSocket handshake timeout")
+	flag.DurationVar(&cfg.replyTimeout, "reply-timeout", 10*time.Second, "SUBSCRIBE reply timeout")
+	flag.DurationVar(&cfg.writeTimeout, "write-timeout", 5*time.Second, "per-frame write deadline")
+	flag.IntVar(&cfg.maxSamples, "max-samples", 1_000_000, "latency samples kept per histogram (reservoir sampled beyond)")
+	flag.BoolVar(&cfg.jsonOutput, "json", false, "print the report as JSON")
+	flag.Parse()
+	return cfg
+}
+
+func newBench(cfg benchConfig) (*bench, error) {
+	switch {
+	case cfg.projectID == "" || cfg.apiKey == "":
+		return nil, errors.New("-project and -api-key are required")
+	case cfg.clients < 1 || cfg.channels < 1:
+		return nil, errors.New("-clients and -channels must be at least 1")
+	case cfg.rate <= 0:
+		return nil, errors.New("-rate must be positive")
+	case cfg.connectConcurrency < 1:
+		cfg.connectConcurrency = 1
+	}
+	if cfg.subsPerClient < 1 {
+		cfg.subsPerClient = 1
+	}
+	if cfg.subsPerClient > cfg.channels {
+		cfg.subsPerClient = cfg.channels
+	}
+
+	wsURL, err := buildWSURL(cfg)
+	if err != nil {
+		return nil, err
+	}
+	idBytes := make([]byte, 6)
+	_, _ = rand.Read(idBytes)
+
+	return &bench{
+		cfg:             cfg,
+		id:              hex.EncodeToString(idBytes),
+		wsURL:           wsURL,
+		pad:             strings.Repeat("x", max(cfg.payloadBytes-64, 0)), // ~64 bytes waa run + sent_at
+		byChannel:       make(map[string][]*benchClient),
+		counters:        benchCounters{errors: make(map[string]int64)},
+		publishLatency:  newLatencyRecorder(cfg.maxSamples),
+		deliveryLatency: newLatencyRecorder(cfg.maxSamples),
+	}, nil
+}
+
+// buildWSURL: /api/v1/ws/{project_id}?api_key=...[&access_token=...]
+func buildWSURL(cfg benchConfig) (string, error) {
+	u, err := url.Parse(strings.TrimRight(cfg.baseURL, "/"))
+	if err != nil {
+		return "", fmt.Errorf("invalid -url: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "ws":
+		u.Scheme = "ws"
+	case "https", "wss":
+		u.Scheme = "wss"
+	default:
+		return "", fmt.Errorf("unsupported -url scheme %q", u.Scheme)
+	}
+	u.Path += "/api/v1/ws/" + url.PathEscape(cfg.projectID)
+	q := url.Values{"api_key": {cfg.apiKey}}
+	if cfg.accessToken != "" {
+		q.Set("access_token", cfg.accessToken)
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+func (b *bench) channelName(i int) string {
+	return fmt.Sprintf("%s-%d", b.cfg.channelPrefix, i%b.cfg.channels)
+}
+
+func (b *bench) subscribersOf(channel string) int {
+	return len(b.byChannel[channel])
+}
+
+func (b *bench) run(ctx context.Context) error {
+	fmt.Fprintf(os.Stderr, "🚀 [rtbench] run %s: %d clients × %d channel(s), %.0f msg/s for %s\n",
+		b.id, b.cfg.clients, b.cfg.channels, b.cfg.rate, b.cfg.duration)
+
+	rampStart := time.Now()
+	b.connectAll(ctx)
+	ramp := time.Since(rampStart)
+	if len(b.clients) == 0 {
+		return fmt.Errorf("no client could connect to %s (%d failures)", b.cfg.baseURL, b.counters.connectFailures.Load())
+	}
+	fmt.Fprintf(os.Stderr, "🔌 [rtbench] %d/%d clients ready in %s\n", len(b.clients), b.cfg.clients, ramp.Round(time.Millisecond))
+
+	var targets []string
+	for name := range b.byChannel {
+		targets = append(targets, name)
+	}
+	sort.Strings(targets)
+	if len(targets) == 0 {
+		return errors.New("no subscription succeeded; nothing to publish to")
+	}
+
+	publishStart := time.Now()
+	b.publishLoop(ctx, targets)
+	publishElapsed := time.Since(publishStart)
+
+	// In-flight deliveries: sug ilaa wax walba yimaadaan ama drain-ku dhammaado
+	deadline := time.Now().Add(b.cfg.drain)
+	for time.Now().Before(deadline) && b.counters.delivered.Load() < b.counters.expected.Load() && ctx.Err() == nil {
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	serverMetrics := b.fetchServerMetrics()
+
+	b.stopping.Store(true)
+	for _, c := range b.clients {
+		c.close()
+	}
+
+	b.report(ramp, publishElapsed, serverMetrics)
+	return nil
+}
+
+// connectAll: Ramp-up (connect-concurrency handshakes isla mar) + SUBSCRIBE
+func (b *bench) connectAll(ctx context.Context) {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, b.cfg.connectConcurrency)
+
+	for i := 0; i < b.cfg.clients && ctx.Err() == nil; i++ {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			c, err := dialClient(b, i)
+			if err != nil {
+				if b.counters.connectFailures.Add(1) == 1 {
+					fmt.Fprintf(os.Stderr, "⚠️ [rtbench] connect failed: %v\n", err)
+				}
+				return
+			}
+			for s := 0; s < b.cfg.subsPerClient; s++ {
+				if err := c.subscribe(b.channelName(i + s)); err != nil {
+					if b.counters.subscribeFails.Add(1) == 1 {
+						fmt.Fprintf(os.Stderr, "⚠️ [rtbench] subscribe failed: %v\n", err)
+					}
+				}
+			}
+
+			mu.Lock()
+			b.clients = append(b.clients, c)
+			for _, name := range c.channels {
+				b.byChannel[name] = append(b.byChannel[name], c)
+			}
+			mu.Unlock()
+		}(i)
+	}
+	wg.Wait()
+}
+
+// publishLoop: Ticker 10ms ah; tick kasta wuxuu diraa inta rate-ku ogol yahay (fractional credit),
+// channels iyo publishers-ka round-robin. Haddii qoristu dib u dhacdo, credit-ka dheeraadka ah waa "skipped".
+func (b *bench) publishLoop(ctx context.Context, targets []string) {
+	const tick = 10 * time.Millisecond
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	timer := time.NewTimer(b.cfg.duration)
+	defer timer.Stop()
+
+	var credit float64
+	var seq int
+	last := time.Now()
+	inflight := make(chan struct{}, 256)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			return
+		case now := <-ticker.C:
+			credit += b.cfg.rate * now.Sub(last).Seconds()
+			last = now
+			for ; credit >= 1; credit-- {
+				channel := targets[seq%len(targets)]
+				subs := b.byChannel[channel]
+				publisher := subs[(seq/len(targets))%len(subs)]
+				seq++
+
+				select {
+				case inflight <- struct{}{}:
+					go func() {
+						defer func() { <-inflight }()
+						publisher.publish(channel)
+					}()
+				default:
+					b.counters.skipped.Add(1)
+				}
+			}
+		}
+	}
+}
+
+// fetchServerMetrics: GET /realtime/admin/metrics (node-ka bench-ku ku xiran yahay kaliya)
+// Route-ku wuxuu u baahan yahay JWT-ga owner-ka project-ka; -dashboard-token la'aan waa la dhaafaa
+func (b *bench) fetchServerMetrics() map[string]interface{} {
+	if b.cfg.dashboardToken == "" {
+		return nil
+	}
+	base := strings.TrimRight(b.cfg.baseURL, "/")
+	base = strings.Replace(strings.Replace(base, "wss://", "https://", 1), "ws://", "http://", 1)
+	req, err := http.NewRequest(http.MethodGet, base+"/api/v1/projects/"+url.PathEscape(b.cfg.projectID)+"/realtime/admin/metrics", nil)
+	if err != nil {
+		return nil
+	}
+	req.Header.Set("Authorization", "Bearer "+b.cfg.dashboardToken)
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil
+	}
+	var body struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if json.Unmarshal(raw, &body) != nil {
+		return nil
+	}
+	return body.Data
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// benchReport: Natiijada orodka (-json ama qoraal)
+type benchReport struct {
+	RunID           string                 `json:"run_id"`
+	Clients         int                    `json:"clients_connected"`
+	ConnectFailures int64                  `json:"connect_failures"`
+	SubscribeFails  int64                  `json:"subscribe_failures"`
+	Channels        int                    `json:"channels"`
+	RampUpSeconds   float64                `json:"ramp_up_seconds"`
+	PublishSeconds  float64                `json:"publish_seconds"`
+	TargetRate      float64                `json:"target_rate"`
+	AchievedRate    float64                `json:"achieved_rate"`
+	Published       int64                  `json:"published"`
+	Accepted        int64                  `json:"publish_accepted"`
+	Rejected        int64                  `json:"publish_rejected"`
+	Unanswered      int64                  `json:"publish_unanswered"`
+	WriteErrors     int64                  `json:"write_errors"`
+	Skipped         int64                  `json:"skipped"`
+	Expected        int64                  `json:"deliveries_expected"`
+	Delivered       int64                  `json:"deliveries_received"`
+	Dropped         int64                  `json:"deliveries_dropped"`
+	DropRate        float64                `json:"drop_rate"`
+	Disconnects     int64                  `json:"unexpected_disconnects"`
+	ServerCloses    int64                  `json:"server_close_frames"`
+	Errors          map[string]int64       `json:"errors_by_code"`
+	PublishLatency  latencySummary         `json:"publish_latency"`
+	DeliveryLatency latencySummary         `json:"delivery_latency"`
+	ServerMetrics   map[string]interface{} `json:"server_metrics,omitempty"`
+}
+
+func (b *bench) report(ramp, publishElapsed time.Duration, serverMetrics map[string]interface{}) {
+	c := &b.counters
+	r := benchReport{
+		RunID:           b.id,
+		Clients:         len(b.clients),
+		ConnectFailures: c.connectFailures.Load(),
+		SubscribeFails:  c.subscribeFails.Load(),
+		Channels:        len(b.byChannel),
+		RampUpSeconds:   ramp.Seconds(),
+		PublishSeconds:  publishElapsed.Seconds(),
+		TargetRate:      b.cfg.rate,
+		Published:       c.published.Load(),
+		Accepted:        c.publishAccepted.Load(),
+		Rejected:        c.publishRejected.Load(),
+		WriteErrors:     c.writeErrors.Load(),
+		Skipped:         c.skipped.Load(),
+		Expected:        c.expected.Load(),
+		Delivered:       c.delivered.Load(),
+		Disconnects:     c.disconnects.Load(),
+		ServerCloses:    c.serverCloses.Load(),
+		PublishLatency:  b.publishLatency.summary(),
+		DeliveryLatency: b.deliveryLatency.summary(),
+		ServerMetrics:   serverMetrics,
+	}
+	r.Unanswered = r.Published - r.Accepted - r.Rejected
+	if publishElapsed > 0 {
+		r.AchievedRate = float64(r.Accepted) / publishElapsed.Seconds()
+	}
+	if r.Expected > r.Delivered {
+		r.Dropped = r.Expected - r.Delivered
+	}
+	if r.Expected > 0 {
+		r.DropRate = float64(r.Dropped) / float64(r.Expected)
+	}
+	c.errMu.Lock()
+	r.Errors = make(map[string]int64, len(c.errors))
+	for code, n := range c.errors {
+		r.Errors[code] = n
+	}
+	c.errMu.Unlock()
+
+	if b.cfg.jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(r)
+		return
+	}
+	printReport(&r)
+}
+
+func printReport(r *benchReport) {
+	fmt.Printf("\n📊 rtbench run %s\n", r.RunID)
+	fmt.Printf("  clients      %d connected (%d connect failures, %d subscribe failures), %d channels, ramp-up %.2fs\n",
+		r.Clients, r.ConnectFailures, r.SubscribeFails, r.Channels, r.RampUpSeconds)
+	fmt.Printf("  publish      %d sent, %d accepted, %d rejected, %d unanswered, %d write errors, %d skipped\n",
+		r.Published, r.Accepted, r.Rejected, r.Unanswered, r.WriteErrors, r.Skipped)
+	fmt.Printf("  rate         target %.1f/s, achieved %.1f/s over %.2fs\n", r.TargetRate, r.AchievedRate, r.PublishSeconds)
+	fmt.Printf("  delivery     %d expected, %d received, %d dropped (%.3f%%)\n",
+		r.Expected, r.Delivered, r.Dropped, r.DropRate*100)
+	fmt.Printf("  connections  %d unexpected disconnects, %d server close frames\n", r.Disconnects, r.ServerCloses)
+
+	fmt.Printf("\n  %-18s %9s %9s %9s %9s %9s %9s %9s\n", "latency (ms)", "count", "p50", "p90", "p99", "p99.9", "max", "mean")
+	for _, row := range []struct {
+		name string
+		s    latencySummary
+	}{
+		{"publish->reply", r.PublishLatency},
+		{"publish->deliver", r.DeliveryLatency},
+	} {
+		fmt.Printf("  %-18s %9d %9.2f %9.2f %9.2f %9.2f %9.2f %9.2f\n",
+			row.name, row.s.Count, row.s.P50, row.s.P90, row.s.P99, row.s.P999, row.s.Max, row.s.Mean)
+	}
+
+	if len(r.Errors) > 0 {
+		codes := make([]string, 0, len(r.Errors))
+		for code := range r.Errors {
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+		fmt.Printf("\n  errors\n")
+		for _, code := range codes {
+			fmt.Printf("    %-24s %d\n", code, r.Errors[code])
+		}
+	}
+
+	if r.ServerMetrics != nil {
+		fmt.Printf("\n  server (node %v)\n", r.ServerMetrics["node_id"])
+		for _, key := range []string{"connections", "totals", "send_queue", "rates"} {
+			if v, ok := r.ServerMetrics[key]; ok {
+				data, _ := json.Marshal(v)
+				fmt.Printf("    %-12s %s\n", key, data)
+			}
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"math/rand/v2"
+	"sort"
+	"sync"
+	"time"
+)
+
+// latencyRecorder: Samples-ka (microseconds) waa la keydiyaa si percentiles sax ah loo xisaabiyo dhammaadka.
+// maxSamples kadib reservoir sampling ayaa la isticmaalaa si xusuusta aysan u koran.
+type latencyRecorder struct {
+	mu      sync.Mutex
+	samples []int64
+	seen    int64
+	max     int
+}
+
+func newLatencyRecorder(maxSamples int) *latencyRecorder {
+	return &latencyRecorder{max: maxSamples}
+}
+
+func (r *latencyRecorder) record(d time.Duration) {
+	us := d.Microseconds()
+	if us < 0 {
+		us = 0
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seen++
+	if len(r.samples) < r.max {
+		r.samples = append(r.samples, us)
+		return
+	}
+	// Reservoir (Algorithm R): sample kasta wuxuu leeyahay fursad siman
+	if i := rand.Int64N(r.seen); i < int64(r.max) {
+		r.samples[i] = us
+	}
+}
+
+// latencySummary: Warbixinta hal recorder
+type latencySummary struct {
+	Count int64   `json:"count"`
+	Min   float64 `json:"min_ms"`
+	Mean  float64 `json:"mean_ms"`
+	P50   float64 `json:"p50_ms"`
+	P90   float64 `json:"p90_ms"`
+	P99   float64 `json:"p99_ms"`
+	P999  float64 `json:"p999_ms"`
+	Max   float64 `json:"max_ms"`
+}
+
+func (r *latencyRecorder) summary() latencySummary {
+	r.mu.Lock()
+	sorted := append([]int64(nil), r.samples...)
+	seen := r.seen
+	r.mu.Unlock()
+
+	s := latencySummary{Count: seen}
+	if len(sorted) == 0 {
+		return s
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var total int64
+	for _, v := range sorted {
+		total += v
+	}
+	s.Min = toMillis(sorted[0])
+	s.Max = toMillis(sorted[len(sorted)-1])
+	s.Mean = toMillis(total / int64(len(sorted)))
+	s.P50 = toMillis(percentile(sorted, 0.50))
+	s.P90 = toMillis(percentile(sorted, 0.90))
+	s.P99 = toMillis(percentile(sorted, 0.99))
+	s.P999 = toMillis(percentile(sorted, 0.999))
+	return s
+}
+
+// percentile: Nearest-rank (sorted waa inuu horay u kala horreeyaa)
+func percentile(sorted []int64, q float64) int64 {
+	idx := int(q*float64(len(sorted))+0.5) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx]
+}
+
+func toMillis(us int64) float64 {
+	return float64(us) / 1000
+}
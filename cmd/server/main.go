@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"os" // 👈 KAN AYAAN KU DARNAY SI UU PORT-KA U AQRIYO
+	"os/signal"
 	"strings"
 	"superaib/internal/api/handlers"
 	"superaib/internal/api/middleware"
@@ -15,6 +16,10 @@ import (
 	"superaib/internal/services"
 	"superaib/internal/storage/postgres"
 	"superaib/internal/storage/repo"
+	"superaib/pkg/blobstore"
+	wsbus "superaib/pkg/websocket"
+	"syscall"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/rs/cors"
@@ -72,7 +77,12 @@ func main() {
 		&models.Document{},
 		&models.RealtimeChannel{},
 		&models.RealtimeEvent{},
+		&models.RealtimePresence{},
+		&models.RealtimeModeration{},
 		&models.StorageFile{},
+		&models.StorageUpload{},
+		&models.StorageBucket{},
+		&models.StorageBlob{},
 		&models.Analytics{},
 		&models.ProjectUsage{},
 		&models.GlobalFeature{},
@@ -87,6 +97,7 @@ func main() {
 		&models.DeviceToken{},
 		&models.Notification{},
 		&models.ProjectPushConfig{},
+		&models.CollectionMigration{},
 	); err != nil {
 		logger.Log.Fatalf("Failed to migrate models: %v", err)
 	}
@@ -96,6 +107,11 @@ func main() {
 	models.SeedGlobalFeatures(db.DB)
 	logger.Log.Info("✅ Global features seeded successfully.")
 
+	// Realtime limits-ka projects-kii hore (limit 0 = wax walba waa la diidi lahaa)
+	if err := repo.NewGormProjectUsageRepository(db.DB).BackfillRealtimeUsage(context.Background()); err != nil {
+		logger.Log.Fatalf("Failed to backfill realtime usage: %v", err)
+	}
+
 	// 3. Repositories
 	userRepo := repo.NewGormUserRepository(db.DB)
 	projectRepo := repo.NewGormProjectRepository(db.DB)
@@ -104,7 +120,12 @@ func main() {
 	documentRepo := repo.NewDocumentRepository(db.DB)
 	realtimeChannelRepo := repo.NewGormRealtimeChannelRepository(db.DB)
 	realtimeEventRepo := repo.NewGormRealtimeEventRepository(db.DB)
+	realtimePresenceRepo := repo.NewGormRealtimePresenceRepository(db.DB)
+	realtimeModerationRepo := repo.NewGormRealtimeModerationRepository(db.DB)
 	storageRepo := repo.NewGormStorageRepository(db.DB)
+	storageUploadRepo := repo.NewGormStorageUploadRepository(db.DB)
+	storageBucketRepo := repo.NewGormStorageBucketRepository(db.DB)
+	storageBlobRepo := repo.NewGormStorageBlobRepository(db.DB)
 	analyticsRepo := repo.NewGormAnalyticsRepository(db.DB)
 	usageRepo := repo.NewGormProjectUsageRepository(db.DB)
 	featureRepo := repo.NewGormProjectFeatureRepo(db.DB)
@@ -119,6 +140,7 @@ func main() {
 	transactionRepo := repo.NewTransactionRepository(db.DB)
 	noteRepo := repo.NewNotificationRepository(db.DB)
 	pushConfigRepo := repo.NewPushConfigRepository(db.DB)
+	migrationRepo := repo.NewCollectionMigrationRepository(db.DB)
 
 	// 4. Services
 	analyticsService := services.NewAnalyticsService(analyticsRepo)
@@ -129,11 +151,12 @@ func main() {
 	authProvService := services.NewGlobalAuthProviderService(authProvRepo)
 	otpTrackerService := services.NewOtpTrackerService(otpTrackerRepo, rateLimitRepo, authUserRepo)
 	documentService := services.NewDocumentService(documentRepo, analyticsTracker, usageService)
+	migrationService := services.NewCollectionMigrationService(migrationRepo, documentRepo, analyticsTracker)
 	projectService := services.NewProjectService(projectRepo, featureService, analyticsService, usageService, db.DB)
 	apiKeyService := services.NewAPIKeyService(apiKeyRepo, projectRepo, analyticsTracker, usageService)
 	authUserService := services.NewAuthUserService(authUserRepo, projectAuthConfigRepo, analyticsTracker, usageService, db.DB)
-	realtimeService := services.NewRealtimeService(realtimeChannelRepo, realtimeEventRepo, analyticsTracker, usageService)
-	storageService := services.NewStorageService(storageRepo, featureRepo, analyticsTracker, usageService)
+	realtimeService := services.NewRealtimeService(realtimeChannelRepo, realtimeEventRepo, authUserRepo, realtimePresenceRepo, realtimeModerationRepo, featureRepo, analyticsTracker, usageService)
+	storageService := services.NewStorageService(storageRepo, featureRepo, authUserRepo, storageUploadRepo, storageBucketRepo, storageBlobRepo, analyticsTracker, usageService, blobstore.Options{LocalRoot: cfg.StorageLocalDir}, cfg.StorageSigningSecret)
 	userService := services.NewUserService(userRepo)
 	authService := services.NewAuthService(userRepo, cfg)
 	projectAuthConfigService := services.NewProjectAuthConfigService(projectAuthConfigRepo)
@@ -142,11 +165,59 @@ func main() {
 	rateLimitService := services.NewRateLimitPolicyService(rateLimitRepo)
 	planService := services.NewPlanService(planRepo)
 	subscriptionService := services.NewSubscriptionService(db.DB, transactionRepo, projectRepo)
-	realtimeHandler := handlers.NewRealtimeHandler(realtimeService)
+	// 🌐 REALTIME BACKPLANE (memory = hal instance, postgres = replicas badan)
+	var backplane wsbus.Backplane
+	if cfg.RealtimeBackplane == "postgres" {
+		sqlDB, err := db.DB.DB()
+		if err != nil {
+			logger.Log.Fatalf("Failed to get sql.DB for backplane: %v", err)
+		}
+		backplane = wsbus.NewPostgresBackplane(cfg.DatabaseURL, sqlDB, logger.Log)
+	} else {
+		backplane = wsbus.NewMemoryBackplane(nil)
+	}
+	if err := backplane.Start(context.Background()); err != nil {
+		logger.Log.Fatalf("Failed to start realtime backplane: %v", err)
+	}
+	defer backplane.Close()
+	logger.Log.Infof("🌐 Realtime backplane '%s' started (node %s)", cfg.RealtimeBackplane, backplane.NodeID())
+
+	realtimeHandler := handlers.NewRealtimeHandler(realtimeService, backplane, handlers.RealtimeLimits{
+		MaxFrameBytes:    cfg.RealtimeMaxFrameBytes,
+		ConnRate:         cfg.RealtimeConnRate,
+		ConnBurst:        cfg.RealtimeConnBurst,
+		ProjectRate:      cfg.RealtimeProjectRate,
+		ProjectBurst:     cfg.RealtimeProjectBurst,
+		MaxSubscriptions: cfg.RealtimeMaxSubscriptions,
+		MaxViolations:    cfg.RealtimeMaxViolations,
+	})
 	noteService := services.NewNotificationService(noteRepo, pushConfigRepo, analyticsTracker, usageService, realtimeHandler)
 
+	// 🛑 SIGINT/SIGTERM: background workers-ka waxay istaagaan marka ctx-kan la joojiyo (graceful shutdown hoos)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// 🚀 KICI SCHEDULER-KA (Background Worker)
-	noteService.StartScheduler(context.Background())
+	noteService.StartScheduler(ctx)
+
+	// 💓 Presence heartbeats & eviction (sockets dhintay)
+	realtimeHandler.StartPresenceSweeper(ctx)
+
+	// 🗄️ Retention: pruning (ephemeral, max age, max events) + archiving idle channels
+	realtimeService.StartRetentionPruner(ctx, services.RetentionOptions{
+		IdleAfter: time.Duration(cfg.RealtimeArchiveIdleDays) * 24 * time.Hour,
+		ExportDir: cfg.RealtimeArchiveExportDir,
+	})
+
+	// ⏫ tus uploads: staging directory + tirtirista uploads-ka waqtigoodu dhacay
+	storageService.StartUploadSweeper(ctx, services.ResumableOptions{
+		Dir:     cfg.StorageUploadDir,
+		Expiry:  time.Duration(cfg.StorageUploadExpiryH) * time.Hour,
+		MaxSize: cfg.StorageMaxUploadMB << 20,
+	})
+
+	// 🔁 Sii wad data migrations-kii server-ku ka go'ay
+	migrationService.ResumeInterrupted(ctx)
 
 	passResetService := services.NewPasswordResetService(
 		passResetRepo,
@@ -162,6 +233,7 @@ func main() {
 	projectHandler := handlers.NewProjectHandler(projectService)
 	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService)
 	documentHandler := handlers.NewDocumentHandler(documentService)
+	migrationHandler := handlers.NewCollectionMigrationHandler(migrationService)
 	authUserHandler := handlers.NewAuthUserHandler(authUserService)
 	storageHandler := handlers.NewStorageHandler(storageService)
 	globalFeatureHandler := handlers.NewGlobalFeatureHandler(globalFeatureService)
@@ -181,12 +253,16 @@ func main() {
 	// 6. Middlewares
 	authMiddleware := middleware.NewAuthMiddleware(cfg)
 	apiKeyMiddleware := middleware.NewAPIKeyMiddleware(apiKeyService, projectService, usageService)
+	projectOwnerMiddleware := middleware.NewProjectOwnerMiddleware(projectService)
 
 	// 7. Router Setup
 	router := mux.NewRouter()
 
-	// 🚀 A. WEBSOCKET ROUTE (Ugu horreysii router-ka)
-	router.HandleFunc("/api/v1/ws/{project_id}", realtimeHandler.HandleWebSocket).Methods("GET")
+	// 🚀 A. WEBSOCKET ROUTE (Ugu horreysii router-ka) - API Key waa khasab (?api_key=...)
+	router.Handle("/api/v1/ws/{project_id}", apiKeyMiddleware.AuthenticateAPIKey(http.HandlerFunc(realtimeHandler.HandleWebSocket))).Methods("GET")
+
+	// 🔏 SIGNED FILE URLS - API Key looma baahna, saxiixa HMAC-ka ayaa ogolaansho ah
+	router.HandleFunc("/api/v1/storage/signed/{file_id}", storageHandler.ServeSignedFile).Methods("GET", "HEAD")
 
 	// 8. API v1 Subrouter
 	apiV1 := router.PathPrefix("/api/v1").Subrouter()
@@ -202,6 +278,7 @@ func main() {
 	routes.RealtimeRoutes(sdkRouter, realtimeHandler, apiKeyMiddleware.AuthenticateAPIKey)
 	routes.StorageRoutes(sdkRouter, storageHandler, apiKeyMiddleware.AuthenticateAPIKey)
 	routes.DocumentRoutes(sdkRouter, documentHandler, apiKeyMiddleware.AuthenticateAPIKey)
+	routes.CollectionMigrationRoutes(sdkRouter, migrationHandler, apiKeyMiddleware.AuthenticateAPIKey)
 	routes.PasswordResetRoutes(sdkRouter, passResetHandler, apiKeyMiddleware.AuthenticateAPIKey)
 	routes.RateLimitRoutes(sdkRouter, rateLimitHandler, apiKeyMiddleware.AuthenticateAPIKey)
 	routes.OtpTrackerRoutes(sdkRouter, otpTrackerHandler, apiKeyMiddleware.AuthenticateAPIKey)
@@ -223,16 +300,23 @@ func main() {
 	routes.PlanRoutes(dashRouter, planHandler, authMiddleware)
 	routes.SubscriptionRoutes(dashRouter, subscriptionHandler, authMiddleware)
 	routes.ProjectUsageRoutes(dashRouter, projectUsageHandler, authMiddleware)
+	routes.RealtimeAdminRoutes(dashRouter, realtimeHandler, authMiddleware, projectOwnerMiddleware)
 
 	logger.Log.Info("All routes registered successfully.")
 
 	// 9. CORS SETUP
 	c := cors.New(cors.Options{
 		// 🚨 AllowOriginFunc waxay si toos ah u fasaxaysaa cid kasta (Sida Flutter Web)
-		AllowOriginFunc:  func(origin string) bool { return true },
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
-		AllowedHeaders:   []string{"Accept", "Content-Type", "Content-Length", "Accept-Encoding", "X-CSRF-Token", "Authorization", "X-Requested-With", "x-api-key", "If-Match", "ETag"},
-		ExposedHeaders:   []string{"ETag", "If-Match"},
+		AllowOriginFunc: func(origin string) bool { return true },
+		AllowedMethods:  []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH", "HEAD"},
+		AllowedHeaders: []string{"Accept", "Content-Type", "Content-Length", "Accept-Encoding", "X-CSRF-Token", "Authorization", "X-Requested-With", "x-api-key", "If-Match", "ETag",
+			// tus resumable uploads
+			"Tus-Resumable", "Upload-Length", "Upload-Offset", "Upload-Metadata", "Upload-Defer-Length", "X-HTTP-Method-Override",
+			// upload integrity
+			"Content-MD5", "X-Checksum-Sha256"},
+		ExposedHeaders: []string{"ETag", "If-Match",
+			"Location", "Tus-Resumable", "Tus-Version", "Tus-Extension", "Tus-Max-Size", "Upload-Offset", "Upload-Length", "Upload-Metadata", "Upload-Expires", "X-File-Id",
+			"X-Image-Cache", "X-Checksum-Sha256"},
 		AllowCredentials: true,
 		Debug:            true, // Waxay ku tusi doontaa log-ga haddii CORS uu dhaco
 	})
@@ -252,7 +336,35 @@ func main() {
 		Handler: handler,
 	}
 
-	if err := server.ListenAndServe(); err != nil {
-		logger.Log.Fatalf("Server failed to start: %v", err)
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Log.Fatalf("Server failed to start: %v", err)
+		}
+	}()
+
+	// 11. Graceful shutdown (deploy): realtime drain → HTTP → background workers
+	<-ctx.Done()
+	stop()
+	logger.Log.Info("🛑 Shutdown signal received, draining...")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.ShutdownTimeoutSeconds)*time.Second)
+	defer cancel()
+
+	// SSE/long-poll waa requests furan; waa in la xiraa ka hor server.Shutdown (kaas oo sugaya)
+	if err := realtimeHandler.Shutdown(shutdownCtx, time.Duration(cfg.RealtimeReconnectBackoffMs)*time.Millisecond); err != nil {
+		logger.Log.Warnf("Realtime drain incomplete: %v", err)
+	}
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Log.Warnf("HTTP shutdown incomplete: %v", err)
+	}
+	if err := migrationService.Shutdown(shutdownCtx); err != nil {
+		logger.Log.Warnf("Migration workers did not stop: %v", err)
+	}
+	if err := noteService.Shutdown(shutdownCtx); err != nil {
+		logger.Log.Warnf("Notification workers did not finish: %v", err)
+	}
+	if err := analyticsTracker.Shutdown(shutdownCtx); err != nil {
+		logger.Log.Warnf("Analytics writes did not finish: %v", err)
 	}
+	logger.Log.Info("👋 Server stopped cleanly.")
 }
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"superaib/internal/api/middleware"
+	"superaib/internal/api/response"
+	"superaib/internal/services"
+
+	"github.com/gorilla/mux"
+)
+
+type CollectionMigrationHandler struct {
+	service services.CollectionMigrationService
+}
+
+func NewCollectionMigrationHandler(s services.CollectionMigrationService) *CollectionMigrationHandler {
+	return &CollectionMigrationHandler{service: s}
+}
+
+func (h *CollectionMigrationHandler) getPID(r *http.Request) string {
+	if pID, ok := r.Context().Value(middleware.ProjectIDKey).(string); ok && pID != "" {
+		return pID
+	}
+	return mux.Vars(r)["project_id"]
+}
+
+// StartMigration: POST /collections/{collection}/migrations
+func (h *CollectionMigrationHandler) StartMigration(w http.ResponseWriter, r *http.Request) {
+	var req services.CollectionMigrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid JSON body", err.Error())
+		return
+	}
+
+	job, err := h.service.Start(r.Context(), h.getPID(r), mux.Vars(r)["collection"], req)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, "Failed to start migration", err.Error())
+		return
+	}
+	response.JSON(w, http.StatusAccepted, "Migration started", job)
+}
+
+// GetHistory: GET /collections/{collection}/migrations
+func (h *CollectionMigrationHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	jobs, err := h.service.History(r.Context(), h.getPID(r), mux.Vars(r)["collection"])
+	if err != nil {
+		response.Error(w, http.StatusNotFound, "Failed to load migration history", err.Error())
+		return
+	}
+	response.JSON(w, http.StatusOK, "Success", jobs)
+}
+
+// GetMigration: GET /migrations/{migration_id} (Progress polling)
+func (h *CollectionMigrationHandler) GetMigration(w http.ResponseWriter, r *http.Request) {
+	job, err := h.service.Get(r.Context(), h.getPID(r), mux.Vars(r)["migration_id"])
+	if err != nil {
+		response.Error(w, http.StatusNotFound, "Migration not found", err.Error())
+		return
+	}
+	response.JSON(w, http.StatusOK, "Success", job)
+}
+
+// CancelMigration: POST /migrations/{migration_id}/cancel
+func (h *CollectionMigrationHandler) CancelMigration(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.Cancel(r.Context(), h.getPID(r), mux.Vars(r)["migration_id"]); err != nil {
+		response.Error(w, http.StatusBadRequest, "Cancel failed", err.Error())
+		return
+	}
+	response.JSON(w, http.StatusOK, "Cancellation requested", nil)
+}
+
+// ResumeMigration: POST /migrations/{migration_id}/resume
+func (h *CollectionMigrationHandler) ResumeMigration(w http.ResponseWriter, r *http.Request) {
+	job, err := h.service.Resume(r.Context(), h.getPID(r), mux.Vars(r)["migration_id"])
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, "Resume failed", err.Error())
+		return
+	}
+	response.JSON(w, http.StatusAccepted, "Migration resumed", job)
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"superaib/internal/models"
+	"superaib/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"gorm.io/datatypes"
+)
+
+type stubFeatureService struct {
+	services.ProjectFeatureService
+	features []models.ProjectFeature
+}
+
+func (s *stubFeatureService) GetAllFeaturesByProject(ctx context.Context, projectID string) ([]models.ProjectFeature, error) {
+	return s.features, nil
+}
+
+// Route-ka features-ka waxaa ilaaliya API key-ga kaliya: secrets-ka config-ka waa inaysan ku jirin jawaabta
+func TestGetAllFeaturesOmitsSecrets(t *testing.T) {
+	secrets := []string{"s3cr3t-channel-hmac-key", "s3cr3t-aws-secret-key", "s3cr3t-cloudinary-secret"}
+	projectID := uuid.NewString()
+	svc := &stubFeatureService{features: []models.ProjectFeature{{
+		ID:        uuid.New(),
+		ProjectID: projectID,
+		Type:      models.FeatureTypeRealtime,
+		Enabled:   true,
+		Config:    datatypes.JSON(`{"auth_secret":"` + secrets[0] + `","auth_endpoint":"https://example.com/auth"}`),
+	}, {
+		ID:        uuid.New(),
+		ProjectID: projectID,
+		Type:      models.FeatureTypeStorage,
+		Enabled:   true,
+		Config:    datatypes.JSON(`{"driver":"s3","bucket":"files","access_key_id":"AKIA","secret_access_key":"` + secrets[1] + `","api_secret":"` + secrets[2] + `"}`),
+	}}}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/projects/{project_id}/features", NewProjectFeatureHandler(svc).GetAllFeatures)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/projects/"+projectID+"/features", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+	body := w.Body.String()
+	for _, secret := range secrets {
+		if strings.Contains(body, secret) {
+			t.Fatalf("response leaks a secret: %s", body)
+		}
+	}
+	if !strings.Contains(body, `"auth_secret_set":true`) || !strings.Contains(body, `"secret_access_key_set":true`) ||
+		!strings.Contains(body, "https://example.com/auth") || !strings.Contains(body, `"bucket":"files"`) {
+		t.Fatalf("response lost the non-secret config: %s", body)
+	}
+}
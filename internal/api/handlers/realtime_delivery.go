@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"superaib/internal/models"
+	wsbus "superaib/pkg/websocket"
+	"time"
+)
+
+// 📬 Guaranteed delivery: fariimaha ack_required waa la soo celinayaa ilaa ACK la helo
+const (
+	redeliveryInterval = 1 * time.Second
+	redeliveryBase     = 1 * time.Second // 1s, 2s, 4s, 8s, 16s
+	maxDeliveryAttempt = 5
+	replayLimit        = 500 // Ugu badnaan inta fariimood ee hal resume la celinayo
+)
+
+// pendingDelivery: Frame loo diray subscriber laakiin aan weli ACK laga helin
+type pendingDelivery struct {
+	data     []byte
+	attempts int
+	nextAt   time.Time
+}
+
+func newPendingDelivery(data []byte) *pendingDelivery {
+	return &pendingDelivery{data: data, attempts: 1, nextAt: time.Now().Add(redeliveryBase)}
+}
+
+// eventFrame: Frame-ka event la keydiyay (id + seq si client-ku u ACK gareeyo ama gap u ogaado)
+func eventFrame(channelName string, ev *models.RealtimeEvent, replay bool) []byte {
+	frame := map[string]interface{}{
+		"id":           ev.ID,
+		"seq":          ev.Sequence,
+		"channel":      channelName,
+		"event_type":   ev.EventType,
+		"payload":      json.RawMessage(ev.Payload),
+		"ack_required": ev.AckRequired,
+		"timestamp":    ev.CreatedAt,
+	}
+	if ev.SenderID != nil {
+		frame["sender_id"] = *ev.SenderID
+	}
+	if ev.EditCount > 0 {
+		frame["edited_at"] = ev.EditedAt
+		frame["edit_count"] = ev.EditCount
+	}
+	if replay {
+		frame["replay"] = true
+	}
+	data, _ := json.Marshal(frame)
+	return data
+}
+
+// broadcastEvent: U dir event la keydiyay subscribers-ka node-kan iyo nodes-ka kale
+func (h *RealtimeHandler) broadcastEvent(pID, channelName string, ev *models.RealtimeEvent) {
+	data := eventFrame(channelName, ev, false)
+	ackID := ""
+	if ev.AckRequired {
+		ackID = ev.ID.String()
+	}
+
+	h.deliverToChannel(pID, channelName, data, ackID)
+	h.publish(wsbus.Message{
+		Kind:        wsbus.KindChannel,
+		ProjectID:   pID,
+		Channel:     channelName,
+		Data:        data,
+		EventID:     ev.ID.String(),
+		AckRequired: ev.AckRequired,
+	})
+}
+
+// ack: {"action":"ACK","event_id":"..."} — jooji redelivery-ga kadibna calaamadee delivered
+func (h *RealtimeHandler) ack(c *Client, eventID string) {
+	if eventID == "" {
+		return
+	}
+	c.mu.Lock()
+	_, ok := c.pending[eventID]
+	delete(c.pending, eventID)
+	c.mu.Unlock()
+	if !ok {
+		return
+	}
+	if err := h.service.AckEvent(context.Background(), eventID); err != nil {
+		fmt.Printf("❌ [Realtime] Ack failed for %s: %v\n", eventID, err)
+	}
+}
+
+// replay: SUBSCRIBE + last_event_id → u dir fariimihii la seegay (sequence order)
+func (h *RealtimeHandler) replay(c *Client, channel *models.RealtimeChannel, lastEventID string) {
+	if err := h.replayFrames(c, channel, lastEventID); err != nil {
+		h.sendError(c, channel.Name, err)
+	}
+}
+
+func (h *RealtimeHandler) replayFrames(c *Client, channel *models.RealtimeChannel, lastEventID string) error {
+	events, err := h.service.ReplaySince(context.Background(), channel, lastEventID, replayLimit)
+	if err != nil {
+		return err
+	}
+	for i := range events {
+		ev := &events[i]
+		data := eventFrame(channel.Name, ev, true)
+		if ev.AckRequired && c.acks {
+			c.mu.Lock()
+			c.pending[ev.ID.String()] = newPendingDelivery(data)
+			c.mu.Unlock()
+		}
+		if !h.send(c, data) {
+			fmt.Printf("⚠️ [Realtime] Send buffer full during replay for conn %s on %s\n", c.ID, channel.Name)
+		}
+	}
+	return nil
+}
+
+// redeliveryLoop: Dib u dir fariimaha aan ACK la helin (exponential backoff, ugu badnaan 5 jeer)
+func (h *RealtimeHandler) redeliveryLoop(ctx context.Context) {
+	ticker := time.NewTicker(redeliveryInterval)
+	defer ticker.Stop()
+	for {
+		var now time.Time
+		select {
+		case <-ctx.Done():
+			return
+		case now = <-ticker.C:
+		}
+
+		h.projectsMux.RLock()
+		var clients []*Client
+		for _, set := range h.projects {
+			for c := range set {
+				clients = append(clients, c)
+			}
+		}
+		h.projectsMux.RUnlock()
+
+		for _, c := range clients {
+			var due [][]byte
+			var retried []string
+			c.mu.Lock()
+			for id, p := range c.pending {
+				if now.Before(p.nextAt) {
+					continue
+				}
+				if p.attempts >= maxDeliveryAttempt {
+					delete(c.pending, id)
+					fmt.Printf("⚠️ [Realtime] Giving up on event %s for conn %s after %d attempts\n", id, c.ID, p.attempts)
+					continue
+				}
+				p.attempts++
+				p.nextAt = now.Add(redeliveryBase << (p.attempts - 1))
+				due = append(due, p.data)
+				retried = append(retried, id)
+			}
+			c.mu.Unlock()
+
+			for _, data := range due {
+				h.send(c, data)
+			}
+			if len(retried) > 0 {
+				go func(ids []string) {
+					for _, id := range ids {
+						h.service.RecordRetry(context.Background(), id)
+					}
+				}(retried)
+			}
+		}
+	}
+}
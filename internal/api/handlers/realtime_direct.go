@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"superaib/internal/api/response"
+	"superaib/internal/services"
+	wsbus "superaib/pkg/websocket"
+	"time"
+)
+
+// maxDirectRecipients: Hal codsi ugu badnaan inta user ee la gaarsiin karo
+const maxDirectRecipients = 1000
+
+// SendToUsers: U dir fariin dhamaan connections-ka (tabs/devices) user-yadan, channel la'aan.
+// Services-ka server-ka (tusaale notifications) ayaa si toos ah u wacaya.
+func (h *RealtimeHandler) SendToUsers(pID string, userIDs []string, event string, payload interface{}) {
+	if len(userIDs) == 0 {
+		return
+	}
+	data, _ := json.Marshal(map[string]interface{}{
+		"event_type": event,
+		"payload":    payload,
+		"direct":     true,
+		"timestamp":  time.Now(),
+	})
+
+	h.deliverToUsers(pID, userIDs, data)
+	h.publish(wsbus.Message{Kind: wsbus.KindUser, ProjectID: pID, UserIDs: userIDs, Data: data})
+}
+
+// deliverToUsers: Kaliya clients-ka node-kan ee user_id-gooda liiska ku jiro (transport kasta)
+func (h *RealtimeHandler) deliverToUsers(pID string, userIDs []string, data []byte) {
+	targets := make(map[string]bool, len(userIDs))
+	for _, id := range userIDs {
+		targets[id] = true
+	}
+	for _, client := range h.clientsOf(pID) {
+		if client.UserID == "" || !targets[client.UserID] {
+			continue
+		}
+		h.send(client, data)
+	}
+}
+
+// SendDirectMessage: POST /realtime/direct {"user_id" | "user_ids", "event", "payload"}
+func (h *RealtimeHandler) SendDirectMessage(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		UserID  string                 `json:"user_id"`
+		UserIDs []string               `json:"user_ids"`
+		Event   string                 `json:"event"`
+		Payload map[string]interface{} `json:"payload"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	seen := make(map[string]bool)
+	var recipients []string
+	for _, id := range append(body.UserIDs, body.UserID) {
+		if id != "" && !seen[id] {
+			seen[id] = true
+			recipients = append(recipients, id)
+		}
+	}
+	if len(recipients) == 0 {
+		response.Error(w, http.StatusBadRequest, "At least one recipient is required", "user_id or user_ids")
+		return
+	}
+	if len(recipients) > maxDirectRecipients {
+		response.Error(w, http.StatusBadRequest, "Too many recipients", "max 1000 user_ids per request")
+		return
+	}
+	if body.Event == "" {
+		body.Event = "direct_message"
+	}
+
+	pID := h.getPID(r)
+	if err := h.service.ConsumeDirectMessage(r.Context(), pID); err != nil {
+		if err == services.ErrRealtimeEventLimit {
+			response.Error(w, http.StatusForbidden, "Realtime event limit reached", "limit_reached_realtime_events")
+			return
+		}
+		response.Error(w, http.StatusInternalServerError, "Failed to send direct message", err.Error())
+		return
+	}
+
+	h.SendToUsers(pID, recipients, body.Event, body.Payload)
+	response.JSON(w, http.StatusAccepted, "Sent", map[string]interface{}{
+		"recipients": len(recipients),
+	})
+}
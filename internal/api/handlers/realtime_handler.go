@@ -3,140 +3,551 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 	"superaib/internal/api/middleware"
 	"superaib/internal/api/response"
 	"superaib/internal/models"
 	"superaib/internal/services"
+	"superaib/pkg/utils"
+	wsbus "superaib/pkg/websocket"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
+	"golang.org/x/time/rate"
 	"gorm.io/datatypes"
 )
 
 type Client struct {
+	ID        string // Connection ID (tab kasta wuxuu leeyahay mid u gaar ah)
 	Conn      *websocket.Conn
 	ProjectID string
 	UserID    string
 	Channels  map[string]bool // Qolalka uu ku jiro qofkan
 	mu        sync.Mutex
 	Send      chan []byte
+
+	channelIDs  map[string]uuid.UUID        // channel name -> DB id (presence leave)
+	pending     map[string]*pendingDelivery // event_id -> fariin sugaysa ACK
+	patternAuth map[string]patternDecision  // channel dhab ah -> pattern subscription-ku ma oggol yahay (channelCacheTTL)
+	patternWait map[string][]parkedFrame    // channel dhab ah -> fariimo sugaya go'aanka (resolver ayaa socda)
+	acks        bool                        // Transport-ku ACK ma soo diri karaa (WebSocket); SSE/long-poll waxay ku tiirsan yihiin replay
+	protocol    string                      // Subprotocol-ka la isku afgartay ("" = legacy JSON)
+	closed      chan struct{}               // SSE/long-poll: server-ku wuu xiray (ban); WebSocket-ka Conn.Close ayaa la isticmaalaa
+	closeOnce   sync.Once
+
+	// 📊 Inspector
+	transport   string // websocket | sse | long_poll
+	remoteAddr  string
+	connectedAt time.Time
+	stats       trafficCounters
+
+	// 🚦 Rate limiting (readPump kaliya ayaa taabta violations)
+	limiter         *rate.Limiter
+	violations      int
+	violationsSince time.Time
 }
 
+const (
+	// 💓 Heartbeat: server-ku ping ayuu diraa 30s kasta; haddii 60s pong la waayo socket-ku waa dhintay
+	pingPeriod = 30 * time.Second
+	pongWait   = 60 * time.Second
+
+	publishFlushTimeout = 2 * time.Second
+)
+
 type RealtimeHandler struct {
 	service     services.RealtimeService
 	projects    map[string]map[*Client]bool
 	projectsMux sync.RWMutex
 	upgrader    websocket.Upgrader
+
+	// 🌐 BACKPLANE: Broadcasts-ka waxay gaaraan dhamaan replicas-ka (nodes)
+	backplane wsbus.Backplane
+	outbox    chan wsbus.Message
+
+	// 🌳 Subscriptions (exact + wildcard) project kasta; channelCache: pattern authorization
+	subs         map[string]*subTrie
+	subsMux      sync.RWMutex
+	channelCache sync.Map
+
+	// 🚦 Abuse protection
+	limits          RealtimeLimits
+	projectLimiters map[string]*rate.Limiter
+	limitersMux     sync.Mutex
+
+	// 🛑 Graceful shutdown: upgrades cusub waa la diidaa; background loops-ka waxay ku xiran yihiin ctx
+	// (Shutdown ayaa cancel gareeya kadibna loops-ka sugaa)
+	draining atomic.Bool
+	ctx      context.Context
+	stop     context.CancelFunc
+	loops    sync.WaitGroup
+
+	// 📊 Metrics (project kasta): counters + rates
+	stats sync.Map // project_id -> *projectStats
+
+	// 🐢 Long-poll sessions (session_id -> client); SSE wuxuu isticmaalaa request-ka oo furan
+	// reserved: connection IDs la bixiyay ka hor SSE/long-poll si channel tokens loogu xiro
+	pollSessions map[string]*pollSession
+	reserved     map[string]connectionReservation
+	pollMux      sync.Mutex
 }
 
-func NewRealtimeHandler(s services.RealtimeService) *RealtimeHandler {
-	return &RealtimeHandler{
+func NewRealtimeHandler(s services.RealtimeService, bp wsbus.Backplane, limits RealtimeLimits) *RealtimeHandler {
+	if bp == nil {
+		bp = wsbus.NewMemoryBackplane(nil)
+	}
+	h := &RealtimeHandler{
 		service:  s,
+		limits:   limits.withDefaults(),
 		projects: make(map[string]map[*Client]bool),
 		upgrader: websocket.Upgrader{
 			CheckOrigin: func(r *http.Request) bool { return true },
 			// 🚀 XALKA SIMULATOR-KA: Dami wax kasta oo compression ah
 			EnableCompression: false,
+			// 📦 Sec-WebSocket-Protocol: superaib.v1.msgpack / superaib.v1.json (haddii la waayo → legacy JSON)
+			Subprotocols: wsbus.Subprotocols,
 		},
+		backplane:    bp,
+		outbox:       make(chan wsbus.Message, 1024),
+		pollSessions: make(map[string]*pollSession),
+		reserved:     make(map[string]connectionReservation),
+		subs:         make(map[string]*subTrie),
+
+		projectLimiters: make(map[string]*rate.Limiter),
+	}
+	h.ctx, h.stop = context.WithCancel(context.Background())
+	bp.Subscribe(h.onBackplaneMessage)
+	for _, loop := range []func(context.Context){h.publishLoop, h.redeliveryLoop, h.pollReaper, h.metricsLoop} {
+		h.loops.Add(1)
+		go func() {
+			defer h.loops.Done()
+			loop(h.ctx)
+		}()
 	}
+	return h
 }
 
 func (h *RealtimeHandler) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
+	if h.rejectIfDraining(w) {
+		return
+	}
 	// Tirtir extensions-ka simulator-ka uu soo diro
 	r.Header.Del("Sec-WebSocket-Extensions")
 
-	vars := mux.Vars(r)
-	projectID := vars["project_id"]
-	if projectID == "" {
-		projectID = r.URL.Query().Get("project_id")
+	projectID, userID, ok := h.authenticateRequest(w, r)
+	if !ok {
+		return
 	}
-	userID := r.URL.Query().Get("user_id")
 
 	conn, err := h.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		return
 	}
 
-	client := &Client{
-		Conn:      conn,
-		ProjectID: projectID,
-		UserID:    userID,
-		Channels:  make(map[string]bool),
-		Send:      make(chan []byte, 256),
-	}
+	client := newClient(projectID, userID, transportWebSocket, r.RemoteAddr)
+	client.Conn = conn
+	client.acks = true
+	client.protocol = conn.Subprotocol()
+	client.limiter = rate.NewLimiter(rate.Limit(h.limits.ConnRate), h.limits.ConnBurst)
 
-	h.registerClient(client)
+	if !h.registerClient(client) {
+		h.goAway(client) // Drain-ku wuu bilaabmay inta upgrade-ku socday
+		return
+	}
 	fmt.Printf("\n🚀 [Realtime] Connected: User [%s]\n", userID)
 
 	go h.writePump(client)
 	h.readPump(client)
 }
 
+// authenticateRequest: Project-ka (APIKeyMiddleware) + auth user ikhtiyaari ah; WebSocket, SSE iyo long-poll way wadaagaan
+func (h *RealtimeHandler) authenticateRequest(w http.ResponseWriter, r *http.Request) (string, string, bool) {
+	// 🔐 API Key-ga waxaa horay u xaqiijiyay APIKeyMiddleware (project.ID dhabta ah ayuu context-ga galiyay)
+	projectID := h.getPID(r)
+	if projectID == "" {
+		projectID = r.URL.Query().Get("project_id")
+	}
+
+	// 🔐 Auth user (optional): user_id waxaa laga soo saaraa JWT-ga, lagama aamino query-ga
+	userID := ""
+	if token := bearerToken(r); token != "" {
+		uid, err := h.service.AuthenticateUser(r.Context(), projectID, token)
+		if err != nil {
+			response.Error(w, http.StatusUnauthorized, "Invalid user token", err.Error())
+			return "", "", false
+		}
+		userID = uid
+
+		// 🛡️ User-ka project-ka laga ban gareeyay ma furi karo connection cusub
+		if err := h.service.CheckModeration(r.Context(), projectID, nil, userID, services.ChannelActionSubscribe); err == services.ErrRealtimeBanned {
+			response.Error(w, http.StatusForbidden, "User is banned from realtime", errorCode(err))
+			return "", "", false
+		}
+	}
+	return projectID, userID, true
+}
+
+func newClient(projectID, userID, transport, remoteAddr string) *Client {
+	return &Client{
+		ID:          uuid.New().String(),
+		ProjectID:   projectID,
+		UserID:      userID,
+		transport:   transport,
+		remoteAddr:  remoteAddr,
+		connectedAt: time.Now(),
+		Channels:    make(map[string]bool),
+		Send:        make(chan []byte, 256),
+		channelIDs:  make(map[string]uuid.UUID),
+		pending:     make(map[string]*pendingDelivery),
+		patternAuth: make(map[string]patternDecision),
+		patternWait: make(map[string][]parkedFrame),
+		closed:      make(chan struct{}),
+	}
+}
+
+// bearerToken: JWT-ga auth user-ka (Header "Authorization: Bearer" ama Query "access_token" - WebSocket)
+func bearerToken(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimPrefix(auth, "Bearer ")
+	}
+	if t := r.URL.Query().Get("access_token"); t != "" {
+		return t
+	}
+	return r.URL.Query().Get("token")
+}
+
 func (h *RealtimeHandler) readPump(c *Client) {
 	defer func() {
 		h.unregisterClient(c)
 		c.Conn.Close()
+		go h.leaveAll(c)
 	}()
 
+	c.Conn.SetReadLimit(h.limits.MaxFrameBytes)
+	c.Conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.Conn.SetPongHandler(func(string) error {
+		return c.Conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
 	for {
-		_, message, err := c.Conn.ReadMessage()
+		mt, message, err := c.Conn.ReadMessage()
 		if err != nil {
+			if errors.Is(err, websocket.ErrReadLimit) {
+				fmt.Printf("🚫 [Realtime] Conn %s sent a frame larger than %d bytes\n", c.ID, h.limits.MaxFrameBytes)
+			}
 			break
 		}
-
-		var msg struct {
-			Action  string                 `json:"action"`
-			Channel string                 `json:"channel"`
-			Event   string                 `json:"event"`
-			Payload map[string]interface{} `json:"payload"`
+		c.Conn.SetReadDeadline(time.Now().Add(pongWait))
+		h.countIn(c)
+
+		// 📦 MessagePack subprotocol: binary frames → JSON (hal parser ayaa jira)
+		if mt == websocket.BinaryMessage {
+			if c.protocol != wsbus.SubprotocolMsgpack {
+				h.reply(c, "", "", nil, newProtocolError(wsbus.CodeInvalidFrame, "binary frames require the "+wsbus.SubprotocolMsgpack+" subprotocol"))
+				continue
+			}
+			if message, err = wsbus.MsgpackToJSON(message); err != nil {
+				h.reply(c, "", "", nil, newProtocolError(wsbus.CodeInvalidFrame, err.Error()))
+				continue
+			}
 		}
 
+		var msg clientFrame
 		if err := json.Unmarshal(message, &msg); err != nil {
+			h.reply(c, "", "", nil, newProtocolError(wsbus.CodeInvalidFrame, "frame is not valid JSON: "+err.Error()))
+			continue
+		}
+		if msg.V > wsbus.ProtocolVersion {
+			h.reply(c, msg.Ref, msg.Channel, nil, newProtocolError(wsbus.CodeUnsupportedV, fmt.Sprintf("protocol version %d is not supported (max %d)", msg.V, wsbus.ProtocolVersion)))
+			continue
+		}
+
+		// 🚦 Token buckets: error frame, kadibna disconnect haddii uu ku celceliyo
+		if err := h.checkRate(c, msg.Action); err != nil {
+			h.reply(c, msg.Ref, msg.Channel, nil, err)
+			if h.recordViolation(c) {
+				h.disconnect(c, websocket.ClosePolicyViolation, "rate limit exceeded repeatedly")
+				break
+			}
 			continue
 		}
 
 		fmt.Printf("📩 [Realtime] Action: %s | Channel: %s\n", msg.Action, msg.Channel)
 
-		switch msg.Action {
-		case "SUBSCRIBE":
-			c.mu.Lock()
-			c.Channels[msg.Channel] = true
-			c.mu.Unlock()
-
-			// 💾 Database sync (SAVE TO PGADMIN)
-			go h.service.JoinChannel(context.Background(), c.ProjectID, msg.Channel, c.UserID)
-
-		case "BROADCAST":
-			// 1. LIVE SEND (U dir qof kasta oo online ah)
-			h.BroadcastToChannel(c.ProjectID, msg.Channel, msg.Event, msg.Payload, c.UserID)
-
-			// 2. 💾 DATABASE SAVE (Inuu pgAdmin ka soo muuqdo)
-			go func(p, ch, ev string, py map[string]interface{}) {
-				channel, _ := h.service.JoinChannel(context.Background(), p, ch, c.UserID)
-				if channel != nil {
-					event := &models.RealtimeEvent{
-						ChannelID: channel.ID,
-						EventType: models.RealtimeEventType(ev),
-						Payload:   h.mapToJSON(py),
-					}
-					if c.UserID != "" {
-						uID := c.UserID
-						event.SenderID = &uID
-					}
-					h.service.CreateEvent(context.Background(), p, event)
-				}
-			}(c.ProjectID, msg.Channel, msg.Event, msg.Payload)
+		payload, err := h.handleAction(c, &msg)
+		h.reply(c, msg.Ref, msg.Channel, payload, err)
+	}
+}
+
+// clientFrame: Fariinta client-ka (v0 legacy ama v1 oo leh "v" iyo "ref")
+type clientFrame struct {
+	V        int                    `json:"v"`   // Protocol version (0 = legacy)
+	Ref      string                 `json:"ref"` // Request id; jawaab (reply) ayaa loo celinayaa
+	Action   string                 `json:"action"`
+	Channel  string                 `json:"channel"`
+	Event    string                 `json:"event"`
+	Payload  map[string]interface{} `json:"payload"`
+	Presence map[string]interface{} `json:"presence"` // SUBSCRIBE: metadata-da user-ka (name, avatar, status...)
+
+	// 🔑 SUBSCRIBE (private channels): token-ka backend-ka developer-ka + presence-ka la saxiixay
+	Auth        string `json:"auth"`
+	ChannelData string `json:"channel_data"`
+
+	// 🧩 PATCH (shared-state channels)
+	Ops []utils.PatchOperation `json:"ops"`
+
+	// 📬 Guaranteed delivery
+	AckRequired bool   `json:"ack_required"`  // BROADCAST: subscribers-ku waa inay ACK soo diraan
+	EventID     string `json:"event_id"`      // ACK
+	LastEventID string `json:"last_event_id"` // SUBSCRIBE: replay wixii la seegay tan iyo event-kan
+}
+
+// handleAction: Fuli action-ka; payload-ka waxaa lagu celiyaa "ok" reply-ga
+func (h *RealtimeHandler) handleAction(c *Client, msg *clientFrame) (interface{}, error) {
+	switch msg.Action {
+	case "SUBSCRIBE":
+		channel, err := h.subscribe(c, msg.Channel, msg.Presence, services.ChannelAuthRequest{Token: msg.Auth, ChannelData: msg.ChannelData})
+		if err != nil {
+			return nil, err
+		}
+
+		// 🔁 Resume: u dir fariimihii la seegay intii uu maqnaa (pattern-ka ma laha taariikh)
+		if channel != nil && msg.LastEventID != "" {
+			h.replay(c, channel, msg.LastEventID)
+		}
+		h.sendSnapshot(c, channel)
+		return map[string]interface{}{"channel": msg.Channel, "pattern": channel == nil}, nil
+
+	case "UNSUBSCRIBE":
+		h.unsubscribe(c, msg.Channel)
+		return map[string]interface{}{"channel": msg.Channel}, nil
+
+	case "ACK":
+		h.ack(c, msg.EventID)
+		return map[string]interface{}{"event_id": msg.EventID}, nil
+
+	case "PATCH":
+		return h.patchState(c, msg)
+
+	case "STATE":
+		return h.currentState(c, msg.Channel)
+
+	case "HEARTBEAT":
+		// Read deadline-ka kor ayaa lagu cusboonaysiiyay; presence sweeper-ka ayaa DB-ga u sheega
+		return map[string]interface{}{"server_time": time.Now()}, nil
+
+	case "BROADCAST":
+		channel, err := h.authorize(c, msg.Channel, services.ChannelActionBroadcast)
+		if err != nil {
+			return nil, err
+		}
+
+		// 1. 💾 DATABASE SAVE (sequence + id) si replay iyo ACK ay u shaqeeyaan
+		event := &models.RealtimeEvent{
+			ChannelID:   channel.ID,
+			EventType:   models.RealtimeEventType(msg.Event),
+			Payload:     h.mapToJSON(msg.Payload),
+			AckRequired: msg.AckRequired,
+		}
+		if c.UserID != "" {
+			uID := c.UserID
+			event.SenderID = &uID
+		}
+		if err := h.service.CreateEvent(context.Background(), c.ProjectID, event); err != nil {
+			return nil, err
+		}
+
+		// 2. LIVE SEND (U dir qof kasta oo online ah)
+		h.broadcastEvent(c.ProjectID, channel.Name, event)
+		return map[string]interface{}{"id": event.ID, "seq": event.Sequence}, nil
+	}
+	return nil, newProtocolError(wsbus.CodeUnknownAction, fmt.Sprintf("unknown action %q", msg.Action))
+}
+
+// subscribe: Auth + presence + ku dar client-ka channel-ka (transport kasta wuu isticmaalaa)
+// Pattern-ka ("orders:*") channel lama abuurayo, presence ma leh, channel-na lama soo celiyo (nil);
+// authorization-ka waxaa lagu sameeyaa channel kasta oo dhab ah marka fariin timaado
+// (callbacks-ka private channels kaliya subscribe exact ah ayay taageeraan).
+func (h *RealtimeHandler) subscribe(c *Client, name string, presence map[string]interface{}, auth services.ChannelAuthRequest) (*models.RealtimeChannel, error) {
+	if err := h.checkSubscriptionLimit(c, name); err != nil {
+		return nil, err
+	}
+
+	if isPattern(name) {
+		if err := validatePattern(name); err != nil {
+			return nil, err
+		}
+		c.mu.Lock()
+		c.Channels[name] = true
+		c.mu.Unlock()
+		h.indexSubscription(c, name)
+		return nil, nil
+	}
+
+	if name == "" {
+		return nil, errors.New("channel is required")
+	}
+
+	// 💾 Database sync (SAVE TO PGADMIN) + 🔐 hubi ogolaanshaha channel-ka (private: token / auth endpoint)
+	channel, err := h.service.JoinChannel(context.Background(), c.ProjectID, name, c.UserID)
+	if err != nil {
+		return nil, err
+	}
+	auth.ConnectionID, auth.UserID = c.ID, c.UserID
+	granted, err := h.service.AuthorizeSubscription(context.Background(), channel, auth)
+	if err != nil {
+		return nil, err
+	}
+	if granted != nil {
+		presence = granted // channel_data-ka la saxiixay ayaa laga aaminayaa kan client-ka
+	}
+
+	// 👥 Presence: presence_join (kaliya tab-ka ugu horreeya) + presence_state client-kan
+	// 🚪 MaxClients: haddii channel-ku buuxo subscribe-ka waa la diidayaa
+	if err := h.joinPresence(c, channel, presence); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.Channels[channel.Name] = true
+	c.channelIDs[channel.Name] = channel.ID
+	c.mu.Unlock()
+	h.indexSubscription(c, channel.Name)
+	return channel, nil
+}
+
+func (h *RealtimeHandler) unsubscribe(c *Client, name string) {
+	c.mu.Lock()
+	channelID, ok := c.channelIDs[name]
+	_, subscribed := c.Channels[name]
+	delete(c.Channels, name)
+	delete(c.channelIDs, name)
+	c.mu.Unlock()
+	if subscribed {
+		h.unindexSubscription(c, name)
+	}
+	if ok {
+		go h.leavePresence(c.ProjectID, channelID, c.ID)
+	}
+}
+
+// authorize: Soo hel (ama abuur) channel-ka kadibna hubi in client-kan loo ogol yahay
+func (h *RealtimeHandler) authorize(c *Client, name string, action services.ChannelAction) (*models.RealtimeChannel, error) {
+	if name == "" {
+		return nil, errors.New("channel is required")
+	}
+	channel, err := h.service.JoinChannel(context.Background(), c.ProjectID, name, c.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.service.AuthorizeChannel(context.Background(), channel, c.UserID, action); err != nil {
+		// 🔑 Private channel-ka callback-ga lagu galay: subscriber-ku wuu ku dhex diri karaa
+		if action != services.ChannelActionBroadcast || (err != services.ErrRealtimeForbidden && err != services.ErrRealtimeAuthRequired) {
+			return nil, err
+		}
+		c.mu.Lock()
+		_, subscribed := c.channelIDs[channel.Name]
+		c.mu.Unlock()
+		if !subscribed {
+			return nil, err
 		}
 	}
+	return channel, nil
+}
+
+// errorCode: Code-ka mashiinku akhriyi karo ee error kasta (error frames iyo HTTP responses)
+func errorCode(err error) string {
+	var pe *protocolError
+	if errors.As(err, &pe) {
+		return pe.code
+	}
+	if errors.Is(err, services.ErrRealtimeInvalidPatch) {
+		return "invalid_patch"
+	}
+	switch err {
+	case services.ErrRealtimeAuthRequired:
+		return "unauthorized"
+	case services.ErrRealtimeForbidden:
+		return "forbidden"
+	case services.ErrRealtimeChannelLimit:
+		return "limit_reached_realtime_channels"
+	case services.ErrRealtimeEventLimit:
+		return "limit_reached_realtime_events"
+	case services.ErrRealtimeChannelFull:
+		return "channel_full"
+	case services.ErrRealtimeBanned:
+		return "banned"
+	case services.ErrRealtimeMuted:
+		return "muted"
+	case services.ErrRealtimeAuthUnavailable:
+		return "auth_unavailable"
+	case services.ErrRealtimeNotSharedState:
+		return "not_shared_state"
+	case services.ErrRealtimeStateTooLarge:
+		return "state_too_large"
+	case services.ErrRealtimeInvalidChannel, errInvalidPattern:
+		return wsbus.CodeInvalidChannel
+	}
+	return "error"
+}
+
+// protocolError: Frame-ka laftiisa ayaa khaldan (ma aha service error)
+type protocolError struct {
+	code    string
+	message string
+}
+
+func (e *protocolError) Error() string { return e.message }
+
+func newProtocolError(code, message string) error {
+	return &protocolError{code: code, message: message}
+}
+
+// reply: Frame leh "ref" wuxuu helayaa hal jawaab (ok/error); legacy frames kaliya error frame ayay helaan
+func (h *RealtimeHandler) reply(c *Client, ref, channel string, payload interface{}, err error) {
+	if ref == "" {
+		if err != nil {
+			h.sendError(c, channel, err)
+		}
+		return
+	}
+
+	frame := map[string]interface{}{
+		"v":      wsbus.ProtocolVersion,
+		"type":   "reply",
+		"ref":    ref,
+		"status": wsbus.ReplyOK,
+	}
+	if err != nil {
+		frame["status"] = wsbus.ReplyError
+		frame["payload"] = map[string]string{"code": errorCode(err), "message": err.Error()}
+	} else if payload != nil {
+		frame["payload"] = payload
+	}
+	data, _ := json.Marshal(frame)
+	h.send(c, data)
+}
+
+// sendError: U sheeg client-ka in codsigiisii la diiday
+func (h *RealtimeHandler) sendError(c *Client, channel string, err error) {
+	data, _ := json.Marshal(map[string]interface{}{
+		"channel":    channel,
+		"event_type": "error",
+		"payload":    map[string]string{"code": errorCode(err), "message": err.Error()},
+		"timestamp":  time.Now(),
+	})
+	h.send(c, data)
 }
 
 func (h *RealtimeHandler) writePump(c *Client) {
-	ticker := time.NewTicker(30 * time.Second)
+	ticker := time.NewTicker(pingPeriod)
 	defer func() {
 		ticker.Stop()
 		c.Conn.Close()
@@ -149,7 +560,9 @@ func (h *RealtimeHandler) writePump(c *Client) {
 				c.Conn.WriteMessage(websocket.CloseMessage, []byte{})
 				return
 			}
-			c.Conn.WriteMessage(websocket.TextMessage, message)
+			if err := h.writeFrame(c, message); err != nil {
+				return
+			}
 		case <-ticker.C:
 			if err := c.Conn.WriteMessage(websocket.PingMessage, nil); err != nil {
 				return
@@ -158,11 +571,20 @@ func (h *RealtimeHandler) writePump(c *Client) {
 	}
 }
 
-func (h *RealtimeHandler) BroadcastToChannel(pID, channel, event string, payload interface{}, senderID string) {
-	h.projectsMux.RLock()
-	clients := h.projects[pID]
-	h.projectsMux.RUnlock()
+// writeFrame: Frames-ka gudaha waa JSON; msgpack clients waxay helayaan binary (isla document-ka)
+func (h *RealtimeHandler) writeFrame(c *Client, message []byte) error {
+	if c.protocol == wsbus.SubprotocolMsgpack {
+		packed, err := wsbus.JSONToMsgpack(message)
+		if err != nil {
+			fmt.Printf("❌ [Realtime] msgpack encode failed for conn %s: %v\n", c.ID, err)
+			return nil
+		}
+		return c.Conn.WriteMessage(websocket.BinaryMessage, packed)
+	}
+	return c.Conn.WriteMessage(websocket.TextMessage, message)
+}
 
+func (h *RealtimeHandler) BroadcastToChannel(pID, channel, event string, payload interface{}, senderID string) {
 	data, _ := json.Marshal(map[string]interface{}{
 		"channel":    channel,
 		"event_type": event,
@@ -171,27 +593,145 @@ func (h *RealtimeHandler) BroadcastToChannel(pID, channel, event string, payload
 		"timestamp":  time.Now(),
 	})
 
-	for client := range clients {
+	h.deliverToChannel(pID, channel, data, "")
+	h.publish(wsbus.Message{Kind: wsbus.KindChannel, ProjectID: pID, Channel: channel, Data: data})
+}
+
+// deliverToChannel: U dir frame-ka kaliya clients-ka node-kan ku xiran
+// ackID: haddii la rabo ACK, subscriber kasta wuxuu helayaa pending entry (redelivery)
+// Subscribers-ka waxaa laga helaa trie-ga (exact + wildcard patterns), ma aha scan clients oo dhan
+func (h *RealtimeHandler) deliverToChannel(pID, channel string, data []byte, ackID string) {
+	ready, pending := h.subscribersOf(pID, channel)
+	for _, client := range ready {
+		h.deliverTo(client, channel, data, ackID)
+	}
+	for _, client := range pending {
+		h.parkForPattern(client, channel, data, ackID)
+	}
+}
+
+func (h *RealtimeHandler) deliverTo(client *Client, channel string, data []byte, ackID string) {
+	if ackID != "" && client.acks {
 		client.mu.Lock()
-		in := client.Channels[channel]
+		client.pending[ackID] = newPendingDelivery(data)
 		client.mu.Unlock()
-		if in {
-			select {
-			case client.Send <- data:
-			default:
+	}
+	if !h.send(client, data) {
+		// Buffer-ku wuu buuxaa: ack_required waxaa soo celin doona redelivery-ga,
+		// kuwa kale client-ku wuxuu ka ogaanayaa "seq" gap kadibna last_event_id ayuu ku resume gareynayaa
+		fmt.Printf("⚠️ [Realtime] Send buffer full for conn %s on %s\n", client.ID, channel)
+	}
+}
+
+func (h *RealtimeHandler) deliverToProject(pID string, data []byte) {
+	for _, client := range h.clientsOf(pID) {
+		h.send(client, data)
+	}
+}
+
+// =========================================================================
+// 🌐 BACKPLANE (Multi-node fan-out)
+// =========================================================================
+
+// publish: Hal goroutine ayaa u dirta backplane-ka si tartibka fariimaha loo ilaaliyo
+func (h *RealtimeHandler) publish(msg wsbus.Message) {
+	msg.NodeID = h.backplane.NodeID()
+	if h.ctx.Err() != nil {
+		// Shutdown: publishLoop wuu istaagay laakiin HTTP requests (CreateEvent) weli way socdaan
+		ctx, cancel := context.WithTimeout(context.Background(), publishFlushTimeout)
+		defer cancel()
+		if err := h.backplane.Publish(ctx, msg); err != nil {
+			fmt.Printf("❌ [Realtime] Backplane publish failed: %v\n", err)
+		}
+		return
+	}
+	select {
+	case h.outbox <- msg:
+	default:
+		fmt.Printf("⚠️ [Realtime] Backplane outbox full, dropping %s message for %s\n", msg.Kind, msg.ProjectID)
+	}
+}
+
+func (h *RealtimeHandler) publishLoop(ctx context.Context) {
+	for {
+		select {
+		case msg := <-h.outbox:
+			if err := h.backplane.Publish(ctx, msg); err != nil {
+				fmt.Printf("❌ [Realtime] Backplane publish failed: %v\n", err)
+			}
+		case <-ctx.Done():
+			h.flushOutbox()
+			return
+		}
+	}
+}
+
+// flushOutbox: Shutdown: fariimaha outbox-ka ku hadhay u gudbi nodes-ka kale (publishFlushTimeout)
+func (h *RealtimeHandler) flushOutbox() {
+	ctx, cancel := context.WithTimeout(context.Background(), publishFlushTimeout)
+	defer cancel()
+	for {
+		select {
+		case msg := <-h.outbox:
+			if err := h.backplane.Publish(ctx, msg); err != nil {
+				fmt.Printf("❌ [Realtime] Backplane publish failed during shutdown: %v\n", err)
+				return
 			}
+		default:
+			return
+		}
+	}
+}
+
+// onBackplaneMessage: Fariimaha ka yimid nodes-ka kale (kuwa annaga naga yimid horay ayaa loo gaarsiiyay)
+func (h *RealtimeHandler) onBackplaneMessage(msg wsbus.Message) {
+	if msg.NodeID == h.backplane.NodeID() {
+		return
+	}
+	switch msg.Kind {
+	case wsbus.KindChannel:
+		ackID := ""
+		if msg.AckRequired {
+			ackID = msg.EventID
+		}
+		h.deliverToChannel(msg.ProjectID, msg.Channel, msg.Data, ackID)
+	case wsbus.KindProject:
+		h.deliverToProject(msg.ProjectID, msg.Data)
+	case wsbus.KindUser:
+		h.deliverToUsers(msg.ProjectID, msg.UserIDs, msg.Data)
+	case wsbus.KindModeration:
+		var notice moderationNotice
+		if err := json.Unmarshal(msg.Data, &notice); err == nil {
+			h.applyModeration(msg.ProjectID, notice)
 		}
 	}
 }
 
 // Helpers
-func (h *RealtimeHandler) registerClient(c *Client) {
+// clientsOf: Snapshot si map-ka loo akhriyo iyadoo aan lock la haysan inta fariimaha la dirayo
+func (h *RealtimeHandler) clientsOf(pID string) []*Client {
+	h.projectsMux.RLock()
+	defer h.projectsMux.RUnlock()
+	list := make([]*Client, 0, len(h.projects[pID]))
+	for c := range h.projects[pID] {
+		list = append(list, c)
+	}
+	return list
+}
+
+// registerClient: false marka drain-ku bilaabmay (Shutdown snapshot-kiisa lock-kan ayuu ku qaataa,
+// markaa client kasta ama snapshot-ka ayuu ku jiraa ama halkan ayaa lagu diidaa)
+func (h *RealtimeHandler) registerClient(c *Client) bool {
 	h.projectsMux.Lock()
+	defer h.projectsMux.Unlock()
+	if h.draining.Load() {
+		return false
+	}
 	if h.projects[c.ProjectID] == nil {
 		h.projects[c.ProjectID] = make(map[*Client]bool)
 	}
 	h.projects[c.ProjectID][c] = true
-	h.projectsMux.Unlock()
+	return true
 }
 func (h *RealtimeHandler) unregisterClient(c *Client) {
 	h.projectsMux.Lock()
@@ -199,6 +739,16 @@ func (h *RealtimeHandler) unregisterClient(c *Client) {
 		delete(clients, c)
 	}
 	h.projectsMux.Unlock()
+
+	c.mu.Lock()
+	names := make([]string, 0, len(c.Channels))
+	for name := range c.Channels {
+		names = append(names, name)
+	}
+	c.mu.Unlock()
+	for _, name := range names {
+		h.unindexSubscription(c, name)
+	}
 }
 func (h *RealtimeHandler) mapToJSON(m map[string]interface{}) datatypes.JSON {
 	b, _ := json.Marshal(m)
@@ -218,16 +768,83 @@ func (h *RealtimeHandler) GetChannels(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *RealtimeHandler) UpdateChannel(w http.ResponseWriter, r *http.Request) {
-	var c models.RealtimeChannel
-	json.NewDecoder(r.Body).Decode(&c)
-	c.ID, _ = uuid.Parse(mux.Vars(r)["id"])
-	h.service.UpdateChannel(r.Context(), &c)
-	response.JSON(w, 200, "Updated", c)
+	var updates map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&updates); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	pID := h.getPID(r)
+	old, err := h.service.GetChannelByID(r.Context(), mux.Vars(r)["id"])
+	if err != nil || old.ProjectID != pID {
+		response.Error(w, http.StatusNotFound, "Channel not found")
+		return
+	}
+
+	channel, err := h.service.UpdateChannel(r.Context(), pID, mux.Vars(r)["id"], updates)
+	if err == services.ErrRealtimeChannelNotFound {
+		response.Error(w, http.StatusNotFound, "Channel not found")
+		return
+	}
+	if err == services.ErrRealtimeInvalidChannel {
+		response.Error(w, http.StatusBadRequest, "Invalid channel name", err.Error())
+		return
+	}
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, "Failed to update channel", err.Error())
+		return
+	}
+	h.invalidateChannelAuth(old.ProjectID, old.Name) // subscription_type / magaca ayaa laga yaabaa inay is beddeleen
+	response.JSON(w, 200, "Updated", channel)
 }
+
 func (h *RealtimeHandler) DeleteChannel(w http.ResponseWriter, r *http.Request) {
-	h.service.DeleteChannel(r.Context(), mux.Vars(r)["id"])
+	pID := h.getPID(r)
+	old, err := h.service.GetChannelByID(r.Context(), mux.Vars(r)["id"])
+	if err != nil || old.ProjectID != pID {
+		response.Error(w, http.StatusNotFound, "Channel not found")
+		return
+	}
+	err = h.service.DeleteChannel(r.Context(), pID, mux.Vars(r)["id"])
+	if err == services.ErrRealtimeChannelNotFound {
+		response.Error(w, http.StatusNotFound, "Channel not found")
+		return
+	}
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, "Failed to delete channel", err.Error())
+		return
+	}
+	h.invalidateChannelAuth(old.ProjectID, old.Name)
 	response.JSON(w, 200, "Deleted", nil)
 }
+
+// ArchiveChannel: POST /channels/{id}/archive (export ikhtiyaari ah + tirtir taariikhda)
+func (h *RealtimeHandler) ArchiveChannel(w http.ResponseWriter, r *http.Request) {
+	channel, err := h.service.ArchiveChannel(r.Context(), h.getPID(r), mux.Vars(r)["id"])
+	if err == services.ErrRealtimeChannelNotFound {
+		response.Error(w, http.StatusNotFound, "Channel not found")
+		return
+	}
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, "Failed to archive channel", err.Error())
+		return
+	}
+	response.JSON(w, 200, "Archived", channel)
+}
+
+// ExportEvents: GET /channels/{channel_id}/events/export (NDJSON download)
+func (h *RealtimeHandler) ExportEvents(w http.ResponseWriter, r *http.Request) {
+	channel, err := h.service.GetChannelByID(r.Context(), mux.Vars(r)["channel_id"])
+	if err != nil || channel.ProjectID != h.getPID(r) {
+		response.Error(w, http.StatusNotFound, "Channel not found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", channel.Name+".ndjson"))
+	if err := h.service.ExportEvents(r.Context(), channel.ID, w); err != nil {
+		fmt.Printf("❌ [Realtime] Export failed for %s: %v\n", channel.Name, err)
+	}
+}
+
 func (h *RealtimeHandler) GetEvents(w http.ResponseWriter, r *http.Request) {
 	events, _ := h.service.GetEventsByChannel(r.Context(), mux.Vars(r)["channel_id"])
 	response.JSON(w, 200, "Success", events)
@@ -236,25 +853,46 @@ func (h *RealtimeHandler) UpdateEvent(w http.ResponseWriter, r *http.Request) {
 	var b struct {
 		Payload datatypes.JSON `json:"payload"`
 	}
-	json.NewDecoder(r.Body).Decode(&b)
-	h.service.UpdateEvent(r.Context(), mux.Vars(r)["event_id"], b.Payload)
-	response.JSON(w, 200, "Updated", nil)
+	if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+	pID := h.getPID(r)
+	event, channel, err := h.service.UpdateEvent(r.Context(), pID, mux.Vars(r)["event_id"], b.Payload)
+	if err != nil {
+		response.Error(w, http.StatusNotFound, "Failed to update event", err.Error())
+		return
+	}
+
+	// ✏️ Subscribers-ka live-ka ah ha cusboonaysiiyaan fariinta (chat edit)
+	h.BroadcastToChannel(pID, channel.Name, "event_updated", map[string]interface{}{
+		"id":         event.ID,
+		"seq":        event.Sequence,
+		"payload":    json.RawMessage(event.Payload),
+		"edited_at":  event.EditedAt,
+		"edit_count": event.EditCount,
+	}, "")
+	response.JSON(w, 200, "Updated", event)
 }
 func (h *RealtimeHandler) DeleteEvent(w http.ResponseWriter, r *http.Request) {
-	h.service.DeleteEvent(r.Context(), mux.Vars(r)["event_id"])
+	pID := h.getPID(r)
+	event, channel, err := h.service.DeleteEvent(r.Context(), pID, mux.Vars(r)["event_id"])
+	if err != nil {
+		response.Error(w, http.StatusNotFound, "Failed to delete event", err.Error())
+		return
+	}
+
+	// 🗑️ Subscribers-ka ha ka saaraan fariinta (retract)
+	h.BroadcastToChannel(pID, channel.Name, "event_deleted", map[string]interface{}{
+		"id":  event.ID,
+		"seq": event.Sequence,
+	}, "")
 	response.JSON(w, 200, "Deleted", nil)
 }
 func (h *RealtimeHandler) BroadcastToProject(pID, event string, payload interface{}) {
-	h.projectsMux.RLock()
-	clients := h.projects[pID]
-	h.projectsMux.RUnlock()
 	data, _ := json.Marshal(map[string]interface{}{"event_type": event, "payload": payload, "timestamp": time.Now()})
-	for client := range clients {
-		select {
-		case client.Send <- data:
-		default:
-		}
-	}
+	h.deliverToProject(pID, data)
+	h.publish(wsbus.Message{Kind: wsbus.KindProject, ProjectID: pID, Data: data})
 }
 
 // 📺 1. CREATE CHANNEL (Kani waa kan SDK-gu waco marka uu bilaabanayo)
@@ -269,6 +907,14 @@ func (h *RealtimeHandler) CreateChannel(w http.ResponseWriter, r *http.Request)
 	// 🚀 XALKA: Halkii aan h.channelRepo.Create wici lahayn, waxaan wacaynaa JoinChannel
 	// JoinChannel ayaa isagu aqoon u leh inuu "Get or Create" sameeyo
 	finalChannel, err := h.service.JoinChannel(r.Context(), pID, ch.Name, "")
+	if err == services.ErrRealtimeChannelLimit {
+		response.Error(w, http.StatusForbidden, "Realtime channel limit reached", "limit_reached_realtime_channels")
+		return
+	}
+	if err == services.ErrRealtimeInvalidChannel {
+		response.Error(w, http.StatusBadRequest, "Invalid channel name", err.Error())
+		return
+	}
 	if err != nil {
 		response.Error(w, 500, "Failed to initialize channel", err.Error())
 		return
@@ -277,39 +923,89 @@ func (h *RealtimeHandler) CreateChannel(w http.ResponseWriter, r *http.Request)
 	response.JSON(w, 201, "Success", finalChannel)
 }
 
-// 📩 2. CREATE EVENT (Kani waa kan fariimaha pgAdmin ku ridaya)
+// 📩 2. CREATE EVENT: publishing-ka SSE / long-poll (iyo backend-yada) — isla rules-ka WebSocket BROADCAST
+// Body: {"event_type": "...", "payload": {...}}. Sender-ka waxaa laga qaadaa JWT-ga, lagama aamino body-ga.
+// Private channel-ka callback-ga lagu galay: ?connection_id= (SSE connection_id / long-poll session_id) oo subscribed ah.
 func (h *RealtimeHandler) CreateEvent(w http.ResponseWriter, r *http.Request) {
-	pID := h.getPID(r)
-	channelIDStr := mux.Vars(r)["channel_id"]
-
-	// Hubi ID-ga uu SDK-gu soo diray
-	chanID, err := uuid.Parse(channelIDStr)
-	if err != nil {
-		response.Error(w, 400, "Invalid Channel UUID")
+	pID, userID, ok := h.authenticateRequest(w, r)
+	if !ok {
+		return
+	}
+	channel, err := h.service.GetChannelByID(r.Context(), mux.Vars(r)["channel_id"])
+	if err != nil || channel.ProjectID != pID {
+		response.Error(w, http.StatusNotFound, "Channel not found")
 		return
 	}
 
-	var event models.RealtimeEvent
-	if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+	var body struct {
+		EventType models.RealtimeEventType `json:"event_type"`
+		Payload   json.RawMessage          `json:"payload"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || len(body.Payload) == 0 {
 		response.Error(w, 400, "Invalid JSON payload")
 		return
 	}
-	event.ChannelID = chanID // 👈 Ku xir ID-ga saxda ah
+
+	if err := h.authorizePublish(r, channel, userID); err != nil {
+		response.Error(w, channelErrorStatus(err), "Publish to "+channel.Name+" rejected", map[string]string{
+			"code":    errorCode(err),
+			"message": err.Error(),
+		})
+		return
+	}
+
+	event := models.RealtimeEvent{
+		ChannelID: channel.ID,
+		EventType: body.EventType,
+		Payload:   datatypes.JSON(body.Payload),
+	}
+	if event.EventType == "" {
+		event.EventType = models.EventTypeCustom
+	}
+	if userID != "" {
+		event.SenderID = &userID
+	}
 
 	// 💾 SAVE TO pgAdmin
 	if err := h.service.CreateEvent(r.Context(), pID, &event); err != nil {
+		if err == services.ErrRealtimeEventLimit {
+			response.Error(w, http.StatusForbidden, "Realtime event limit reached", "limit_reached_realtime_events")
+			return
+		}
 		fmt.Printf("❌ [DB ERROR]: Event save failed: %v\n", err)
 		response.Error(w, 500, "Failed to save event")
 		return
 	}
 
-	fmt.Printf("✅ [DB SUCCESS]: Event [%s] saved to pgAdmin\n", event.EventType)
+	// 📢 LIVE BROADCAST (id + seq si subscribers-ku u ACK gareeyaan ama u resume gareeyaan)
+	h.broadcastEvent(pID, channel.Name, &event)
+	response.JSON(w, 201, "Created & Saved", event)
+}
 
-	// 📢 LIVE BROADCAST
-	channel, _ := h.service.GetChannelByID(r.Context(), channelIDStr)
-	if channel != nil {
-		h.BroadcastToChannel(pID, channel.Name, string(event.EventType), event.Payload, "sdk_user")
+// authorizePublish: AuthorizeChannel (channel rules + ban/mute). Private channel-ka uu callback/token
+// ku galay: connection-ka (isla project + user) oo node-kan ku subscribed ah ayaa dirkara, sida WebSocket-ka.
+func (h *RealtimeHandler) authorizePublish(r *http.Request, channel *models.RealtimeChannel, userID string) error {
+	err := h.service.AuthorizeChannel(r.Context(), channel, userID, services.ChannelActionBroadcast)
+	if err != services.ErrRealtimeForbidden && err != services.ErrRealtimeAuthRequired {
+		return err
+	}
+	connID := r.URL.Query().Get("connection_id")
+	if connID == "" {
+		return err
 	}
 
-	response.JSON(w, 201, "Created & Saved", event)
+	h.projectsMux.RLock()
+	defer h.projectsMux.RUnlock()
+	for c := range h.projects[channel.ProjectID] {
+		if c.ID != connID || c.UserID != userID {
+			continue
+		}
+		c.mu.Lock()
+		_, subscribed := c.channelIDs[channel.Name]
+		c.mu.Unlock()
+		if subscribed {
+			return nil
+		}
+	}
+	return err
 }
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"superaib/internal/core/logger"
+	"superaib/internal/models"
+	"superaib/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// response.Error wuxuu u baahan yahay logger-ka
+func TestMain(m *testing.M) {
+	logger.Init()
+	os.Exit(m.Run())
+}
+
+type stubRealtimeService struct {
+	services.RealtimeService
+	channels map[string]*models.RealtimeChannel
+	created  []*models.RealtimeEvent
+}
+
+func (s *stubRealtimeService) GetChannelByID(ctx context.Context, id string) (*models.RealtimeChannel, error) {
+	if ch, ok := s.channels[id]; ok {
+		return ch, nil
+	}
+	return nil, services.ErrRealtimeChannelNotFound
+}
+
+func (s *stubRealtimeService) AuthorizeChannel(ctx context.Context, ch *models.RealtimeChannel, userID string, action services.ChannelAction) error {
+	if ch.IsPrivate && userID == "" {
+		return services.ErrRealtimeAuthRequired
+	}
+	return nil
+}
+
+func (s *stubRealtimeService) CreateEvent(ctx context.Context, projectID string, event *models.RealtimeEvent) error {
+	event.ID = uuid.New()
+	s.created = append(s.created, event)
+	return nil
+}
+
+func TestCreateEventRules(t *testing.T) {
+	own := &models.RealtimeChannel{ID: uuid.New(), ProjectID: "p1", Name: "chat"}
+	other := &models.RealtimeChannel{ID: uuid.New(), ProjectID: "p2", Name: "chat"}
+	private := &models.RealtimeChannel{ID: uuid.New(), ProjectID: "p1", Name: "vip", IsPrivate: true}
+
+	tests := []struct {
+		name    string
+		channel *models.RealtimeChannel
+		body    string
+		status  int
+	}{
+		{"own channel", own, `{"event_type":"custom","payload":{"text":"hi"},"sender_id":"` + uuid.NewString() + `","ack_required":true}`, http.StatusCreated},
+		{"other project's channel", other, `{"payload":{"text":"hi"}}`, http.StatusNotFound},
+		{"private channel without user", private, `{"payload":{"text":"hi"}}`, http.StatusUnauthorized},
+		{"missing payload", own, `{"event_type":"custom"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &stubRealtimeService{channels: map[string]*models.RealtimeChannel{tt.channel.ID.String(): tt.channel}}
+			h := NewRealtimeHandler(svc, nil, RealtimeLimits{})
+			router := mux.NewRouter()
+			router.HandleFunc("/projects/{project_id}/channels/{channel_id}/events", h.CreateEvent)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/projects/p1/channels/"+tt.channel.ID.String()+"/events", strings.NewReader(tt.body))
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body = %s", w.Code, tt.status, w.Body.String())
+			}
+			if tt.status != http.StatusCreated {
+				if len(svc.created) != 0 {
+					t.Fatalf("event saved on a rejected publish")
+				}
+				return
+			}
+			// sender_id iyo ack_required body-ga lagama aamino
+			if ev := svc.created[0]; ev.SenderID != nil || ev.AckRequired {
+				t.Fatalf("body fields trusted: sender=%v ack=%v", ev.SenderID, ev.AckRequired)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"fmt"
+	wsbus "superaib/pkg/websocket"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"golang.org/x/time/rate"
+)
+
+// 🚦 Abuse protection: hal client oo xumaaday ma buuxin karo DB-ga iyo subscribers-ka
+const violationWindow = time.Minute
+
+// RealtimeLimits: Xadka WebSocket-ka (config-ka ayaa laga buuxiyaa; 0 = default)
+type RealtimeLimits struct {
+	MaxFrameBytes    int64   // SetReadLimit: frame ka weyn connection-ka waa la xiraa (1009)
+	ConnRate         float64 // Frames ilbiriqsi kasta hal connection (token bucket)
+	ConnBurst        int
+	ProjectRate      float64 // BROADCAST ilbiriqsi kasta hal project (node-kan)
+	ProjectBurst     int
+	MaxSubscriptions int // Channels + patterns hal connection
+	MaxViolations    int // Violations daqiiqad gudaheed ka hor disconnect (1008)
+}
+
+func (l RealtimeLimits) withDefaults() RealtimeLimits {
+	if l.MaxFrameBytes <= 0 {
+		l.MaxFrameBytes = 64 * 1024
+	}
+	if l.ConnRate <= 0 {
+		l.ConnRate = 20
+	}
+	if l.ConnBurst <= 0 {
+		l.ConnBurst = 40
+	}
+	if l.ProjectRate <= 0 {
+		l.ProjectRate = 500
+	}
+	if l.ProjectBurst <= 0 {
+		l.ProjectBurst = 1000
+	}
+	if l.MaxSubscriptions <= 0 {
+		l.MaxSubscriptions = 100
+	}
+	if l.MaxViolations <= 0 {
+		l.MaxViolations = 20
+	}
+	return l
+}
+
+// projectLimiter: Bucket-ka BROADCAST ee project-ka (node kasta wuxuu leeyahay mid u gaar ah)
+func (h *RealtimeHandler) projectLimiter(pID string) *rate.Limiter {
+	h.limitersMux.Lock()
+	defer h.limitersMux.Unlock()
+	l, ok := h.projectLimiters[pID]
+	if !ok {
+		l = rate.NewLimiter(rate.Limit(h.limits.ProjectRate), h.limits.ProjectBurst)
+		h.projectLimiters[pID] = l
+	}
+	return l
+}
+
+// evictIdleLimiters: metricsLoop; project aan connection ku lahayn node-kan oo bucket-kiisu buuxo
+// (tokens == burst) waa la tirtiraa — limiter cusub oo buuxa ayaa la abuuraa marka uu soo laabto
+func (h *RealtimeHandler) evictIdleLimiters() {
+	h.limitersMux.Lock()
+	defer h.limitersMux.Unlock()
+	for pID, l := range h.projectLimiters {
+		if l.Tokens() >= float64(l.Burst()) && len(h.clientsOf(pID)) == 0 {
+			delete(h.projectLimiters, pID)
+		}
+	}
+}
+
+// checkRate: Connection bucket frame kasta; project bucket kaliya BROADCAST/PATCH (DB write + fan-out)
+func (h *RealtimeHandler) checkRate(c *Client, action string) error {
+	if !c.limiter.Allow() {
+		return newProtocolError(wsbus.CodeRateLimited, fmt.Sprintf("connection rate limit exceeded (%.0f frames/s)", h.limits.ConnRate))
+	}
+	if (action == "BROADCAST" || action == "PATCH") && !h.projectLimiter(c.ProjectID).Allow() {
+		return newProtocolError(wsbus.CodeRateLimited, "project broadcast rate limit exceeded")
+	}
+	return nil
+}
+
+// recordViolation: true marka client-ku gaaro MaxViolations daqiiqad gudaheed
+func (h *RealtimeHandler) recordViolation(c *Client) bool {
+	now := time.Now()
+	if now.Sub(c.violationsSince) > violationWindow {
+		c.violations = 0
+		c.violationsSince = now
+	}
+	c.violations++
+	return c.violations >= h.limits.MaxViolations
+}
+
+// disconnect: Xir connection-ka oo sheeg sababta (close frame); readPump-ka ayaa nadiifiya
+func (h *RealtimeHandler) disconnect(c *Client, code int, reason string) {
+	fmt.Printf("🚫 [Realtime] Disconnecting conn %s (project %s): %s\n", c.ID, c.ProjectID, reason)
+	_ = c.Conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(time.Second))
+	c.Conn.Close()
+}
+
+// checkSubscriptionLimit: Channel cusub kaliya ayaa la tiriyaa (resubscribe waa la oggol yahay)
+func (h *RealtimeHandler) checkSubscriptionLimit(c *Client, name string) error {
+	c.mu.Lock()
+	n, already := len(c.Channels), c.Channels[name]
+	c.mu.Unlock()
+	if !already && n >= h.limits.MaxSubscriptions {
+		return newProtocolError(wsbus.CodeTooManySubscriptions, fmt.Sprintf("a connection can hold at most %d subscriptions", h.limits.MaxSubscriptions))
+	}
+	return nil
+}
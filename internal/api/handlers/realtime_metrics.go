@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"superaib/internal/api/response"
+	wsbus "superaib/pkg/websocket"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// 📊 Operational metrics: node-kan kaliya (replica kasta wuxuu leeyahay tirooyinkiisa; node_id ayaa la celiyaa).
+// Counters-ka waa atomic si hot path-ka (send) aan lock loo qaadan; rates-ka waxaa xisaabiya metricsLoop.
+const metricsInterval = 5 * time.Second
+
+const (
+	transportWebSocket = "websocket"
+	transportSSE       = "sse"
+	transportLongPoll  = "long_poll"
+)
+
+// trafficCounters: in = frames client-ka ka yimid, out = frames send queue-ga la galiyay, dropped = queue buuxa
+type trafficCounters struct {
+	in, out, dropped atomic.Int64
+}
+
+// projectStats: Counters + rates-kii ugu dambeeyay ee project-ka
+type projectStats struct {
+	trafficCounters
+
+	mu                        sync.Mutex
+	lastIn, lastOut, lastDrop int64
+	rateIn, rateOut, rateDrop float64
+	sampledAt                 time.Time
+}
+
+func (h *RealtimeHandler) statsFor(pID string) *projectStats {
+	if v, ok := h.stats.Load(pID); ok {
+		return v.(*projectStats)
+	}
+	v, _ := h.stats.LoadOrStore(pID, &projectStats{sampledAt: time.Now()})
+	return v.(*projectStats)
+}
+
+// send: Meesha kaliya ee frames-ka lagu riixo c.Send (non-blocking); false = buffer-ku wuu buuxaa
+func (h *RealtimeHandler) send(c *Client, data []byte) bool {
+	ps := h.statsFor(c.ProjectID)
+	select {
+	case c.Send <- data:
+		c.stats.out.Add(1)
+		ps.out.Add(1)
+		return true
+	default:
+		c.stats.dropped.Add(1)
+		ps.dropped.Add(1)
+		return false
+	}
+}
+
+func (h *RealtimeHandler) countIn(c *Client) {
+	c.stats.in.Add(1)
+	h.statsFor(c.ProjectID).in.Add(1)
+}
+
+// metricsLoop: Rates (per second) + nadiifi projects-ka aan connection lahayn
+func (h *RealtimeHandler) metricsLoop(ctx context.Context) {
+	ticker := time.NewTicker(metricsInterval)
+	defer ticker.Stop()
+	for {
+		var now time.Time
+		select {
+		case <-ctx.Done():
+			return
+		case now = <-ticker.C:
+		}
+
+		h.stats.Range(func(key, value interface{}) bool {
+			ps := value.(*projectStats)
+			in, out, drop := ps.in.Load(), ps.out.Load(), ps.dropped.Load()
+
+			ps.mu.Lock()
+			secs := now.Sub(ps.sampledAt).Seconds()
+			if secs > 0 {
+				ps.rateIn = float64(in-ps.lastIn) / secs
+				ps.rateOut = float64(out-ps.lastOut) / secs
+				ps.rateDrop = float64(drop-ps.lastDrop) / secs
+			}
+			idle := in == ps.lastIn && out == ps.lastOut && drop == ps.lastDrop
+			ps.lastIn, ps.lastOut, ps.lastDrop, ps.sampledAt = in, out, drop, now
+			ps.mu.Unlock()
+
+			if idle && len(h.clientsOf(key.(string))) == 0 {
+				h.stats.Delete(key)
+			}
+			return true
+		})
+		h.evictIdleLimiters()
+	}
+}
+
+// connectionInfo: Hal connection (inspector-ka dashboard-ka)
+type connectionInfo struct {
+	ID            string    `json:"id"`
+	UserID        string    `json:"user_id,omitempty"`
+	Transport     string    `json:"transport"`
+	Protocol      string    `json:"protocol,omitempty"`
+	RemoteAddr    string    `json:"remote_addr"`
+	ConnectedAt   time.Time `json:"connected_at"`
+	Channels      []string  `json:"channels"`
+	QueueDepth    int       `json:"queue_depth"`
+	QueueCapacity int       `json:"queue_capacity"`
+	PendingAcks   int       `json:"pending_acks"`
+	MessagesIn    int64     `json:"messages_in"`
+	MessagesOut   int64     `json:"messages_out"`
+	Dropped       int64     `json:"dropped"`
+}
+
+func (h *RealtimeHandler) inspect(c *Client) connectionInfo {
+	c.mu.Lock()
+	channels := make([]string, 0, len(c.Channels))
+	for name := range c.Channels {
+		channels = append(channels, name)
+	}
+	pending := len(c.pending)
+	c.mu.Unlock()
+	sort.Strings(channels)
+
+	return connectionInfo{
+		ID:            c.ID,
+		UserID:        c.UserID,
+		Transport:     c.transport,
+		Protocol:      c.protocol,
+		RemoteAddr:    c.remoteAddr,
+		ConnectedAt:   c.connectedAt,
+		Channels:      channels,
+		QueueDepth:    len(c.Send),
+		QueueCapacity: cap(c.Send),
+		PendingAcks:   pending,
+		MessagesIn:    c.stats.in.Load(),
+		MessagesOut:   c.stats.out.Load(),
+		Dropped:       c.stats.dropped.Load(),
+	}
+}
+
+// =========================================================================
+// 🛠️ REST (DASHBOARD)
+// =========================================================================
+
+// GetMetrics: GET /realtime/admin/metrics
+func (h *RealtimeHandler) GetMetrics(w http.ResponseWriter, r *http.Request) {
+	pID := h.getPID(r)
+	clients := h.clientsOf(pID)
+
+	byTransport := map[string]int{}
+	subscriptions := map[string]int{}
+	users := map[string]bool{}
+	queued, maxQueue := 0, 0
+	for _, c := range clients {
+		byTransport[c.transport]++
+		if c.UserID != "" {
+			users[c.UserID] = true
+		}
+		c.mu.Lock()
+		for name := range c.Channels {
+			subscriptions[name]++
+		}
+		c.mu.Unlock()
+		depth := len(c.Send)
+		queued += depth
+		if depth > maxQueue {
+			maxQueue = depth
+		}
+	}
+
+	ps := h.statsFor(pID)
+	ps.mu.Lock()
+	rates := map[string]float64{
+		"messages_in_per_sec":  ps.rateIn,
+		"messages_out_per_sec": ps.rateOut,
+		"dropped_per_sec":      ps.rateDrop,
+	}
+	ps.mu.Unlock()
+
+	response.JSON(w, http.StatusOK, "Success", map[string]interface{}{
+		"node_id":                   h.backplane.NodeID(),
+		"connections":               len(clients),
+		"connections_by_transport":  byTransport,
+		"unique_users":              len(users),
+		"subscriptions_per_channel": subscriptions,
+		"rates":                     rates,
+		"totals": map[string]int64{
+			"messages_in":  ps.in.Load(),
+			"messages_out": ps.out.Load(),
+			"dropped":      ps.dropped.Load(),
+		},
+		"send_queue": map[string]int{
+			"queued_total": queued,
+			"max_depth":    maxQueue,
+		},
+		"sample_interval_seconds": metricsInterval.Seconds(),
+	})
+}
+
+// ListConnections: GET /realtime/admin/connections[?user_id=&channel=]
+func (h *RealtimeHandler) ListConnections(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	channel := r.URL.Query().Get("channel")
+
+	list := make([]connectionInfo, 0)
+	for _, c := range h.clientsOf(h.getPID(r)) {
+		if userID != "" && c.UserID != userID {
+			continue
+		}
+		if channel != "" {
+			c.mu.Lock()
+			subscribed := c.Channels[channel]
+			c.mu.Unlock()
+			if !subscribed {
+				continue
+			}
+		}
+		list = append(list, h.inspect(c))
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ConnectedAt.Before(list[j].ConnectedAt) })
+
+	response.JSON(w, http.StatusOK, "Success", map[string]interface{}{
+		"node_id":     h.backplane.NodeID(),
+		"connections": list,
+	})
+}
+
+// CloseConnection: DELETE /realtime/admin/connections/{connection_id}
+// Connection-ku node kale ayuu ku jiri karaa, sidaas darteed amarka backplane-ka ayaa la marsiiyaa.
+func (h *RealtimeHandler) CloseConnection(w http.ResponseWriter, r *http.Request) {
+	pID := h.getPID(r)
+	notice := moderationNotice{Action: moderationDisconnect, ConnectionID: mux.Vars(r)["connection_id"]}
+
+	local := h.applyModeration(pID, notice)
+	if !local {
+		data, _ := json.Marshal(notice)
+		h.publish(wsbus.Message{Kind: wsbus.KindModeration, ProjectID: pID, Data: data})
+	}
+	response.JSON(w, http.StatusAccepted, "Closing", map[string]interface{}{
+		"connection_id": notice.ConnectionID,
+		"local":         local,
+	})
+}
@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"superaib/internal/api/response"
+	"superaib/internal/models"
+	wsbus "superaib/pkg/websocket"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+)
+
+// 🛡️ Moderation: kick (live kaliya), ban iyo mute (DB-ga ayaa lagu kaydiyaa).
+// Go'aan kasta wuxuu si degdeg ah u gaaraa connections-ka nodes-ka oo dhan (KindModeration);
+// subscribe/broadcast kasta oo dambe waxaa hubiya AuthorizeChannel (CheckModeration).
+
+// moderationNotice: Waxa backplane-ka la marsiiyo; Channel "" = project-ka oo dhan
+type moderationNotice struct {
+	Action       string     `json:"action"` // kick | ban | mute | lift | disconnect
+	UserID       string     `json:"user_id,omitempty"`
+	ConnectionID string     `json:"connection_id,omitempty"` // disconnect: hal connection (dashboard inspector)
+	Channel      string     `json:"channel,omitempty"`
+	Reason       string     `json:"reason,omitempty"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+}
+
+const (
+	moderationLift       = "lift"
+	moderationDisconnect = "disconnect"
+)
+
+// moderate: Ku fuli node-kan kadibna u gudbi nodes-ka kale
+func (h *RealtimeHandler) moderate(pID string, notice moderationNotice) {
+	h.applyModeration(pID, notice)
+	data, _ := json.Marshal(notice)
+	h.publish(wsbus.Message{Kind: wsbus.KindModeration, ProjectID: pID, UserIDs: []string{notice.UserID}, Data: data})
+}
+
+// applyModeration: Connections-ka user-ka ee node-kan (transport kasta); true haddii mid la helay
+func (h *RealtimeHandler) applyModeration(pID string, notice moderationNotice) bool {
+	found := false
+	for _, c := range h.clientsOf(pID) {
+		if notice.Action == moderationDisconnect {
+			if c.ID == notice.ConnectionID {
+				h.terminate(c, "closed by project admin")
+				return true
+			}
+			continue
+		}
+		if c.UserID == "" || c.UserID != notice.UserID {
+			continue
+		}
+		found = true
+
+		switch notice.Action {
+		case string(models.ModerationKick):
+			c.mu.Lock()
+			subscribed := c.Channels[notice.Channel]
+			c.mu.Unlock()
+			if !subscribed {
+				continue // Tab-yada kale ee user-ka ma aha in loo sheego
+			}
+			h.unsubscribe(c, notice.Channel)
+
+		case string(models.ModerationBan):
+			if notice.Channel == "" {
+				h.notifyModeration(c, "banned", notice)
+				h.terminate(c, "banned")
+				continue
+			}
+			h.unsubscribe(c, notice.Channel)
+			// Pattern subscriptions ("chat.*") sidoo kale ha u gudbin channel-kan
+			c.mu.Lock()
+			c.patternAuth[notice.Channel] = patternDecision{allowed: false, at: time.Now()}
+			c.mu.Unlock()
+
+		case moderationLift:
+			// Go'aannadii pattern-ka ee hore (ban) mar kale ha la hubiyo
+			c.mu.Lock()
+			for name, d := range c.patternAuth {
+				if !d.allowed {
+					delete(c.patternAuth, name)
+				}
+			}
+			c.mu.Unlock()
+		}
+
+		event := map[string]string{
+			string(models.ModerationKick): "kicked",
+			string(models.ModerationBan):  "banned",
+			string(models.ModerationMute): "muted",
+			moderationLift:                "moderation_lifted",
+		}[notice.Action]
+		if event != "" {
+			h.notifyModeration(c, event, notice)
+		}
+	}
+	return found
+}
+
+func (h *RealtimeHandler) notifyModeration(c *Client, event string, notice moderationNotice) {
+	data, _ := json.Marshal(map[string]interface{}{
+		"channel":    notice.Channel,
+		"event_type": event,
+		"payload": map[string]interface{}{
+			"reason":     notice.Reason,
+			"expires_at": notice.ExpiresAt,
+		},
+		"timestamp": time.Now(),
+	})
+	h.send(c, data)
+}
+
+// terminate: Xir connection-ka server-ka dhinaciisa (WebSocket close frame; SSE/long-poll c.closed)
+func (h *RealtimeHandler) terminate(c *Client, reason string) {
+	if c.Conn != nil {
+		h.disconnect(c, websocket.ClosePolicyViolation, reason)
+		return
+	}
+	c.closeOnce.Do(func() { close(c.closed) })
+
+	h.pollMux.Lock()
+	_, polling := h.pollSessions[c.ID]
+	delete(h.pollSessions, c.ID)
+	h.pollMux.Unlock()
+	if polling {
+		h.unregisterClient(c)
+		go h.leaveAll(c)
+	}
+}
+
+// =========================================================================
+// 🛠️ REST (DASHBOARD)
+// =========================================================================
+
+// KickFromChannel: POST /admin/channels/{channel_id}/kick {"user_id", "reason"} — wuu soo laaban karaa
+func (h *RealtimeHandler) KickFromChannel(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		UserID string `json:"user_id"`
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.UserID == "" {
+		response.Error(w, http.StatusBadRequest, "user_id is required")
+		return
+	}
+
+	pID := h.getPID(r)
+	channel, err := h.service.GetChannelByID(r.Context(), mux.Vars(r)["channel_id"])
+	if err != nil || channel.ProjectID != pID {
+		response.Error(w, http.StatusNotFound, "Channel not found")
+		return
+	}
+
+	h.moderate(pID, moderationNotice{
+		Action:  string(models.ModerationKick),
+		UserID:  body.UserID,
+		Channel: channel.Name,
+		Reason:  body.Reason,
+	})
+	response.JSON(w, http.StatusOK, "Kicked", map[string]interface{}{
+		"user_id": body.UserID,
+		"channel": channel.Name,
+	})
+}
+
+// Moderate: POST /admin/moderation/{action:ban|mute} {"user_id", "channel_id"?, "duration_seconds"?, "reason"}
+func (h *RealtimeHandler) Moderate(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		UserID          string     `json:"user_id"`
+		ChannelID       *uuid.UUID `json:"channel_id"`
+		DurationSeconds int64      `json:"duration_seconds"` // 0 = weligeed
+		Reason          string     `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	pID := h.getPID(r)
+	m := &models.RealtimeModeration{
+		ProjectID: pID,
+		UserID:    body.UserID,
+		ChannelID: body.ChannelID,
+		Action:    models.RealtimeModerationAction(mux.Vars(r)["action"]),
+		Reason:    body.Reason,
+	}
+	if err := h.service.Moderate(r.Context(), m, time.Duration(body.DurationSeconds)*time.Second); err != nil {
+		response.Error(w, http.StatusBadRequest, "Failed to apply moderation", err.Error())
+		return
+	}
+
+	h.moderate(pID, moderationNotice{
+		Action:    string(m.Action),
+		UserID:    m.UserID,
+		Channel:   m.ChannelName,
+		Reason:    m.Reason,
+		ExpiresAt: m.ExpiresAt,
+	})
+	response.JSON(w, http.StatusCreated, "Applied", m)
+}
+
+// ListModerations: GET /admin/moderation[?channel_id=] (kuwa weli shaqeeya oo kaliya)
+func (h *RealtimeHandler) ListModerations(w http.ResponseWriter, r *http.Request) {
+	list, err := h.service.ListModerations(r.Context(), h.getPID(r), r.URL.Query().Get("channel_id"))
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, "Failed to list moderations", err.Error())
+		return
+	}
+	response.JSON(w, http.StatusOK, "Success", list)
+}
+
+// LiftModeration: DELETE /admin/moderation/{moderation_id} (unban / unmute)
+func (h *RealtimeHandler) LiftModeration(w http.ResponseWriter, r *http.Request) {
+	pID := h.getPID(r)
+	m, err := h.service.LiftModeration(r.Context(), pID, mux.Vars(r)["moderation_id"])
+	if err != nil {
+		response.Error(w, http.StatusNotFound, "Moderation not found")
+		return
+	}
+
+	h.moderate(pID, moderationNotice{Action: moderationLift, UserID: m.UserID, Channel: m.ChannelName})
+	response.JSON(w, http.StatusOK, "Lifted", m)
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"superaib/internal/api/response"
+	"superaib/internal/models"
+	"superaib/internal/services"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// presenceTTL: Connection aan heartbeat la helin muddadan waa la saarayaa (node dhintay, socket go'ay)
+const (
+	presenceSweepInterval = 30 * time.Second
+	presenceTTL           = 90 * time.Second
+)
+
+func presencePayload(p *models.RealtimePresence) map[string]interface{} {
+	return map[string]interface{}{
+		"presence_key": p.PresenceKey,
+		"user_id":      p.UserID,
+		"metadata":     p.Metadata,
+	}
+}
+
+// joinPresence: Diiwaangeli connection-ka, u sheeg kuwa kale (presence_join), una dir client-ka liiska (presence_state)
+// Error-ka kaliya ee la soo celiyo waa ErrRealtimeChannelFull (subscribe-ka waa la diidayaa)
+func (h *RealtimeHandler) joinPresence(c *Client, channel *models.RealtimeChannel, meta map[string]interface{}) error {
+	ctx := context.Background()
+	joined, err := h.service.TrackJoin(ctx, channel, c.ID, c.UserID, h.backplane.NodeID(), meta)
+	if err == services.ErrRealtimeChannelFull {
+		return err
+	}
+	if err != nil {
+		fmt.Printf("❌ [Presence] Join failed: %v\n", err)
+		return nil
+	}
+
+	if joined {
+		metaJSON, _ := json.Marshal(meta)
+		p := &models.RealtimePresence{PresenceKey: c.UserID, Metadata: metaJSON}
+		if c.UserID == "" {
+			p.PresenceKey = "anon:" + c.ID
+		} else {
+			uid := c.UserID
+			p.UserID = &uid
+		}
+		h.BroadcastToChannel(c.ProjectID, channel.Name, "presence_join", presencePayload(p), c.UserID)
+	}
+
+	members, err := h.service.ListPresence(ctx, channel.ID.String())
+	if err != nil {
+		return nil
+	}
+	data, _ := json.Marshal(map[string]interface{}{
+		"channel":    channel.Name,
+		"event_type": "presence_state",
+		"payload":    map[string]interface{}{"count": len(members), "members": members},
+		"timestamp":  time.Now(),
+	})
+	h.send(c, data)
+	return nil
+}
+
+// leavePresence: Ka saar connection-ka; presence_leave kaliya marka tab-kii ugu dambeeyay baxo
+func (h *RealtimeHandler) leavePresence(projectID string, channelID uuid.UUID, connectionID string) {
+	p, left, err := h.service.TrackLeave(context.Background(), channelID, connectionID)
+	if err != nil || p == nil || !left {
+		return
+	}
+	h.BroadcastToChannel(projectID, p.ChannelName, "presence_leave", presencePayload(p), "")
+}
+
+func (h *RealtimeHandler) leaveAll(c *Client) {
+	c.mu.Lock()
+	ids := make([]uuid.UUID, 0, len(c.channelIDs))
+	for _, id := range c.channelIDs {
+		ids = append(ids, id)
+	}
+	c.mu.Unlock()
+
+	for _, id := range ids {
+		h.leavePresence(c.ProjectID, id, c.ID)
+	}
+}
+
+// StartPresenceSweeper: Heartbeat connections-ka node-kan + saar kuwa dhintay (node kasta wuu wadaa)
+func (h *RealtimeHandler) StartPresenceSweeper(ctx context.Context) {
+	ticker := time.NewTicker(presenceSweepInterval)
+	go func() {
+		defer ticker.Stop()
+		fmt.Println("💓 [SYSTEM] Realtime presence sweeper is running...")
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			// 1. Heartbeat: connections-ka weli nool ee node-kan
+			h.projectsMux.RLock()
+			var ids []string
+			for _, clients := range h.projects {
+				for c := range clients {
+					ids = append(ids, c.ID)
+				}
+			}
+			h.projectsMux.RUnlock()
+			if err := h.service.HeartbeatPresence(ctx, ids); err != nil {
+				fmt.Printf("❌ [Presence] Heartbeat failed: %v\n", err)
+			}
+
+			// 2. Eviction: kuwa aan heartbeat la helin (socket dhintay ama node burburay)
+			evicted, err := h.service.EvictStalePresence(ctx, time.Now().Add(-presenceTTL))
+			if err != nil {
+				fmt.Printf("❌ [Presence] Eviction failed: %v\n", err)
+				continue
+			}
+			for i := range evicted {
+				p := &evicted[i]
+				h.BroadcastToChannel(p.ProjectID, p.ChannelName, "presence_leave", presencePayload(p), "")
+			}
+		}
+	}()
+}
+
+// GetPresence: GET /channels/{channel_id}/presence (Yaa hadda online ah?)
+func (h *RealtimeHandler) GetPresence(w http.ResponseWriter, r *http.Request) {
+	channel, err := h.service.GetChannelByID(r.Context(), mux.Vars(r)["channel_id"])
+	if err != nil || channel.ProjectID != h.getPID(r) {
+		response.Error(w, http.StatusNotFound, "Channel not found")
+		return
+	}
+	members, err := h.service.ListPresence(r.Context(), channel.ID.String())
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, "Failed to load presence", err.Error())
+		return
+	}
+	response.JSON(w, http.StatusOK, "Success", map[string]interface{}{
+		"count":   len(members),
+		"members": members,
+	})
+}
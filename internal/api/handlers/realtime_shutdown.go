@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// 🛑 Graceful drain (deploy / SIGTERM):
+//  1. Upgrades cusub (WebSocket, SSE, long-poll) waxaa lagu celiyaa 503 + Retry-After
+//  2. Client kasta wuxuu helaa "reconnect" frame {backoff_ms} (jitter si aysan dhamaantood isla mar u soo laaban)
+//  3. Send buffers-ka waa la sugaa inta deadline-ku socdo, kadib WebSocket-ka waxaa lagu xiraa 1001 (going away)
+//     SSE/long-poll waxay ku dhammaadaan c.closed (fariimaha sugaya waa la gudbiyaa)
+const drainPollInterval = 50 * time.Millisecond
+
+// rejectIfDraining: true haddii server-ku xirmayo (jawaabta waa la qoray)
+func (h *RealtimeHandler) rejectIfDraining(w http.ResponseWriter) bool {
+	if !h.draining.Load() {
+		return false
+	}
+	w.Header().Set("Retry-After", "1")
+	http.Error(w, "server is shutting down, reconnect to another instance", http.StatusServiceUnavailable)
+	return true
+}
+
+// Shutdown: Drain connections-ka node-kan; ctx-ka deadline-kiisa kadib kuwa hadhay si xoog ah ayaa loo xiraa.
+// Kadib background loops-ka (backplane publish, redelivery, poll reaper, metrics) waa la joojiyaa oo la sugaa.
+func (h *RealtimeHandler) Shutdown(ctx context.Context, backoff time.Duration) error {
+	if !h.draining.CompareAndSwap(false, true) {
+		return nil
+	}
+	err := h.drain(ctx, backoff)
+
+	h.stop()
+	done := make(chan struct{})
+	go func() {
+		h.loops.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		if err == nil {
+			err = fmt.Errorf("realtime background loops did not stop: %w", ctx.Err())
+		}
+	}
+	return err
+}
+
+func (h *RealtimeHandler) drain(ctx context.Context, backoff time.Duration) error {
+	if backoff <= 0 {
+		backoff = 2 * time.Second
+	}
+
+	h.projectsMux.RLock()
+	var clients []*Client
+	for _, set := range h.projects {
+		for c := range set {
+			clients = append(clients, c)
+		}
+	}
+	h.projectsMux.RUnlock()
+	fmt.Printf("🛑 [Realtime] Draining %d connections...\n", len(clients))
+
+	// 1. reconnect frame: backoff ∈ [backoff, 3×backoff)
+	for _, c := range clients {
+		wait := backoff + time.Duration(rand.Int63n(int64(2*backoff)))
+		data, _ := json.Marshal(map[string]interface{}{
+			"event_type": "reconnect",
+			"payload": map[string]interface{}{
+				"reason":     "server_shutdown",
+				"backoff_ms": wait.Milliseconds(),
+			},
+			"timestamp": time.Now(),
+		})
+		h.send(c, data)
+	}
+
+	// 2. SSE / long-poll: stream-ka ayaa isagu gudbiya fariimaha sugaya kadibna xirma
+	var sockets []*Client
+	for _, c := range clients {
+		if c.Conn == nil {
+			h.terminate(c, "server shutdown")
+			continue
+		}
+		sockets = append(sockets, c)
+	}
+
+	// 3. WebSocket: sug in writePump uu faaruqiyo Send (ama deadline), kadib 1001.
+	// Buffer-ku waa inuu madhnaadaa laba jeer oo xiriir ah si frame-kii ugu dambeeyay uu u dhammaado qorista.
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	empty := make(map[*Client]bool, len(sockets))
+	for len(sockets) > 0 {
+		remaining := sockets[:0]
+		for _, c := range sockets {
+			if len(c.Send) > 0 || !empty[c] {
+				empty[c] = len(c.Send) == 0
+				remaining = append(remaining, c)
+				continue
+			}
+			h.goAway(c)
+		}
+		sockets = remaining
+		if len(sockets) == 0 {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			for _, c := range sockets {
+				h.goAway(c)
+			}
+			return fmt.Errorf("realtime drain deadline reached with %d unflushed connections: %w", len(sockets), ctx.Err())
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+func (h *RealtimeHandler) goAway(c *Client) {
+	_ = c.Conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutdown"),
+		time.Now().Add(time.Second))
+	c.Conn.Close()
+}
@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// Shutdown kadib background loops-ku way istaagaan (goroutines ma hadhaan)
+func TestShutdownStopsBackgroundLoops(t *testing.T) {
+	h := NewRealtimeHandler(&stubRealtimeService{}, nil, RealtimeLimits{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := h.Shutdown(ctx, time.Millisecond); err != nil {
+		t.Fatalf("Shutdown = %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		h.loops.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("background loops still running after Shutdown")
+	}
+}
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"superaib/internal/api/response"
+	"superaib/internal/models"
+	"superaib/internal/services"
+	"superaib/pkg/utils"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// 🧩 Shared-state channels (whiteboards, lobbies):
+//   - SUBSCRIBE  → state_snapshot {rev, state} (late joiners)
+//   - PATCH      {"channel", "ops": [RFC 6902]} → reply {rev}; subscribers-ka waxay helaan state_patch {rev, ops}
+//   - STATE      → reply {rev, state} (client-ku wuxuu arkay rev gap)
+// Patch-ka rev-kiisu <= snapshot-ka client-ka haysto waa la iska indho tiraa.
+
+func (h *RealtimeHandler) patchState(c *Client, msg *clientFrame) (interface{}, error) {
+	channel, err := h.authorize(c, msg.Channel, services.ChannelActionBroadcast)
+	if err != nil {
+		return nil, err
+	}
+	updated, err := h.service.PatchState(context.Background(), c.ProjectID, channel.ID, msg.Ops)
+	if err != nil {
+		return nil, err
+	}
+	h.broadcastPatch(c.ProjectID, updated, msg.Ops, c.UserID)
+	return map[string]interface{}{"channel": channel.Name, "rev": updated.StateRevision}, nil
+}
+
+func (h *RealtimeHandler) broadcastPatch(pID string, channel *models.RealtimeChannel, ops []utils.PatchOperation, senderID string) {
+	h.BroadcastToChannel(pID, channel.Name, "state_patch", map[string]interface{}{
+		"rev": channel.StateRevision,
+		"ops": ops,
+	}, senderID)
+}
+
+// currentState: Akhri state-ka DB-ga (ma aha cache) si snapshot-ku u noqdo mid cusub
+func (h *RealtimeHandler) currentState(c *Client, name string) (interface{}, error) {
+	channel, err := h.authorize(c, name, services.ChannelActionSubscribe)
+	if err != nil {
+		return nil, err
+	}
+	fresh, err := h.service.GetChannelByID(context.Background(), channel.ID.String())
+	if err != nil {
+		return nil, err
+	}
+	if fresh.Mode != models.ChannelModeSharedState {
+		return nil, services.ErrRealtimeNotSharedState
+	}
+	return statePayload(fresh), nil
+}
+
+// sendSnapshot: Subscribe kadib (index-ka kadib si patch dambe aan loo seegin)
+func (h *RealtimeHandler) sendSnapshot(c *Client, channel *models.RealtimeChannel) {
+	if channel == nil || channel.Mode != models.ChannelModeSharedState {
+		return
+	}
+	fresh, err := h.service.GetChannelByID(context.Background(), channel.ID.String())
+	if err != nil {
+		return
+	}
+	data, _ := json.Marshal(map[string]interface{}{
+		"channel":    fresh.Name,
+		"event_type": "state_snapshot",
+		"payload":    statePayload(fresh),
+		"timestamp":  time.Now(),
+	})
+	h.send(c, data)
+}
+
+func statePayload(ch *models.RealtimeChannel) map[string]interface{} {
+	state := json.RawMessage(ch.State)
+	if len(state) == 0 {
+		state = json.RawMessage("{}")
+	}
+	return map[string]interface{}{"rev": ch.StateRevision, "state": state}
+}
+
+// =========================================================================
+// 🛠️ REST: /channels/{channel_id}/state
+// =========================================================================
+
+func (h *RealtimeHandler) stateChannel(w http.ResponseWriter, r *http.Request) (*models.RealtimeChannel, bool) {
+	channel, err := h.service.GetChannelByID(r.Context(), mux.Vars(r)["channel_id"])
+	if err != nil || channel.ProjectID != h.getPID(r) {
+		response.Error(w, http.StatusNotFound, "Channel not found")
+		return nil, false
+	}
+	return channel, true
+}
+
+// GetState: GET /channels/{channel_id}/state
+func (h *RealtimeHandler) GetState(w http.ResponseWriter, r *http.Request) {
+	channel, ok := h.stateChannel(w, r)
+	if !ok {
+		return
+	}
+	payload := statePayload(channel)
+	payload["mode"] = channel.Mode
+	response.JSON(w, http.StatusOK, "Success", payload)
+}
+
+// PatchState: PATCH /channels/{channel_id}/state {"ops": [...]} (server-side update)
+func (h *RealtimeHandler) PatchState(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Ops []utils.PatchOperation `json:"ops"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+	channelID, err := uuid.Parse(mux.Vars(r)["channel_id"])
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid Channel UUID")
+		return
+	}
+
+	pID := h.getPID(r)
+	updated, err := h.service.PatchState(r.Context(), pID, channelID, body.Ops)
+	if err != nil {
+		stateError(w, err)
+		return
+	}
+	h.broadcastPatch(pID, updated, body.Ops, "")
+	response.JSON(w, http.StatusOK, "Patched", statePayload(updated))
+}
+
+// ResetState: PUT /channels/{channel_id}/state {"state": {...}} — wuxuu sidoo kale shidaa shared_state mode
+func (h *RealtimeHandler) ResetState(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		State json.RawMessage `json:"state"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+	channel, ok := h.stateChannel(w, r)
+	if !ok {
+		return
+	}
+
+	pID := h.getPID(r)
+	updated, err := h.service.ResetState(r.Context(), pID, channel.ID, body.State)
+	if err != nil {
+		stateError(w, err)
+		return
+	}
+	// Subscribers-ka oo dhan snapshot cusub (patches-kii hore ma khuseeyaan)
+	h.BroadcastToChannel(pID, updated.Name, "state_snapshot", statePayload(updated), "")
+	response.JSON(w, http.StatusOK, "State replaced", statePayload(updated))
+}
+
+func stateError(w http.ResponseWriter, err error) {
+	switch {
+	case err == services.ErrRealtimeEventLimit:
+		response.Error(w, http.StatusForbidden, "Realtime event limit reached", "limit_reached_realtime_events")
+	case err == services.ErrRealtimeNotSharedState:
+		response.Error(w, http.StatusConflict, err.Error(), errorCode(err))
+	case err == services.ErrRealtimeStateTooLarge:
+		response.Error(w, http.StatusRequestEntityTooLarge, err.Error(), errorCode(err))
+	case errors.Is(err, services.ErrRealtimeInvalidPatch):
+		response.Error(w, http.StatusUnprocessableEntity, "Patch rejected", err.Error())
+	default:
+		response.Error(w, http.StatusBadRequest, "Failed to update state", err.Error())
+	}
+}
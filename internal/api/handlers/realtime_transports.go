@@ -0,0 +1,501 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"superaib/internal/api/response"
+	"superaib/internal/models"
+	"superaib/internal/services"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// 🧱 Fallback transports (corporate proxies oo xannibaya WebSocket).
+// Labaduba waxay isticmaalaan isla Client, subscribe() iyo deliverToChannel() sida WebSocket-ka,
+// sidaas darteed auth, channel rules, presence iyo backplane fan-out waa isku mid.
+// Publishing wuxuu weli maraa POST /channels/{channel_id}/events.
+const (
+	pollWait         = 25 * time.Second // Inta long-poll request-ku sugayo fariin
+	pollSessionTTL   = 60 * time.Second // Session aan la poll gareyn muddadan waa la xiraa
+	pollMaxBatch     = 100
+	pollReapInterval = 30 * time.Second
+	sseRetryMillis   = 3000
+	reservationTTL   = 60 * time.Second // Connection ID la bixiyay waa in la isticmaalo muddadan
+)
+
+// pollSession: Client-ka long-poll wuu sii jiraa inta u dhaxaysa requests-ka
+// inflight: batch-kii ugu dambeeyay oo aan weli la xaqiijin (?ack=<batch>); write-ku haddii uu fashilmo dib ayaa loo diraa
+type pollSession struct {
+	client   *Client
+	lastPoll time.Time
+	active   int // Requests hadda sugaya
+	batch    int64
+	inflight []json.RawMessage
+}
+
+// connectionReservation: POST /realtime/connections ayaa bixiya; SSE/long-poll ayaa ?connection_id= ku qaata
+type connectionReservation struct {
+	projectID string
+	userID    string
+	expires   time.Time
+}
+
+func parseChannels(raw string) []string {
+	var names []string
+	for _, n := range strings.Split(raw, ",") {
+		if n = strings.TrimSpace(n); n != "" {
+			names = append(names, n)
+		}
+	}
+	return names
+}
+
+func lastEventID(r *http.Request) string {
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		return id
+	}
+	return r.URL.Query().Get("last_event_id")
+}
+
+// subscribeError: Subscribe-ka HTTP-ga (SSE/long-poll) oo fashilmay
+func subscribeError(w http.ResponseWriter, channel string, err error) {
+	response.Error(w, channelErrorStatus(err), "Subscription to "+channel+" rejected", map[string]string{
+		"code":    errorCode(err),
+		"message": err.Error(),
+	})
+}
+
+// channelErrorStatus: HTTP status-ka error-rada auth/moderation/limits ee channel-ka (subscribe iyo publish)
+func channelErrorStatus(err error) int {
+	switch err {
+	case services.ErrRealtimeAuthUnavailable:
+		return http.StatusBadGateway
+	case services.ErrRealtimeAuthRequired:
+		return http.StatusUnauthorized
+	case services.ErrRealtimeForbidden, services.ErrRealtimeChannelLimit, services.ErrRealtimeChannelFull,
+		services.ErrRealtimeBanned, services.ErrRealtimeMuted, services.ErrRealtimeEventLimit:
+		return http.StatusForbidden
+	}
+	return http.StatusBadRequest
+}
+
+// channelAuth: ?auth={"private-room":{"auth":"<token>","channel_data":"{...}"}}
+// Token-ka waxaa lagu saxiixaa connection ID-ga (POST /realtime/connections ama long-poll session_id)
+type channelAuth struct {
+	Auth        string `json:"auth"`
+	ChannelData string `json:"channel_data"`
+}
+
+func parseChannelAuth(raw string) (map[string]channelAuth, error) {
+	auth := map[string]channelAuth{}
+	if raw == "" {
+		return auth, nil
+	}
+	if err := json.Unmarshal([]byte(raw), &auth); err != nil {
+		return nil, err
+	}
+	return auth, nil
+}
+
+// subscribeAll: Dhamaan channels-ka ama midna (haddii mid la diido kuwii hore waa laga baxayaa)
+func (h *RealtimeHandler) subscribeAll(w http.ResponseWriter, c *Client, names []string, auth map[string]channelAuth) ([]*models.RealtimeChannel, bool) {
+	channels := make([]*models.RealtimeChannel, 0, len(names))
+	for _, name := range names {
+		a := auth[name]
+		ch, err := h.subscribe(c, name, nil, services.ChannelAuthRequest{Token: a.Auth, ChannelData: a.ChannelData})
+		if err != nil {
+			subscribeError(w, name, err)
+			return nil, false
+		}
+		h.sendSnapshot(c, ch)
+		channels = append(channels, ch)
+	}
+	return channels, true
+}
+
+// transportClient: Client cusub oo SSE/long-poll ah; ?connection_id= la bixiyay (isla project + user) ayuu qaataa
+func (h *RealtimeHandler) transportClient(w http.ResponseWriter, r *http.Request, projectID, userID, transport string) (*Client, bool) {
+	c := newClient(projectID, userID, transport, r.RemoteAddr)
+	id := r.URL.Query().Get("connection_id")
+	if id == "" {
+		return c, true
+	}
+	h.pollMux.Lock()
+	res, ok := h.reserved[id]
+	if ok && res.projectID == projectID && res.userID == userID && time.Now().Before(res.expires) {
+		delete(h.reserved, id)
+	} else {
+		ok = false
+	}
+	h.pollMux.Unlock()
+	if !ok {
+		response.Error(w, http.StatusNotFound, "Unknown or expired connection_id", "connection_expired")
+		return nil, false
+	}
+	c.ID = id
+	return c, true
+}
+
+// ReserveConnection: POST /realtime/connections — connection ID ka hor SSE/long-poll.
+// Backend-ka developer-ka ayaa ID-gan ku saxiixa channel tokens-ka (sida WebSocket-ka connection_id).
+// ID-gu waa node-kan kaliya: load balancer-ku waa inuu request-ka xiga u diraa isla node-ka (sticky).
+func (h *RealtimeHandler) ReserveConnection(w http.ResponseWriter, r *http.Request) {
+	if h.rejectIfDraining(w) {
+		return
+	}
+	projectID, userID, ok := h.authenticateRequest(w, r)
+	if !ok {
+		return
+	}
+	id := uuid.New().String()
+	h.pollMux.Lock()
+	h.reserved[id] = connectionReservation{projectID: projectID, userID: userID, expires: time.Now().Add(reservationTTL)}
+	h.pollMux.Unlock()
+	response.JSON(w, http.StatusCreated, "Reserved", map[string]interface{}{
+		"connection_id": id,
+		"expires_in":    int(reservationTTL.Seconds()),
+	})
+}
+
+// resume: Last-Event-ID waa hal id oo kaliya; channel-ka uu ka tirsan yahay ayaa laga celinayaa,
+// kuwa kale ReplaySince wuu diidayaa (sidaas darteed stream hal channel ah ayaa resume lossless ah)
+func (h *RealtimeHandler) resume(c *Client, channels []*models.RealtimeChannel, id string) {
+	if id == "" {
+		return
+	}
+	for _, ch := range channels {
+		if ch != nil {
+			_ = h.replayFrames(c, ch, id)
+		}
+	}
+}
+
+// =========================================================================
+// 📡 SERVER-SENT EVENTS: GET /realtime/stream?channels=a,b
+// =========================================================================
+
+func (h *RealtimeHandler) HandleSSE(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		response.Error(w, http.StatusInternalServerError, "Streaming unsupported")
+		return
+	}
+	if h.rejectIfDraining(w) {
+		return
+	}
+
+	projectID, userID, ok := h.authenticateRequest(w, r)
+	if !ok {
+		return
+	}
+	names := parseChannels(r.URL.Query().Get("channels"))
+	if len(names) == 0 {
+		response.Error(w, http.StatusBadRequest, "At least one channel is required", "channels=a,b")
+		return
+	}
+
+	auth, err := parseChannelAuth(r.URL.Query().Get("auth"))
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid auth parameter", err.Error())
+		return
+	}
+
+	c, ok := h.transportClient(w, r, projectID, userID, transportSSE)
+	if !ok {
+		return
+	}
+	if !h.registerClient(c) {
+		h.rejectIfDraining(w)
+		return
+	}
+	defer func() {
+		h.unregisterClient(c)
+		go h.leaveAll(c)
+	}()
+
+	channels, ok := h.subscribeAll(w, c, names, auth)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // nginx buffering ha xannibin stream-ka
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\nevent: connected\ndata: {\"connection_id\":%q}\n\n", sseRetryMillis, c.ID)
+	flusher.Flush()
+
+	fmt.Printf("\n🚀 [Realtime] SSE connected: User [%s] on %v\n", userID, names)
+	h.resume(c, channels, lastEventID(r))
+
+	ticker := time.NewTicker(pingPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-c.closed:
+			// Server-ka ayaa xiray (ban): gudbi fariimaha sugaya (sababta) kadibna jooji stream-ka
+			for {
+				select {
+				case data := <-c.Send:
+					_ = writeSSE(w, data)
+				default:
+					flusher.Flush()
+					return
+				}
+			}
+		case data := <-c.Send:
+			if err := writeSSE(w, data); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-ticker.C:
+			// Comment line: proxies-ka ha u arkin connection-ka mid fadhiya
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// writeSSE: "id:" waxaa laga qaadaa frame-ka si browser-ku Last-Event-ID ugu soo celiyo reconnect-ka
+func writeSSE(w http.ResponseWriter, data []byte) error {
+	var frame struct {
+		ID string `json:"id"`
+	}
+	_ = json.Unmarshal(data, &frame)
+
+	var b strings.Builder
+	if frame.ID != "" {
+		b.WriteString("id: " + frame.ID + "\n")
+	}
+	b.WriteString("data: ")
+	b.Write(data)
+	b.WriteString("\n\n")
+	_, err := fmt.Fprint(w, b.String())
+	return err
+}
+
+// =========================================================================
+// 🐢 LONG-POLL: GET /realtime/poll?channels=a,b[&session_id=...&ack=<batch>]
+// =========================================================================
+// Jawaab kasta waxay leedahay "batch"; poll-ka xiga ?ack=<batch> ayuu ku xaqiijiyaa. Haddii ack-gu
+// ka duwan yahay batch-kii ugu dambeeyay (jawaabtii way lumtay) isla fariimaha ayaa dib loo diraa.
+// ack la'aan = xaqiijin (clients-ka hore).
+
+func (h *RealtimeHandler) HandleLongPoll(w http.ResponseWriter, r *http.Request) {
+	if h.rejectIfDraining(w) {
+		return
+	}
+	projectID, userID, ok := h.authenticateRequest(w, r)
+	if !ok {
+		return
+	}
+	names := parseChannels(r.URL.Query().Get("channels"))
+	if len(names) == 0 {
+		response.Error(w, http.StatusBadRequest, "At least one channel is required", "channels=a,b")
+		return
+	}
+	auth, err := parseChannelAuth(r.URL.Query().Get("auth"))
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid auth parameter", err.Error())
+		return
+	}
+
+	sess, status := h.openPollSession(w, r, projectID, userID, names, auth)
+	if sess == nil {
+		if status != 0 {
+			response.Error(w, status, "Poll session expired", "session_expired")
+		}
+		return
+	}
+	defer h.releasePollSession(sess)
+
+	c := sess.client
+	if batch, messages := h.unackedBatch(sess, r.URL.Query().Get("ack")); messages != nil {
+		writePollBatch(w, c.ID, batch, messages)
+		return
+	}
+
+	messages := make([]json.RawMessage, 0)
+	timer := time.NewTimer(pollWait)
+	defer timer.Stop()
+
+	// Sug fariinta ugu horreysa, kadibna qaado inta diyaarka ah (batch)
+	select {
+	case data := <-c.Send:
+		messages = append(messages, data)
+	case <-timer.C:
+	case <-c.closed:
+	case <-r.Context().Done():
+		return
+	}
+drain:
+	for len(messages) < pollMaxBatch {
+		select {
+		case data := <-c.Send:
+			messages = append(messages, data)
+		default:
+			break drain
+		}
+	}
+
+	// 📬 Batch-ka waa la hayaa ilaa poll-ka xiga uu xaqiijiyo
+	h.pollMux.Lock()
+	if len(messages) > 0 {
+		sess.batch++
+		sess.inflight = messages
+	}
+	batch := sess.batch
+	h.pollMux.Unlock()
+	writePollBatch(w, c.ID, batch, messages)
+}
+
+func writePollBatch(w http.ResponseWriter, sessionID string, batch int64, messages []json.RawMessage) {
+	response.JSON(w, http.StatusOK, "Success", map[string]interface{}{
+		"session_id": sessionID,
+		"batch":      batch,
+		"messages":   messages,
+	})
+}
+
+// unackedBatch: Batch-kii hore haddii aan la xaqiijin (ack != batch); haddii kale waa la sii daayaa
+func (h *RealtimeHandler) unackedBatch(sess *pollSession, ack string) (int64, []json.RawMessage) {
+	h.pollMux.Lock()
+	defer h.pollMux.Unlock()
+	if len(sess.inflight) == 0 {
+		return sess.batch, nil
+	}
+	if ack == "" || ack == strconv.FormatInt(sess.batch, 10) {
+		sess.inflight = nil
+		return sess.batch, nil
+	}
+	return sess.batch, sess.inflight
+}
+
+// openPollSession: Session cusub (subscribe + replay) ama mid jira (channels-ka waa la waafajiyaa)
+func (h *RealtimeHandler) openPollSession(w http.ResponseWriter, r *http.Request, projectID, userID string, names []string, auth map[string]channelAuth) (*pollSession, int) {
+	sid := r.URL.Query().Get("session_id")
+	if sid != "" {
+		h.pollMux.Lock()
+		sess, ok := h.pollSessions[sid]
+		if ok && (sess.client.ProjectID != projectID || sess.client.UserID != userID) {
+			ok = false
+		}
+		if ok {
+			sess.active++
+			sess.lastPoll = time.Now()
+		}
+		h.pollMux.Unlock()
+		if !ok {
+			return nil, http.StatusNotFound
+		}
+		if !h.syncPollChannels(w, sess.client, names, auth) {
+			h.releasePollSession(sess)
+			return nil, 0
+		}
+		return sess, 0
+	}
+
+	c, ok := h.transportClient(w, r, projectID, userID, transportLongPoll)
+	if !ok {
+		return nil, 0
+	}
+	if !h.registerClient(c) {
+		h.rejectIfDraining(w)
+		return nil, 0
+	}
+	channels, ok := h.subscribeAll(w, c, names, auth)
+	if !ok {
+		h.unregisterClient(c)
+		go h.leaveAll(c)
+		return nil, 0
+	}
+	h.resume(c, channels, lastEventID(r))
+
+	sess := &pollSession{client: c, lastPoll: time.Now(), active: 1}
+	h.pollMux.Lock()
+	h.pollSessions[c.ID] = sess
+	h.pollMux.Unlock()
+	fmt.Printf("\n🚀 [Realtime] Long-poll session %s: User [%s] on %v\n", c.ID, userID, names)
+	return sess, 0
+}
+
+// syncPollChannels: Haddii client-ku beddelo ?channels= ku dar kuwa cusub, ka saar kuwa la dhaafay
+// (channels-ka cusub ?auth= tokens-ka waxaa lagu saxiixaa session_id-ga)
+func (h *RealtimeHandler) syncPollChannels(w http.ResponseWriter, c *Client, names []string, auth map[string]channelAuth) bool {
+	want := make(map[string]bool, len(names))
+	for _, n := range names {
+		want[n] = true
+	}
+
+	c.mu.Lock()
+	var add, remove []string
+	for n := range want {
+		if !c.Channels[n] {
+			add = append(add, n)
+		}
+	}
+	for n := range c.Channels {
+		if !want[n] {
+			remove = append(remove, n)
+		}
+	}
+	c.mu.Unlock()
+
+	for _, n := range remove {
+		h.unsubscribe(c, n)
+	}
+	_, ok := h.subscribeAll(w, c, add, auth)
+	return ok
+}
+
+func (h *RealtimeHandler) releasePollSession(sess *pollSession) {
+	h.pollMux.Lock()
+	sess.active--
+	sess.lastPoll = time.Now()
+	h.pollMux.Unlock()
+}
+
+// pollReaper: Xir sessions-ka client-koodu joojiyay polling-ka
+func (h *RealtimeHandler) pollReaper(ctx context.Context) {
+	ticker := time.NewTicker(pollReapInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		cutoff := time.Now().Add(-pollSessionTTL)
+		var expired []*Client
+
+		h.pollMux.Lock()
+		for id, sess := range h.pollSessions {
+			if sess.active == 0 && sess.lastPoll.Before(cutoff) {
+				delete(h.pollSessions, id)
+				expired = append(expired, sess.client)
+			}
+		}
+		for id, res := range h.reserved {
+			if time.Now().After(res.expires) {
+				delete(h.reserved, id)
+			}
+		}
+		h.pollMux.Unlock()
+
+		for _, c := range expired {
+			h.unregisterClient(c)
+			h.leaveAll(c)
+		}
+	}
+}
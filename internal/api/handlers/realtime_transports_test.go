@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func poll(t *testing.T, h *RealtimeHandler, query string) (int64, []string) {
+	t.Helper()
+	router := mux.NewRouter()
+	router.HandleFunc("/projects/{project_id}/realtime/poll", h.HandleLongPoll)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/projects/p1/realtime/poll?channels=chat&"+query, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+	var body struct {
+		Data struct {
+			Batch    int64             `json:"batch"`
+			Messages []json.RawMessage `json:"messages"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	msgs := make([]string, len(body.Data.Messages))
+	for i, m := range body.Data.Messages {
+		msgs[i] = string(m)
+	}
+	return body.Data.Batch, msgs
+}
+
+// Jawaab lumay (ack ma soo gaarin) → isla batch-ka ayaa dib loo diraa; ack-ka saxda ah kadib kan xiga
+func TestLongPollRedeliversUnackedBatch(t *testing.T) {
+	h := NewRealtimeHandler(&stubRealtimeService{}, nil, RealtimeLimits{})
+	c := newClient("p1", "", transportLongPoll, "test")
+	c.Channels["chat"] = true
+	h.registerClient(c)
+	h.pollSessions[c.ID] = &pollSession{client: c, lastPoll: time.Now()}
+	sid := "session_id=" + c.ID
+
+	c.Send <- []byte(`{"n":1}`)
+	batch, msgs := poll(t, h, sid)
+	if batch != 1 || len(msgs) != 1 || msgs[0] != `{"n":1}` {
+		t.Fatalf("first poll = %d %v", batch, msgs)
+	}
+
+	batch, msgs = poll(t, h, sid+"&ack=0")
+	if batch != 1 || len(msgs) != 1 || msgs[0] != `{"n":1}` {
+		t.Fatalf("unacked batch not redelivered: %d %v", batch, msgs)
+	}
+
+	c.Send <- []byte(`{"n":2}`)
+	batch, msgs = poll(t, h, sid+"&ack=1")
+	if batch != 2 || len(msgs) != 1 || msgs[0] != `{"n":2}` {
+		t.Fatalf("acked poll = %d %v", batch, msgs)
+	}
+}
+
+func TestTransportClientClaimsReservation(t *testing.T) {
+	h := NewRealtimeHandler(&stubRealtimeService{}, nil, RealtimeLimits{})
+	h.reserved["r1"] = connectionReservation{projectID: "p1", expires: time.Now().Add(time.Minute)}
+	h.reserved["r2"] = connectionReservation{projectID: "p2", expires: time.Now().Add(time.Minute)}
+	h.reserved["r3"] = connectionReservation{projectID: "p1", expires: time.Now().Add(-time.Second)}
+
+	tests := []struct {
+		id string
+		ok bool
+	}{
+		{"r1", true},
+		{"r1", false}, // hal mar kaliya
+		{"r2", false}, // project kale
+		{"r3", false}, // wuu dhacay
+		{"unknown", false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/poll?connection_id="+tt.id, nil)
+		c, ok := h.transportClient(httptest.NewRecorder(), r, "p1", "", transportLongPoll)
+		if ok != tt.ok || (ok && c.ID != tt.id) {
+			t.Errorf("connection_id %s: ok = %v, want %v", tt.id, ok, tt.ok)
+		}
+	}
+}
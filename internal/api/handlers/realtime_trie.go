@@ -0,0 +1,333 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"superaib/internal/models"
+	"superaib/internal/services"
+	"time"
+)
+
+// 🌳 Subscription trie: channel names waxaa loo kala jaraa segments (":" ama "." ayaa kala xiga).
+// Segment kasta wuxuu xambaarsan yahay separator-kii ka horreeyay si "orders:*" aysan u qaban "orders.1".
+//   - "*"  → hal segment oo kasta          (orders:*     → orders:1, orders:eu)
+//   - "**" → hal ama ka badan (kaliya dhamaadka) (chat.**  → chat.room.1, chat.lobby)
+// Broadcast-ku wuxuu socdaa segments-ka kaliya, ma baaro client kasta.
+
+var errInvalidPattern = errors.New("invalid channel pattern: '*' and '**' must be whole segments and '**' must be last")
+
+type trieNode struct {
+	children map[string]*trieNode
+	clients  map[*Client]bool
+}
+
+func newTrieNode() *trieNode {
+	return &trieNode{children: make(map[string]*trieNode), clients: make(map[*Client]bool)}
+}
+
+type subTrie struct {
+	root *trieNode
+}
+
+func newSubTrie() *subTrie {
+	return &subTrie{root: newTrieNode()}
+}
+
+// splitChannel: "chat.room.42" → ["chat", ".room", ".42"]; "orders:*" → ["orders", ":*"]
+func splitChannel(name string) []string {
+	var segs []string
+	start := 0
+	for i := 0; i < len(name); i++ {
+		if name[i] == '.' || name[i] == ':' {
+			segs = append(segs, name[start:i])
+			start = i
+		}
+	}
+	return append(segs, name[start:])
+}
+
+// segValue: segment-ka oo aan separator lahayn
+func segValue(seg string) string {
+	if seg != "" && (seg[0] == '.' || seg[0] == ':') {
+		return seg[1:]
+	}
+	return seg
+}
+
+func isPattern(name string) bool {
+	return strings.Contains(name, "*")
+}
+
+func validatePattern(name string) error {
+	segs := splitChannel(name)
+	for i, seg := range segs {
+		v := segValue(seg)
+		if strings.Contains(v, "*") && v != "*" && v != "**" {
+			return errInvalidPattern
+		}
+		if v == "**" && i != len(segs)-1 {
+			return errInvalidPattern
+		}
+	}
+	return nil
+}
+
+func (t *subTrie) add(name string, c *Client) {
+	node := t.root
+	for _, seg := range splitChannel(name) {
+		next, ok := node.children[seg]
+		if !ok {
+			next = newTrieNode()
+			node.children[seg] = next
+		}
+		node = next
+	}
+	node.clients[c] = true
+}
+
+// remove: Tirtir client-ka oo nadiifi nodes-ka madhan
+func (t *subTrie) remove(name string, c *Client) {
+	segs := splitChannel(name)
+	path := make([]*trieNode, 0, len(segs)+1)
+	node := t.root
+	path = append(path, node)
+	for _, seg := range segs {
+		next, ok := node.children[seg]
+		if !ok {
+			return
+		}
+		node = next
+		path = append(path, node)
+	}
+	delete(node.clients, c)
+
+	for i := len(segs) - 1; i >= 0; i-- {
+		n := path[i+1]
+		if len(n.clients) > 0 || len(n.children) > 0 {
+			break
+		}
+		delete(path[i].children, segs[i])
+	}
+}
+
+func (t *subTrie) empty() bool {
+	return len(t.root.children) == 0 && len(t.root.clients) == 0
+}
+
+// match: Clients-ka channel-kan la socda; qiimuhu waa true haddii subscription exact ah uu jiro
+// (false = pattern kaliya → authorization-ka channel-ka dhabta ah waa in la hubiyaa)
+func (t *subTrie) match(name string) map[*Client]bool {
+	out := make(map[*Client]bool)
+	t.walk(t.root, splitChannel(name), true, out)
+	return out
+}
+
+func (t *subTrie) walk(node *trieNode, segs []string, exact bool, out map[*Client]bool) {
+	if len(segs) == 0 {
+		for c := range node.clients {
+			out[c] = out[c] || exact
+		}
+		return
+	}
+
+	seg := segs[0]
+	sep := seg[:len(seg)-len(segValue(seg))]
+
+	if next, ok := node.children[seg]; ok {
+		t.walk(next, segs[1:], exact, out)
+	}
+	if next, ok := node.children[sep+"*"]; ok && seg != sep+"*" {
+		t.walk(next, segs[1:], false, out)
+	}
+	if next, ok := node.children[sep+"**"]; ok && seg != sep+"**" {
+		for c := range next.clients {
+			if _, seen := out[c]; !seen {
+				out[c] = false
+			}
+		}
+	}
+}
+
+// =========================================================================
+// 📇 SUBSCRIPTION INDEX (project kasta hal trie)
+// =========================================================================
+
+// channelCacheTTL: Pattern subscribers-ka authorization-kooda waxaa loo baahan yahay channel-ka dhabta ah
+const channelCacheTTL = 30 * time.Second
+
+type cachedChannel struct {
+	channel *models.RealtimeChannel
+	at      time.Time
+}
+
+func (h *RealtimeHandler) indexSubscription(c *Client, name string) {
+	h.subsMux.Lock()
+	t, ok := h.subs[c.ProjectID]
+	if !ok {
+		t = newSubTrie()
+		h.subs[c.ProjectID] = t
+	}
+	t.add(name, c)
+	h.subsMux.Unlock()
+}
+
+func (h *RealtimeHandler) unindexSubscription(c *Client, name string) {
+	h.subsMux.Lock()
+	if t, ok := h.subs[c.ProjectID]; ok {
+		t.remove(name, c)
+		if t.empty() {
+			delete(h.subs, c.ProjectID)
+		}
+	}
+	h.subsMux.Unlock()
+}
+
+// patternParkLimit: Inta fariimood ee la hayo client kasta/channel kasta inta authorization-ku socdo
+const patternParkLimit = 64
+
+// subscribersOf: Clients-ka channel-kan (exact + patterns). Pattern-kaliya waxay maraan go'aanka la kaydiyay;
+// kuwa aan go'aan lahayn waxay ku jiraan "pending" (fariinta waa la hayaa, DB-ga goroutine kale ayaa weydiiya)
+func (h *RealtimeHandler) subscribersOf(pID, channel string) (ready, pending []*Client) {
+	h.subsMux.RLock()
+	var matched map[*Client]bool
+	if t, ok := h.subs[pID]; ok {
+		matched = t.match(channel)
+	}
+	h.subsMux.RUnlock()
+
+	ready = make([]*Client, 0, len(matched))
+	for c, exact := range matched {
+		if exact {
+			ready = append(ready, c)
+			continue
+		}
+		switch allowed, known := h.patternAllowed(c, channel); {
+		case !known:
+			pending = append(pending, c)
+		case allowed:
+			ready = append(ready, c)
+		}
+	}
+	return ready, pending
+}
+
+// patternDecision: Go'aanka authorization-ka pattern-ka ee channel dhab ah (channelCacheTTL kadib dib ayaa loo hubiyaa)
+type patternDecision struct {
+	allowed    bool
+	at         time.Time
+	refreshing bool
+}
+
+// parkedFrame: Fariin sugaysa go'aanka pattern-ka (ackID: ack_required bookkeeping-ka deliverTo)
+type parkedFrame struct {
+	data  []byte
+	ackID string
+}
+
+// patternAllowed: Go'aanka la kaydiyay kaliya (delivery loop-ku DB ma taabto).
+// Go'aan duug ah waa la isticmaalaa inta background-ka dib loo hubinayo; mid la'aan → known=false
+func (h *RealtimeHandler) patternAllowed(c *Client, channel string) (allowed, known bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	d, ok := c.patternAuth[channel]
+	if !ok {
+		return false, false
+	}
+	if time.Since(d.at) >= channelCacheTTL && !d.refreshing {
+		d.refreshing = true
+		c.patternAuth[channel] = d
+		go h.refreshPattern(c, channel)
+	}
+	return d.allowed, true
+}
+
+func (h *RealtimeHandler) authorizePattern(c *Client, channel string) bool {
+	ch := h.channelFor(c.ProjectID, channel)
+	return ch != nil && h.service.AuthorizeChannel(context.Background(), ch, c.UserID, services.ChannelActionSubscribe) == nil
+}
+
+func (h *RealtimeHandler) refreshPattern(c *Client, channel string) {
+	started := time.Now()
+	allowed := h.authorizePattern(c, channel)
+	c.mu.Lock()
+	c.patternAuth[channel] = patternDecision{allowed: newerDecision(c, channel, started, allowed), at: time.Now()}
+	c.mu.Unlock()
+}
+
+// newerDecision: Go'aan la qoray inta DB-ga la weydiinayay (tusaale ban) ayaa ka adag kan duugoobay; c.mu waa la hayaa
+func newerDecision(c *Client, channel string, since time.Time, allowed bool) bool {
+	if d, ok := c.patternAuth[channel]; ok && d.at.After(since) {
+		return d.allowed
+	}
+	return allowed
+}
+
+// parkForPattern: Fariinta hay ilaa go'aanka la helo; resolver-ka koowaad kaliya ayaa la bilaabaa
+func (h *RealtimeHandler) parkForPattern(c *Client, channel string, data []byte, ackID string) {
+	c.mu.Lock()
+	queue, resolving := c.patternWait[channel]
+	if len(queue) >= patternParkLimit {
+		c.mu.Unlock()
+		fmt.Printf("⚠️ [Realtime] Pattern authorization backlog full for conn %s on %s\n", c.ID, channel)
+		return
+	}
+	c.patternWait[channel] = append(queue, parkedFrame{data: data, ackID: ackID})
+	c.mu.Unlock()
+	if !resolving {
+		go h.resolvePattern(c, channel)
+	}
+}
+
+// resolvePattern: Authorization (DB) kadib fariimaha la hayay si tartib ah u dir.
+// Go'aanka waxaa la kaydiyaa marka queue-gu madhan yahay oo kaliya, si fariin cusub aysan uga horrayn kuwii la hayay
+func (h *RealtimeHandler) resolvePattern(c *Client, channel string) {
+	started := time.Now()
+	allowed := h.authorizePattern(c, channel)
+	for {
+		c.mu.Lock()
+		allowed = newerDecision(c, channel, started, allowed)
+		batch := c.patternWait[channel]
+		if len(batch) == 0 {
+			delete(c.patternWait, channel)
+			c.patternAuth[channel] = patternDecision{allowed: allowed, at: time.Now()}
+			c.mu.Unlock()
+			return
+		}
+		c.patternWait[channel] = batch[:0:0]
+		c.mu.Unlock()
+
+		if allowed {
+			for _, f := range batch {
+				h.deliverTo(c, channel, f.data, f.ackID)
+			}
+		}
+	}
+}
+
+// invalidateChannelAuth: Channel-ka waa la beddelay/tirtiray: cache-ka node-kan hadda ka saar
+// (nodes-ka kale waxay ku tiirsan yihiin channelCacheTTL)
+func (h *RealtimeHandler) invalidateChannelAuth(pID, name string) {
+	h.channelCache.Delete(pID + "\x00" + name)
+	for _, c := range h.clientsOf(pID) {
+		c.mu.Lock()
+		delete(c.patternAuth, name)
+		c.mu.Unlock()
+	}
+}
+
+func (h *RealtimeHandler) channelFor(pID, name string) *models.RealtimeChannel {
+	key := pID + "\x00" + name
+	if v, ok := h.channelCache.Load(key); ok {
+		if cc := v.(cachedChannel); time.Since(cc.at) < channelCacheTTL {
+			return cc.channel
+		}
+	}
+	ch, err := h.service.GetChannelByName(context.Background(), pID, name)
+	if err != nil {
+		return nil
+	}
+	h.channelCache.Store(key, cachedChannel{channel: ch, at: time.Now()})
+	return ch
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"superaib/internal/models"
+	"superaib/internal/services"
+
+	"github.com/google/uuid"
+)
+
+// gatedAuthService: AuthorizeChannel wuu sugayaa ilaa gate-ka la furo (DB gaabis ah)
+type gatedAuthService struct {
+	stubRealtimeService
+	gate  chan struct{}
+	allow bool
+}
+
+func (s *gatedAuthService) GetChannelByName(ctx context.Context, projectID, name string) (*models.RealtimeChannel, error) {
+	return &models.RealtimeChannel{ID: uuid.New(), ProjectID: projectID, Name: name}, nil
+}
+
+func (s *gatedAuthService) AuthorizeChannel(ctx context.Context, ch *models.RealtimeChannel, userID string, action services.ChannelAction) error {
+	<-s.gate
+	if !s.allow {
+		return services.ErrRealtimeForbidden
+	}
+	return nil
+}
+
+func recv(c *Client) string {
+	select {
+	case m := <-c.Send:
+		return string(m)
+	case <-time.After(2 * time.Second):
+		return ""
+	}
+}
+
+// Pattern subscriber-ka go'aan la'aanta ah: delivery-gu ma sugo DB-ga, fariimahana tartib ayay u gaaraan
+func TestPatternAuthorizationOffDeliveryPath(t *testing.T) {
+	for _, allow := range []bool{true, false} {
+		svc := &gatedAuthService{gate: make(chan struct{}), allow: allow}
+		h := NewRealtimeHandler(svc, nil, RealtimeLimits{})
+		c := newClient("p1", "", transportLongPoll, "test")
+		h.indexSubscription(c, "chat.*")
+
+		done := make(chan struct{})
+		go func() {
+			h.deliverToChannel("p1", "chat.lobby", []byte("1"), "")
+			h.deliverToChannel("p1", "chat.lobby", []byte("2"), "")
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("delivery blocked on channel authorization")
+		}
+
+		close(svc.gate)
+		if !allow {
+			time.Sleep(50 * time.Millisecond)
+			if len(c.Send) != 0 {
+				t.Fatalf("denied pattern subscriber received %d frames", len(c.Send))
+			}
+			continue
+		}
+		h.deliverToChannel("p1", "chat.lobby", []byte("3"), "")
+		for _, want := range []string{"1", "2", "3"} {
+			if got := recv(c); got != want {
+				t.Fatalf("got frame %q, want %q", got, want)
+			}
+		}
+	}
+}
+
+func TestSubTrieMatch(t *testing.T) {
+	a, b, c := &Client{ID: "a"}, &Client{ID: "b"}, &Client{ID: "c"}
+	subs := []struct {
+		name   string
+		client *Client
+	}{
+		{"orders:*", a},
+		{"chat.**", b},
+		{"chat.room.1", c},
+		{"chat.*.typing", a},
+	}
+	tr := newSubTrie()
+	for _, s := range subs {
+		if err := validatePattern(s.name); err != nil {
+			t.Fatalf("validatePattern(%s) = %v", s.name, err)
+		}
+		tr.add(s.name, s.client)
+	}
+
+	tests := []struct {
+		channel string
+		want    map[string]bool // client ID -> exact
+	}{
+		{"orders:1", map[string]bool{"a": false}},
+		{"orders:eu", map[string]bool{"a": false}},
+		{"orders.1", map[string]bool{}},       // separator-ku waa qayb ka mid ah segment-ka
+		{"orders:1:items", map[string]bool{}}, // "*" hal segment kaliya
+		{"orders", map[string]bool{}},         // "*" waa inuu qabtaa segment
+		{"chat.lobby", map[string]bool{"b": false}},
+		{"chat.room.1", map[string]bool{"b": false, "c": true}},
+		{"chat.room.2.typing", map[string]bool{"b": false}},
+		{"chat.room.typing", map[string]bool{"a": false, "b": false}},
+		{"chat", map[string]bool{}}, // "**" hal ama ka badan
+		{"chat:lobby", map[string]bool{}},
+	}
+	for _, tt := range tests {
+		got := map[string]bool{}
+		for cl, exact := range tr.match(tt.channel) {
+			got[cl.ID] = exact
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("match(%s) = %v, want %v", tt.channel, got, tt.want)
+			continue
+		}
+		for id, exact := range tt.want {
+			if e, ok := got[id]; !ok || e != exact {
+				t.Errorf("match(%s) = %v, want %v", tt.channel, got, tt.want)
+				break
+			}
+		}
+	}
+
+	// remove: nodes-ka madhan waa la nadiifiyaa
+	for _, s := range subs {
+		tr.remove(s.name, s.client)
+	}
+	if !tr.empty() {
+		t.Fatal("trie not empty after removing every subscription")
+	}
+}
+
+func TestValidatePattern(t *testing.T) {
+	tests := []struct {
+		name string
+		ok   bool
+	}{
+		{"orders:*", true},
+		{"chat.**", true},
+		{"*.typing", true},
+		{"a.*.b", true},
+		{"chat.**.typing", false},
+		{"chat.room*", false},
+		{"ch*t", false},
+		{"chat.***", false},
+	}
+	for _, tt := range tests {
+		if err := validatePattern(tt.name); (err == nil) != tt.ok {
+			t.Errorf("validatePattern(%s) = %v, want ok=%v", tt.name, err, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"superaib/internal/api/response"
+	"superaib/internal/services"
+
+	"github.com/gorilla/mux"
+)
+
+// CreateBucket handles POST /storage/buckets
+// {"name": "avatars", "visibility": "public", "allowed_mime_types": ["image/*"], "max_file_size": 5242880, "allowed_prefixes": ["users/"]}
+func (h *StorageHandler) CreateBucket(w http.ResponseWriter, r *http.Request) {
+	var in services.BucketInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	bucket, err := h.service.CreateBucket(r.Context(), h.getPID(r), in)
+	if err != nil {
+		storageError(w, err)
+		return
+	}
+	response.JSON(w, http.StatusCreated, "Bucket created", bucket)
+}
+
+// ListBuckets handles GET /storage/buckets
+func (h *StorageHandler) ListBuckets(w http.ResponseWriter, r *http.Request) {
+	buckets, err := h.service.ListBuckets(r.Context(), h.getPID(r))
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, "Failed to get buckets", err.Error())
+		return
+	}
+	response.JSON(w, http.StatusOK, "Buckets retrieved", buckets)
+}
+
+// GetBucket handles GET /storage/buckets/{bucket}
+func (h *StorageHandler) GetBucket(w http.ResponseWriter, r *http.Request) {
+	bucket, err := h.service.GetBucket(r.Context(), h.getPID(r), mux.Vars(r)["bucket"])
+	if err != nil {
+		storageError(w, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, "Bucket retrieved", bucket)
+}
+
+// UpdateBucket handles PUT /storage/buckets/{bucket} (settings-ka oo dhan, magaca mooyee)
+func (h *StorageHandler) UpdateBucket(w http.ResponseWriter, r *http.Request) {
+	var in services.BucketInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	bucket, err := h.service.UpdateBucket(r.Context(), h.getPID(r), mux.Vars(r)["bucket"], in)
+	if err != nil {
+		storageError(w, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, "Bucket updated", bucket)
+}
+
+// DeleteBucket handles DELETE /storage/buckets/{bucket} (bucket madhan kaliya)
+func (h *StorageHandler) DeleteBucket(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.DeleteBucket(r.Context(), h.getPID(r), mux.Vars(r)["bucket"]); err != nil {
+		storageError(w, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, "Bucket deleted", nil)
+}
+
+// ListObjects handles GET /storage/buckets/{bucket}/objects?prefix=avatars/&delimiter=/&cursor=&limit=100
+func (h *StorageHandler) ListObjects(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	limit := 0
+	if raw := q.Get("limit"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 1 {
+			response.Error(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		limit = v
+	}
+	listing, err := h.service.ListObjects(r.Context(), h.getPID(r), mux.Vars(r)["bucket"], services.ObjectListQuery{
+		Prefix:    q.Get("prefix"),
+		Delimiter: q.Get("delimiter"),
+		Cursor:    q.Get("cursor"),
+		Limit:     limit,
+	})
+	if err != nil {
+		storageError(w, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, "Objects retrieved", listing)
+}
+
+// MoveFile handles POST /storage/files/{id}/move {"bucket": "receipts", "path": "2024/march.pdf"}
+// Rename: isla bucket-ka, path cusub. Blob-ka lama koobiyeeyo.
+func (h *StorageHandler) MoveFile(w http.ResponseWriter, r *http.Request) {
+	var in services.MoveFileInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	file, err := h.service.MoveFile(r.Context(), h.getPID(r), mux.Vars(r)["id"], in)
+	if err != nil {
+		storageError(w, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, "File moved", file)
+}
+
+// bucketError: Khaladaadka buckets-ka (true = jawaab ayaa la qoray)
+func bucketError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, services.ErrBucketNotFound):
+		response.Error(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrBucketExists), errors.Is(err, services.ErrBucketNotEmpty), errors.Is(err, services.ErrPathExists):
+		response.Error(w, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrInvalidBucket), errors.Is(err, services.ErrInvalidPath):
+		response.Error(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrBucketMimeType):
+		response.Error(w, http.StatusUnsupportedMediaType, err.Error())
+	case errors.Is(err, services.ErrBucketFileTooLarge):
+		response.Error(w, http.StatusRequestEntityTooLarge, err.Error())
+	default:
+		return false
+	}
+	return true
+}
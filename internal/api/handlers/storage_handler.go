@@ -1,14 +1,25 @@
 package handlers
 
 import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
 	"strconv"
+	"strings"
+	"superaib/internal/api/middleware"
 	"superaib/internal/api/response"
+	"superaib/internal/core/logger"
 	"superaib/internal/models"
 	"superaib/internal/services"
+	"superaib/pkg/blobstore"
+	"time"
 
 	"github.com/gorilla/mux"
 )
@@ -21,19 +32,39 @@ func NewStorageHandler(s services.StorageService) *StorageHandler {
 	return &StorageHandler{service: s}
 }
 
+// getPID: Project ID-ga dhabta ah (APIKeyMiddleware wuxuu u beddelaa reference-ka ID)
+func (h *StorageHandler) getPID(r *http.Request) string {
+	if pID, ok := r.Context().Value(middleware.ProjectIDKey).(string); ok && pID != "" {
+		return pID
+	}
+	return mux.Vars(r)["project_id"]
+}
+
 // CreateFile handles POST /projects/{project_id}/storage/files
 func (h *StorageHandler) CreateFile(w http.ResponseWriter, r *http.Request) {
 	// Ka soo qaad project_id URL-ka (Waxaa soo saaray Middleware-ka)
 	projectID := mux.Vars(r)["project_id"]
 
-	var file models.StorageFile
-	if err := json.NewDecoder(r.Body).Decode(&file); err != nil {
+	// Record-ka file dibadda ku jira (URL kaliya): driver/storage_key/metadata client-ka lagama aqbalo
+	var body struct {
+		FileName string  `json:"file_name"`
+		FileType string  `json:"file_type"`
+		SizeMB   float64 `json:"size_mb"`
+		URL      string  `json:"url"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 		response.Error(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
 	// Hubi in project_id uu sax yahay
-	file.ProjectID = projectID
+	file := models.StorageFile{
+		ProjectID: projectID,
+		FileName:  body.FileName,
+		FileType:  body.FileType,
+		SizeMB:    body.SizeMB,
+		URL:       body.URL,
+	}
 
 	createdFile, err := h.service.CreateFile(r.Context(), &file)
 	if err != nil {
@@ -72,7 +103,11 @@ func (h *StorageHandler) GetFiles(w http.ResponseWriter, r *http.Request) {
 func (h *StorageHandler) DeleteFile(w http.ResponseWriter, r *http.Request) {
 	fileID := mux.Vars(r)["id"]
 
-	if err := h.service.DeleteFile(r.Context(), fileID); err != nil {
+	if err := h.service.DeleteFile(r.Context(), h.getPID(r), fileID); err != nil {
+		if err == services.ErrStorageFileNotFound {
+			response.Error(w, http.StatusNotFound, err.Error())
+			return
+		}
 		response.Error(w, http.StatusInternalServerError, "Failed to delete file", err.Error())
 		return
 	}
@@ -82,10 +117,10 @@ func (h *StorageHandler) DeleteFile(w http.ResponseWriter, r *http.Request) {
 
 // 🚀 UPLOAD FILE: Kani waa kan SDK-ga u jawaabaya
 func (h *StorageHandler) UploadFile(w http.ResponseWriter, r *http.Request) {
-	projectID := mux.Vars(r)["project_id"]
+	projectID := h.getPID(r)
 
-	// 1. Parse Multipart Form (Sawirka dhabta ah)
-	err := r.ParseMultipartForm(10 << 20) // Max 10MB
+	// 1. Parse Multipart Form (10MB memory-ga, inta kale temp file ayay galaan)
+	err := r.ParseMultipartForm(10 << 20)
 	if err != nil {
 		response.Error(w, 400, "File too large or invalid")
 		return
@@ -98,18 +133,313 @@ func (h *StorageHandler) UploadFile(w http.ResponseWriter, r *http.Request) {
 	}
 	defer file.Close()
 
-	// 2. Akhri Bytes-ka sawirka
-	fileBytes, _ := io.ReadAll(file)
 	fileName := header.Filename
-	fileType := header.Header.Get("Content-Type")
+	opts := services.UploadOptions{
+		FileName: fileName,
+		FileType: header.Header.Get("Content-Type"),
+		Size:     header.Size,
+		Bucket:   r.FormValue("bucket"),
+		Path:     r.FormValue("path"), // "avatars/u1/me.png" ama "avatars/u1/" (magaca file-ka)
+	}
 
-	// 3. U dir Service-ka si uu Cloudinary ugu rido
-	createdFile, err := h.service.UploadToCloud(r.Context(), projectID, fileBytes, fileName, fileType)
+	// 🔐 Content-MD5 / X-Checksum-Sha256 (part-ka file-ka ama codsiga): khilaaf → 400, file-ka lama keydiyo
+	if opts.ChecksumSHA256, err = decodeDigest(firstNonEmpty(header.Header.Get("X-Checksum-Sha256"), r.Header.Get("X-Checksum-Sha256")), sha256.Size); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid X-Checksum-Sha256 header", err.Error())
+		return
+	}
+	if opts.ContentMD5, err = decodeDigest(firstNonEmpty(header.Header.Get("Content-MD5"), r.Header.Get("Content-MD5")), md5.Size); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid Content-MD5 header", err.Error())
+		return
+	}
+
+	// 🔐 Auth user (ikhtiyaari): uploader-ka private files-ka
+	if token := bearerToken(r); token != "" {
+		uid, err := h.service.AuthenticateUser(r.Context(), projectID, token)
+		if err != nil {
+			response.Error(w, http.StatusUnauthorized, "Invalid user token", err.Error())
+			return
+		}
+		opts.UploadedBy = uid
+	}
+	// 🔏 visibility: public (default) | authenticated | private; allowed_users = "id1,id2"
+	if visibility := r.FormValue("visibility"); visibility != "" {
+		opts.AccessControl = &models.StorageAccessControl{
+			Visibility:   visibility,
+			AllowedUsers: splitList(r.FormValue("allowed_users")),
+		}
+	}
+
+	// 2. U gudbi driver-ka project-ka (local, s3 ama cloudinary) stream ahaan
+	createdFile, err := h.service.UploadFile(r.Context(), projectID, file, opts)
 	if err != nil {
-		response.Error(w, 500, err.Error())
+		if bucketError(w, err) {
+			return
+		}
+		switch {
+		case err == services.ErrStorageDisabled:
+			response.Error(w, http.StatusForbidden, err.Error())
+		case errors.Is(err, services.ErrStorageInvalidACL):
+			response.Error(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, services.ErrChecksumMismatch):
+			response.Error(w, http.StatusBadRequest, err.Error(), "bad_digest")
+		default:
+			response.Error(w, 500, err.Error())
+		}
 		return
 	}
 
-	fmt.Printf("✅ [Storage] File %s uploaded and saved to pgAdmin\n", fileName)
+	logger.Log.Infof("✅ [Storage] File %s uploaded via %s", fileName, createdFile.Driver)
 	response.JSON(w, 201, "Uploaded successfully", createdFile)
 }
+
+// GetFile handles GET /storage/files/{id} — record-ka + stat-ka driver-ka
+func (h *StorageHandler) GetFile(w http.ResponseWriter, r *http.Request) {
+	file, obj, err := h.service.StatFile(r.Context(), h.getPID(r), mux.Vars(r)["id"])
+	if err != nil && (file == nil || err != services.ErrStorageExternalFile) {
+		storageError(w, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, "File retrieved", map[string]interface{}{
+		"file":   file,
+		"object": obj, // nil: files-kii hore (URL kaliya)
+	})
+}
+
+// DownloadFile handles GET|HEAD /storage/files/{id}/download
+func (h *StorageHandler) DownloadFile(w http.ResponseWriter, r *http.Request) {
+	pID, fileID := h.getPID(r), mux.Vars(r)["id"]
+
+	if r.Method == http.MethodHead {
+		file, obj, err := h.service.StatFile(r.Context(), pID, fileID)
+		if err != nil {
+			h.downloadError(w, r, file, err)
+			return
+		}
+		writeObjectHeaders(w, file, obj)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	rc, file, obj, err := h.service.OpenFile(r.Context(), pID, fileID)
+	if err != nil {
+		h.downloadError(w, r, file, err)
+		return
+	}
+	defer rc.Close()
+
+	writeObjectHeaders(w, file, obj)
+	// Local disk: Range / If-None-Match waxaa maamula ServeContent
+	if rs, ok := rc.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, file.FileName, obj.ModifiedAt, rs)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, rc); err != nil {
+		logger.Log.Warnf("⚠️ [Storage] Download of %s interrupted: %v", file.ID, err)
+	}
+}
+
+// SetAccess handles PUT /storage/files/{id}/access {"visibility": "private", "allowed_users": [...]}
+func (h *StorageHandler) SetAccess(w http.ResponseWriter, r *http.Request) {
+	var acl models.StorageAccessControl
+	if err := json.NewDecoder(r.Body).Decode(&acl); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	file, err := h.service.SetAccessControl(r.Context(), h.getPID(r), mux.Vars(r)["id"], acl)
+	if err != nil {
+		storageError(w, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, "Access control updated", file)
+}
+
+// CreateSignedURL handles POST /storage/files/{id}/signed-url {"expires_in": 900, "bind_user": false}
+// Private files: Authorization: Bearer <auth user token> waa khasab (AccessControl-ka ayaa la hubiyaa).
+func (h *StorageHandler) CreateSignedURL(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		ExpiresIn int  `json:"expires_in"` // seconds
+		BindUser  bool `json:"bind_user"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			response.Error(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	pID := h.getPID(r)
+	userID := ""
+	if token := bearerToken(r); token != "" {
+		uid, err := h.service.AuthenticateUser(r.Context(), pID, token)
+		if err != nil {
+			response.Error(w, http.StatusUnauthorized, "Invalid user token", err.Error())
+			return
+		}
+		userID = uid
+	}
+
+	signed, err := h.service.SignFileURL(r.Context(), pID, mux.Vars(r)["id"], userID, services.SignURLOptions{
+		TTL:      time.Duration(body.ExpiresIn) * time.Second,
+		BindUser: body.BindUser,
+	})
+	if err != nil {
+		storageError(w, err)
+		return
+	}
+	response.JSON(w, http.StatusCreated, "Signed URL created", map[string]interface{}{
+		"url":        requestBaseURL(r) + signed.Path,
+		"expires_at": signed.ExpiresAt,
+		"bound_user": signed.BoundUser,
+	})
+}
+
+// ServeSignedFile handles GET|HEAD /api/v1/storage/signed/{file_id}?expires=&sig=[&uid=]
+// API key looma baahna (<img src>, <video>): saxiixa ayaa ogolaanshaha ah.
+func (h *StorageHandler) ServeSignedFile(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	expires, _ := strconv.ParseInt(q.Get("expires"), 10, 64)
+	req := services.SignedFileRequest{
+		FileID:    mux.Vars(r)["file_id"],
+		Expires:   expires,
+		UserID:    q.Get("uid"),
+		Signature: q.Get("sig"),
+		Token:     bearerToken(r),
+	}
+
+	// 🖼️ Thumbnails-ka private images: isla signed URL-ka + w/h/fit/format/q
+	if transform, ok, err := imageTransformFromQuery(q); err != nil || ok {
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		variant, err := h.service.SignedImageVariant(r.Context(), req, transform)
+		if err != nil {
+			storageError(w, err)
+			return
+		}
+		writeImageVariant(w, r, variant, "private, max-age=300")
+		return
+	}
+
+	delivery, err := h.service.OpenSignedFile(r.Context(), req, r.Method == http.MethodHead)
+	if err != nil {
+		storageError(w, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "private, no-store")
+	if delivery.RedirectURL != "" {
+		http.Redirect(w, r, delivery.RedirectURL, http.StatusTemporaryRedirect)
+		return
+	}
+	writeObjectHeaders(w, delivery.File, delivery.Object)
+	if delivery.Body == nil {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	defer delivery.Body.Close()
+	if rs, ok := delivery.Body.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, delivery.File.FileName, delivery.Object.ModifiedAt, rs)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, delivery.Body); err != nil {
+		logger.Log.Warnf("⚠️ [Storage] Signed download of %s interrupted: %v", delivery.File.ID, err)
+	}
+}
+
+// requestBaseURL: scheme://host ee codsiga (proxy-ga X-Forwarded-Proto)
+func requestBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https") {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// decodeDigest: Hex ama base64 (Content-MD5 waa base64, RFC 1864); "" = lama soo dirin
+func decodeDigest(value string, size int) ([]byte, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	if len(value) == hex.EncodedLen(size) {
+		if sum, err := hex.DecodeString(value); err == nil {
+			return sum, nil
+		}
+	}
+	sum, err := base64.StdEncoding.DecodeString(value)
+	if err != nil || len(sum) != size {
+		return nil, fmt.Errorf("expected a %d-byte digest in hex or base64", size)
+	}
+	return sum, nil
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+func (h *StorageHandler) downloadError(w http.ResponseWriter, r *http.Request, file *models.StorageFile, err error) {
+	// Files-kii hore ee Cloudinary: u dir URL-ka dibadda
+	if err == services.ErrStorageExternalFile && file != nil && file.URL != "" {
+		http.Redirect(w, r, file.URL, http.StatusFound)
+		return
+	}
+	storageError(w, err)
+}
+
+func writeObjectHeaders(w http.ResponseWriter, file *models.StorageFile, obj *blobstore.Object) {
+	contentType := file.FileType
+	if contentType == "" {
+		contentType = obj.ContentType
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": file.FileName}))
+	if obj.Size >= 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(obj.Size, 10))
+	}
+	if obj.ETag != "" {
+		w.Header().Set("ETag", `"`+obj.ETag+`"`)
+	}
+	if file.Checksum != nil {
+		w.Header().Set("X-Checksum-Sha256", *file.Checksum) // Client-ku wuu hubin karaa bytes-ka la helay
+	}
+	if !obj.ModifiedAt.IsZero() {
+		w.Header().Set("Last-Modified", obj.ModifiedAt.UTC().Format(http.TimeFormat))
+	}
+}
+
+func storageError(w http.ResponseWriter, err error) {
+	if bucketError(w, err) {
+		return
+	}
+	switch {
+	case err == services.ErrStorageFileNotFound || errors.Is(err, blobstore.ErrNotFound):
+		response.Error(w, http.StatusNotFound, "File not found")
+	case err == services.ErrStorageDisabled:
+		response.Error(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, services.ErrStorageExternalFile):
+		response.Error(w, http.StatusConflict, err.Error())
+	case err == services.ErrStorageSignedURL:
+		response.Error(w, http.StatusForbidden, err.Error(), "signed_url_required")
+	case errors.Is(err, services.ErrStorageAccessDenied):
+		response.Error(w, http.StatusForbidden, err.Error())
+	case err == services.ErrStorageBadSignature:
+		response.Error(w, http.StatusForbidden, err.Error(), "invalid_signature")
+	case errors.Is(err, services.ErrStorageInvalidACL), errors.Is(err, services.ErrImageTransform):
+		response.Error(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrChecksumMismatch):
+		response.Error(w, http.StatusBadRequest, err.Error(), "bad_digest")
+	case errors.Is(err, services.ErrImageUnsupported):
+		response.Error(w, http.StatusUnsupportedMediaType, err.Error())
+	default:
+		response.Error(w, http.StatusBadGateway, "Storage backend error", err.Error())
+	}
+}
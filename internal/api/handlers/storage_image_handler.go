@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"superaib/internal/api/response"
+	"superaib/internal/services"
+
+	"github.com/gorilla/mux"
+)
+
+// ImageFile handles GET|HEAD /storage/files/{id}/image?w=&h=&fit=&gravity=&format=&q=
+func (h *StorageHandler) ImageFile(w http.ResponseWriter, r *http.Request) {
+	transform, _, err := imageTransformFromQuery(r.URL.Query())
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	variant, err := h.service.ImageVariant(r.Context(), h.getPID(r), mux.Vars(r)["id"], transform)
+	if err != nil {
+		storageError(w, err)
+		return
+	}
+	// Files-ku isma beddelaan (version 1): variant-ku waa la kaydin karaa muddo dheer
+	writeImageVariant(w, r, variant, "public, max-age=31536000, immutable")
+}
+
+// imageTransformFromQuery: ok = query-gu wuxuu codsanayaa transform (signed URLs-ka)
+func imageTransformFromQuery(q url.Values) (services.ImageTransform, bool, error) {
+	t := services.ImageTransform{
+		Fit:     q.Get("fit"),
+		Gravity: q.Get("gravity"),
+		Format:  q.Get("format"),
+	}
+	for name, dst := range map[string]*int{"w": &t.Width, "h": &t.Height, "q": &t.Quality} {
+		raw := q.Get(name)
+		if raw == "" {
+			continue
+		}
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 0 {
+			return t, false, fmt.Errorf("%s must be a positive integer", name)
+		}
+		*dst = v
+	}
+	ok := t.Width != 0 || t.Height != 0 || t.Quality != 0 || t.Fit != "" || t.Gravity != "" || t.Format != ""
+	return t, ok, nil
+}
+
+func writeImageVariant(w http.ResponseWriter, r *http.Request, v *services.ImageVariant, cacheControl string) {
+	defer v.Body.Close()
+
+	w.Header().Set("Content-Type", "image/"+v.Format)
+	w.Header().Set("Cache-Control", cacheControl)
+	if v.Cached {
+		w.Header().Set("X-Image-Cache", "HIT")
+	} else {
+		w.Header().Set("X-Image-Cache", "MISS")
+	}
+	if v.Object.ETag != "" {
+		w.Header().Set("ETag", `"`+v.Object.ETag+`"`)
+	}
+
+	if rs, ok := v.Body.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, "", v.Object.ModifiedAt, rs)
+		return
+	}
+	if v.Object.Size >= 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(v.Object.Size, 10))
+	}
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		_, _ = io.Copy(w, v.Body)
+	}
+}
@@ -0,0 +1,298 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"superaib/internal/api/response"
+	"superaib/internal/models"
+	"superaib/internal/services"
+
+	"github.com/gorilla/mux"
+)
+
+// =========================================================================
+// ⏫ TUS 1.0 (https://tus.io/protocols/resumable-upload)
+// =========================================================================
+//	OPTIONS /storage/tus               → Tus-Version, Tus-Extension, Tus-Max-Size
+//	POST    /storage/tus               → 201 Location (Upload-Length, Upload-Metadata: filename, filetype, visibility, allowed_users, bucket, path; X-Checksum-Sha256)
+//	HEAD    /storage/tus/{upload_id}   → Upload-Offset / Upload-Length / Upload-Expires
+//	PATCH   /storage/tus/{upload_id}   → 204 Upload-Offset (Content-Type: application/offset+octet-stream)
+//	DELETE  /storage/tus/{upload_id}   → 204 (termination)
+// Marka upload-ku dhammaado, X-File-Id header-ka ayaa sheegaya StorageFile-ka la abuuray.
+
+const (
+	tusVersion     = "1.0.0"
+	tusExtensions  = "creation,creation-with-upload,expiration,termination"
+	tusContentType = "application/offset+octet-stream"
+)
+
+func writeTusHeaders(w http.ResponseWriter) {
+	w.Header().Set("Tus-Resumable", tusVersion)
+	w.Header().Set("Cache-Control", "no-store")
+}
+
+// tusPreamble: Tus-Resumable waa khasab (OPTIONS mooyee)
+func tusPreamble(w http.ResponseWriter, r *http.Request) bool {
+	writeTusHeaders(w)
+	if r.Header.Get("Tus-Resumable") != tusVersion {
+		w.Header().Set("Tus-Version", tusVersion)
+		response.Error(w, http.StatusPreconditionFailed, "Unsupported tus version", "Tus-Resumable: "+tusVersion+" is required")
+		return false
+	}
+	return true
+}
+
+// tusUser: Auth user-ka codsiga (ikhtiyaari); uploads-ka user leh waxaa sii wadi kara isaga kaliya
+func (h *StorageHandler) tusUser(w http.ResponseWriter, r *http.Request, projectID string) (string, bool) {
+	token := bearerToken(r)
+	if token == "" {
+		return "", true
+	}
+	uid, err := h.service.AuthenticateUser(r.Context(), projectID, token)
+	if err != nil {
+		response.Error(w, http.StatusUnauthorized, "Invalid user token", err.Error())
+		return "", false
+	}
+	return uid, true
+}
+
+func writeUploadState(w http.ResponseWriter, upload *models.StorageUpload) {
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.UploadOffset, 10))
+	w.Header().Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	if upload.FileID != nil {
+		w.Header().Set("X-File-Id", upload.FileID.String())
+	}
+}
+
+// TusOptions handles OPTIONS /storage/tus[/{upload_id}]
+func (h *StorageHandler) TusOptions(w http.ResponseWriter, r *http.Request) {
+	writeTusHeaders(w)
+	w.Header().Set("Tus-Version", tusVersion)
+	w.Header().Set("Tus-Extension", tusExtensions)
+	if max := h.service.MaxUploadSize(); max > 0 {
+		w.Header().Set("Tus-Max-Size", strconv.FormatInt(max, 10))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// TusCreate handles POST /storage/tus (creation + creation-with-upload)
+func (h *StorageHandler) TusCreate(w http.ResponseWriter, r *http.Request) {
+	if !tusPreamble(w, r) {
+		return
+	}
+	pID := h.getPID(r)
+
+	if r.Header.Get("Upload-Defer-Length") != "" {
+		response.Error(w, http.StatusBadRequest, "Upload-Defer-Length is not supported")
+		return
+	}
+	length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		response.Error(w, http.StatusBadRequest, "Invalid or missing Upload-Length header")
+		return
+	}
+	rawMetadata := r.Header.Get("Upload-Metadata")
+	meta, err := parseTusMetadata(rawMetadata)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	uid, ok := h.tusUser(w, r, pID)
+	if !ok {
+		return
+	}
+
+	opts := services.UploadOptions{
+		FileName:   firstNonEmpty(meta["filename"], meta["name"]),
+		FileType:   firstNonEmpty(meta["filetype"], meta["type"]),
+		Size:       length,
+		UploadedBy: uid,
+		Bucket:     meta["bucket"],
+		Path:       meta["path"],
+	}
+	// 🔐 X-Checksum-Sha256: file-ka oo dhan (finalize-ka ayaa hubiya)
+	if opts.ChecksumSHA256, err = decodeDigest(r.Header.Get("X-Checksum-Sha256"), sha256.Size); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid X-Checksum-Sha256 header", err.Error())
+		return
+	}
+	if visibility := meta["visibility"]; visibility != "" {
+		opts.AccessControl = &models.StorageAccessControl{
+			Visibility:   visibility,
+			AllowedUsers: splitList(meta["allowed_users"]),
+		}
+	}
+
+	upload, err := h.service.CreateUpload(r.Context(), pID, opts, rawMetadata)
+	if err != nil && upload == nil {
+		tusError(w, err)
+		return
+	}
+	w.Header().Set("Location", requestBaseURL(r)+"/api/v1/projects/"+mux.Vars(r)["project_id"]+"/storage/tus/"+upload.ID.String())
+
+	// creation-with-upload: chunk-ga koowaad isla codsigan
+	if err == nil && r.Header.Get("Content-Type") == tusContentType && r.ContentLength != 0 {
+		upload, err = h.service.WriteUploadChunk(r.Context(), pID, upload.ID.String(), uid, 0, r.Body)
+	}
+	if err != nil {
+		tusError(w, err)
+		return
+	}
+	writeUploadState(w, upload)
+	w.WriteHeader(http.StatusCreated)
+}
+
+// TusHead handles HEAD /storage/tus/{upload_id}
+func (h *StorageHandler) TusHead(w http.ResponseWriter, r *http.Request) {
+	if !tusPreamble(w, r) {
+		return
+	}
+	pID := h.getPID(r)
+	uid, ok := h.tusUser(w, r, pID)
+	if !ok {
+		return
+	}
+	upload, err := h.service.GetUpload(r.Context(), pID, mux.Vars(r)["upload_id"], uid)
+	if err != nil {
+		tusError(w, err)
+		return
+	}
+	writeUploadState(w, upload)
+	w.Header().Set("Upload-Length", strconv.FormatInt(upload.UploadLength, 10))
+	if upload.Metadata != "" {
+		w.Header().Set("Upload-Metadata", upload.Metadata)
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// TusStatus handles GET /storage/tus/{upload_id} (JSON; SDK-yada u baahan file_id-ga kadib dhammaadka)
+func (h *StorageHandler) TusStatus(w http.ResponseWriter, r *http.Request) {
+	pID := h.getPID(r)
+	uid, ok := h.tusUser(w, r, pID)
+	if !ok {
+		return
+	}
+	upload, err := h.service.GetUpload(r.Context(), pID, mux.Vars(r)["upload_id"], uid)
+	if err != nil {
+		tusError(w, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, "Upload retrieved", upload)
+}
+
+// TusPatch handles PATCH /storage/tus/{upload_id}
+func (h *StorageHandler) TusPatch(w http.ResponseWriter, r *http.Request) {
+	if !tusPreamble(w, r) {
+		return
+	}
+	if r.Header.Get("Content-Type") != tusContentType {
+		response.Error(w, http.StatusUnsupportedMediaType, "Content-Type must be "+tusContentType)
+		return
+	}
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		response.Error(w, http.StatusBadRequest, "Invalid or missing Upload-Offset header")
+		return
+	}
+	pID := h.getPID(r)
+	uid, ok := h.tusUser(w, r, pID)
+	if !ok {
+		return
+	}
+
+	upload, err := h.service.WriteUploadChunk(r.Context(), pID, mux.Vars(r)["upload_id"], uid, offset, r.Body)
+	if err != nil {
+		if upload != nil {
+			writeUploadState(w, upload) // 409: client-ku offset-ka saxda ah ayuu arkaa
+		}
+		tusError(w, err)
+		return
+	}
+	writeUploadState(w, upload)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// TusDelete handles DELETE /storage/tus/{upload_id} (termination)
+func (h *StorageHandler) TusDelete(w http.ResponseWriter, r *http.Request) {
+	if !tusPreamble(w, r) {
+		return
+	}
+	pID := h.getPID(r)
+	uid, ok := h.tusUser(w, r, pID)
+	if !ok {
+		return
+	}
+	if err := h.service.TerminateUpload(r.Context(), pID, mux.Vars(r)["upload_id"], uid); err != nil {
+		tusError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// TusMethodOverride handles POST /storage/tus/{upload_id} with X-HTTP-Method-Override (proxies-ka PATCH/DELETE diida)
+func (h *StorageHandler) TusMethodOverride(w http.ResponseWriter, r *http.Request) {
+	switch strings.ToUpper(r.Header.Get("X-HTTP-Method-Override")) {
+	case http.MethodPatch:
+		h.TusPatch(w, r)
+	case http.MethodDelete:
+		h.TusDelete(w, r)
+	case http.MethodHead:
+		h.TusHead(w, r)
+	default:
+		writeTusHeaders(w)
+		response.Error(w, http.StatusMethodNotAllowed, "X-HTTP-Method-Override must be PATCH, DELETE or HEAD")
+	}
+}
+
+func tusError(w http.ResponseWriter, err error) {
+	switch {
+	case err == services.ErrUploadNotFound:
+		response.Error(w, http.StatusNotFound, err.Error())
+	case err == services.ErrUploadExpired:
+		response.Error(w, http.StatusGone, err.Error())
+	case err == services.ErrUploadOffsetMismatch:
+		response.Error(w, http.StatusConflict, err.Error())
+	case err == services.ErrUploadLocked:
+		response.Error(w, http.StatusLocked, err.Error())
+	case err == services.ErrUploadTooLarge:
+		response.Error(w, http.StatusRequestEntityTooLarge, err.Error())
+	case errors.Is(err, services.ErrChecksumMismatch):
+		// tus checksum extension: 460 Checksum Mismatch (upload-ka waa la tirtiray, mid cusub ayaa loo baahan yahay)
+		response.Error(w, 460, err.Error(), "bad_digest")
+	default:
+		storageError(w, err)
+	}
+}
+
+// parseTusMetadata: "key base64value,key2 base64value2" (value waa ikhtiyaari)
+func parseTusMetadata(header string) (map[string]string, error) {
+	meta := map[string]string{}
+	if strings.TrimSpace(header) == "" {
+		return meta, nil
+	}
+	for _, pair := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			return nil, errors.New("invalid Upload-Metadata header")
+		}
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid Upload-Metadata value for %q", key)
+		}
+		meta[key] = string(decoded)
+	}
+	return meta, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
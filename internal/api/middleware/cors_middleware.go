@@ -32,14 +32,8 @@ func NewAPIKeyMiddleware(ak services.APIKeyService, ps services.ProjectService,
 func (m *APIKeyMiddleware) AuthenticateAPIKey(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
-		// 🚀 1. WEBSOCKET BYPASS (VERY IMPORTANT)
-		// Haddii codsigu yahay WebSocket, Middleware-ku ha dhaafo.
-		// Sababta: WebSocket Handshake kuma soo qaado Header-ka "x-api-key".
-		// Realtime Handler-ka ayaa isagu si toos ah URL-ka uga hubin doona API Key-ga.
-		if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
-			next.ServeHTTP(w, r)
-			return
-		}
+		// 🚀 1. WEBSOCKET: Handshake-ku isna wuu maraa halkan.
+		// Browsers-ku ma diri karaan header-ka "x-api-key", sidaas darteed SDK-gu wuxuu ku soo daraa "?api_key=..."
 
 		// 2. Hel Project ID (URL Vars, Reference ID ama Query Params)
 		vars := mux.Vars(r)
@@ -56,7 +50,7 @@ func (m *APIKeyMiddleware) AuthenticateAPIKey(next http.Handler) http.Handler {
 			return
 		}
 
-		// 3. Hel API Key (Header "x-api-key" ama Query "api_key")
+		// 3. Hel API Key (Header "x-api-key" ama Query "api_key" - WebSocket)
 		apiKeyString := r.Header.Get("x-api-key")
 		if apiKeyString == "" {
 			apiKeyString = r.URL.Query().Get("api_key")
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"superaib/internal/api/response"
+	"superaib/internal/services"
+
+	"github.com/gorilla/mux"
+)
+
+// ProjectOwnerMiddleware: Dashboard routes-ka project gaar ah (admin/moderation) — API key-gu kuma filna
+type ProjectOwnerMiddleware struct {
+	projectService services.ProjectService
+}
+
+func NewProjectOwnerMiddleware(ps services.ProjectService) *ProjectOwnerMiddleware {
+	return &ProjectOwnerMiddleware{projectService: ps}
+}
+
+// RequireOwner: AuthMiddleware.Authenticate kadib; {project_id} waa inuu yahay project-ka user-ka JWT-ga.
+// Project ID-ga dhabta ah waxaa la galiyaa context-ga (ProjectIDKey) sida AuthenticateAPIKey.
+func (m *ProjectOwnerMiddleware) RequireOwner(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := r.Context().Value("userID").(string)
+		if userID == "" {
+			response.Error(w, http.StatusUnauthorized, "Authorization header required")
+			return
+		}
+
+		project, err := m.projectService.GetProjectByRefOrID(r.Context(), mux.Vars(r)["project_id"])
+		if err != nil || project.OwnerID != userID {
+			// 404 (ma aha 403) si aan loo ogaan project-yada dadka kale
+			response.Error(w, http.StatusNotFound, "Project not found")
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), ProjectIDKey, project.ID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
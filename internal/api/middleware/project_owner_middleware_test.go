@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"superaib/internal/core/logger"
+	"superaib/internal/models"
+	"superaib/internal/services"
+
+	"github.com/gorilla/mux"
+)
+
+func TestMain(m *testing.M) {
+	logger.Init()
+	os.Exit(m.Run())
+}
+
+type stubProjectService struct {
+	services.ProjectService
+	projects map[string]*models.Project
+}
+
+func (s *stubProjectService) GetProjectByRefOrID(ctx context.Context, param string) (*models.Project, error) {
+	if p, ok := s.projects[param]; ok {
+		return p, nil
+	}
+	return nil, errors.New("project not found")
+}
+
+func TestRequireOwner(t *testing.T) {
+	owner := NewProjectOwnerMiddleware(&stubProjectService{projects: map[string]*models.Project{
+		"ref-a": {ID: "project-a", OwnerID: "alice"},
+	}})
+
+	tests := []struct {
+		name    string
+		userID  string
+		project string
+		status  int
+	}{
+		{"owner", "alice", "ref-a", http.StatusOK},
+		{"other developer", "bob", "ref-a", http.StatusNotFound},
+		{"unknown project", "alice", "ref-b", http.StatusNotFound},
+		{"no session", "", "ref-a", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotPID string
+			router := mux.NewRouter()
+			router.Handle("/projects/{project_id}/realtime/admin/metrics", owner.RequireOwner(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotPID, _ = r.Context().Value(ProjectIDKey).(string)
+			})))
+
+			req := httptest.NewRequest(http.MethodGet, "/projects/"+tt.project+"/realtime/admin/metrics", nil)
+			if tt.userID != "" {
+				req = req.WithContext(context.WithValue(req.Context(), "userID", tt.userID))
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+			if tt.status == http.StatusOK && gotPID != "project-a" {
+				t.Fatalf("project id in context = %q", gotPID)
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"net/http"
+	"superaib/internal/api/handlers"
+
+	"github.com/gorilla/mux"
+)
+
+// CollectionMigrationRoutes: Data migrations (rename, default, cast, drop, merge patch) ee collection
+func CollectionMigrationRoutes(router *mux.Router, h *handlers.CollectionMigrationHandler, apiKeyAuth func(http.Handler) http.Handler) {
+	projectRouter := router.PathPrefix("/projects/{project_id}").Subrouter()
+	projectRouter.Use(apiKeyAuth)
+
+	// Bilow job cusub (dry_run: true si loo arko natiijada iyadoo aan la qorin)
+	projectRouter.HandleFunc("/collections/{collection}/migrations", h.StartMigration).Methods("POST")
+
+	// Run history-ga collection-ka
+	projectRouter.HandleFunc("/collections/{collection}/migrations", h.GetHistory).Methods("GET")
+
+	// Progress, Cancel iyo Resume
+	projectRouter.HandleFunc("/migrations/{migration_id}", h.GetMigration).Methods("GET")
+	projectRouter.HandleFunc("/migrations/{migration_id}/cancel", h.CancelMigration).Methods("POST")
+	projectRouter.HandleFunc("/migrations/{migration_id}/resume", h.ResumeMigration).Methods("POST")
+}
@@ -3,6 +3,7 @@ package routes
 import (
 	"net/http"
 	"superaib/internal/api/handlers"
+	"superaib/internal/api/middleware"
 	"superaib/internal/core/logger"
 
 	"github.com/gorilla/mux"
@@ -14,8 +15,8 @@ func RealtimeRoutes(router *mux.Router, h *handlers.RealtimeHandler, apiKeyAuth
 	// =========================================================================
 	// 📡 1. WEBSOCKET ENTRY POINT (SDK-ga Flutter wuxuu ka soo galaa halkan)
 	// =========================================================================
-	// ⚠️ MUHIIM: WebSocket route-ka halkan ayaan ku qoreynaa si uu u noqdo mid furan.
-	// Hubinta API Key-ga waxaa lagu dhex qabtaa HandleWebSocket dhexdiisa.
+	// 🔐 MUHIIM: Handshake-ku wuxuu u baahan yahay "?api_key=..." (APIKeyMiddleware ee router-ka)
+	// iyo ikhtiyaar ahaan "?access_token=<auth user JWT>" si user_id looga soo saaro token-ka.
 	router.HandleFunc("/ws/{project_id}", h.HandleWebSocket).Methods("GET")
 	router.HandleFunc("/ws", h.HandleWebSocket).Methods("GET") // Fallback route
 
@@ -28,6 +29,20 @@ func RealtimeRoutes(router *mux.Router, h *handlers.RealtimeHandler, apiKeyAuth
 	// Dhamaan API-yada hoose waxay u baahan yihiin API Key hubaal ah (X-API-KEY)
 	rt.Use(apiKeyAuth)
 
+	// --- 🧱 FALLBACK TRANSPORTS (proxies xannibaya WebSocket) ---
+	// Server-Sent Events: /realtime/stream?channels=a,b (Last-Event-ID resume)
+	rt.HandleFunc("/stream", h.HandleSSE).Methods("GET")
+
+	// Long-poll: /realtime/poll?channels=a,b&session_id=...&ack=<batch> (session_id waxaa soo celiya poll-ka ugu horreeya)
+	rt.HandleFunc("/poll", h.HandleLongPoll).Methods("GET")
+
+	// Connection ID ka hor stream/poll (?connection_id=) si private channel tokens (?auth=) loogu saxiixo
+	rt.HandleFunc("/connections", h.ReserveConnection).Methods("POST")
+
+	// --- ✉️ DIRECT MESSAGES ---
+	// U dir fariin user gaar ah (ama liis users ah) dhamaan connections-kooda, channel la'aan
+	rt.HandleFunc("/direct", h.SendDirectMessage).Methods("POST")
+
 	// --- 📺 CHANNEL MANAGEMENT (CRUD) ---
 	// Soo saar dhamaan channels-ka mashruuca
 	rt.HandleFunc("/channels", h.GetChannels).Methods("GET")
@@ -41,6 +56,9 @@ func RealtimeRoutes(router *mux.Router, h *handlers.RealtimeHandler, apiKeyAuth
 	// Tirtir channel gabi ahaanba
 	rt.HandleFunc("/channels/{id}", h.DeleteChannel).Methods("DELETE")
 
+	// 🗄️ Archive gareey (taariikhda waa la export gareeyaa haddii la dejiyay, kadib waa la tirtiraa)
+	rt.HandleFunc("/channels/{id}/archive", h.ArchiveChannel).Methods("POST")
+
 	// --- 📩 MESSAGE & EVENT HISTORY ---
 	// U dir fariin/event channel gaar ah (Broadcast via HTTP)
 	rt.HandleFunc("/channels/{channel_id}/events", h.CreateEvent).Methods("POST")
@@ -48,6 +66,23 @@ func RealtimeRoutes(router *mux.Router, h *handlers.RealtimeHandler, apiKeyAuth
 	// Soo saar taariikhda fariimaha (Message History) ee channel-kaas
 	rt.HandleFunc("/channels/{channel_id}/events", h.GetEvents).Methods("GET")
 
+	// Soo deji taariikhda oo dhan (NDJSON, hal event sadar kasta)
+	rt.HandleFunc("/channels/{channel_id}/events/export", h.ExportEvents).Methods("GET")
+
+	// --- 👥 PRESENCE ---
+	// Soo saar dadka hadda online ka ah channel-ka (metadata + tirada tabs-ka)
+	rt.HandleFunc("/channels/{channel_id}/presence", h.GetPresence).Methods("GET")
+
+	// --- 🧩 SHARED STATE (mode = shared_state) ---
+	// Soo saar state-ka hadda + revision
+	rt.HandleFunc("/channels/{channel_id}/state", h.GetState).Methods("GET")
+
+	// JSON Patch (RFC 6902) server-ka dhinaciisa; subscribers-ka waxay helaan state_patch
+	rt.HandleFunc("/channels/{channel_id}/state", h.PatchState).Methods("PATCH")
+
+	// Beddel state-ka oo dhan (channel-ka wuxuu noqdaa shared_state)
+	rt.HandleFunc("/channels/{channel_id}/state", h.ResetState).Methods("PUT")
+
 	// --- 🔧 SPECIFIC EVENT MANAGEMENT ---
 	// Wax ka bedel fariin hore u jirtay (Payload update)
 	rt.HandleFunc("/events/{event_id}", h.UpdateEvent).Methods("PUT", "PATCH")
@@ -57,3 +92,33 @@ func RealtimeRoutes(router *mux.Router, h *handlers.RealtimeHandler, apiKeyAuth
 
 	logger.Log.Info("🚀 Realtime routes optimized for SDK & Dashboard successfully.")
 }
+
+// RealtimeAdminRoutes: Inspector-ka, xiritaanka connections-ka iyo moderation-ka waxaa isticmaali kara
+// kaliya owner-ka project-ka (dashboard JWT). API key-ga app-ka (browser/mobile ku jira) kuma filna.
+// Wadada (Path): /api/v1/projects/{project_id}/realtime/admin
+func RealtimeAdminRoutes(router *mux.Router, h *handlers.RealtimeHandler, auth *middleware.AuthMiddleware, owner *middleware.ProjectOwnerMiddleware) {
+	admin := router.PathPrefix("/projects/{project_id}/realtime/admin").Subrouter()
+	admin.Use(auth.Authenticate)
+	admin.Use(owner.RequireOwner)
+
+	// --- 📊 METRICS & CONNECTION INSPECTOR (node-kan) ---
+	// Connections, subscriptions channel kasta, msgs in/out per second, dropped sends, queue depth
+	admin.HandleFunc("/metrics", h.GetMetrics).Methods("GET")
+
+	// Liiska connections-ka (?user_id=&channel=) iyo xiritaankooda (node kasta ha ahaadeen)
+	admin.HandleFunc("/connections", h.ListConnections).Methods("GET")
+	admin.HandleFunc("/connections/{connection_id}", h.CloseConnection).Methods("DELETE")
+
+	// --- 🛡️ MODERATION ---
+	// Ka saar user channel-ka hadda (connections-kiisa live ah); wuu soo laaban karaa
+	admin.HandleFunc("/channels/{channel_id}/kick", h.KickFromChannel).Methods("POST")
+
+	// Ban (subscribe + publish) ama mute (publish) user; channel_id la'aan = project-ka oo dhan
+	admin.HandleFunc("/moderation/{action:ban|mute}", h.Moderate).Methods("POST")
+
+	// Soo saar bans/mutes-ka weli shaqeeya (?channel_id= ikhtiyaari)
+	admin.HandleFunc("/moderation", h.ListModerations).Methods("GET")
+
+	// Unban / unmute
+	admin.HandleFunc("/moderation/{moderation_id}", h.LiftModeration).Methods("DELETE")
+}
@@ -21,7 +21,7 @@ func StorageRoutes(router *mux.Router, handler *handlers.StorageHandler, apiKeyA
 	// --- 🚀 BINARY FILE OPERATIONS ---
 
 	// POST /api/v1/projects/{project_id}/storage/upload
-	// Kani waa mishiinka sawirka dhabta ah (Binary) ka aqbalaya SDK-ga kuna ridayo Cloudinary
+	// Kani waa mishiinka file-ka dhabta ah (Binary) ka aqbalaya SDK-ga kuna ridayo driver-ka project-ka (local, s3, cloudinary)
 	storageRouter.HandleFunc("/upload", handler.UploadFile).Methods("POST")
 
 	// --- 📊 METADATA OPERATIONS (pgAdmin) ---
@@ -35,8 +35,63 @@ func StorageRoutes(router *mux.Router, handler *handlers.StorageHandler, apiKeyA
 	storageRouter.HandleFunc("/files", handler.GetFiles).Methods("GET")
 
 	// DELETE /api/v1/projects/{project_id}/storage/files/{id}
-	// Kani wuxuu tirtirayaa file gaar ah isagoo isticmaalaya UUID
+	// Kani wuxuu tirtirayaa file gaar ah isagoo isticmaalaya UUID (blob-ka driver-ka sidoo kale)
 	storageRouter.HandleFunc("/files/{id}", handler.DeleteFile).Methods("DELETE")
 
+	// GET /api/v1/projects/{project_id}/storage/files/{id}
+	// Record-ka + stat-ka backend-ka (size, etag, last modified)
+	storageRouter.HandleFunc("/files/{id}", handler.GetFile).Methods("GET")
+
+	// GET|HEAD /api/v1/projects/{project_id}/storage/files/{id}/download
+	// Soo dejinta driver-ka (local / s3 private); files-kii hore ee Cloudinary waa redirect
+	storageRouter.HandleFunc("/files/{id}/download", handler.DownloadFile).Methods("GET", "HEAD")
+
+	// GET|HEAD /api/v1/projects/{project_id}/storage/files/{id}/image?w=256&h=256&fit=cover&format=jpeg&q=80
+	// Thumbnails / resize / crop / format; variants-ka waxaa lagu kaydiyaa driver-ka (w/h: allowlist)
+	storageRouter.HandleFunc("/files/{id}/image", handler.ImageFile).Methods("GET", "HEAD")
+
+	// PUT /api/v1/projects/{project_id}/storage/files/{id}/access
+	// Visibility (public | authenticated | private) iyo allowed_users
+	storageRouter.HandleFunc("/files/{id}/access", handler.SetAccess).Methods("PUT")
+
+	// POST /api/v1/projects/{project_id}/storage/files/{id}/signed-url
+	// URL saxiixan oo dhacaya (AccessControl-ka ayaa marka hore la hubiyaa)
+	storageRouter.HandleFunc("/files/{id}/signed-url", handler.CreateSignedURL).Methods("POST")
+
+	// POST /api/v1/projects/{project_id}/storage/files/{id}/move
+	// Rename ama u rar bucket/folder kale (metadata kaliya)
+	storageRouter.HandleFunc("/files/{id}/move", handler.MoveFile).Methods("POST")
+
+	// --- 🗂️ BUCKETS + FOLDERS ---
+
+	// GET|POST /api/v1/projects/{project_id}/storage/buckets
+	storageRouter.HandleFunc("/buckets", handler.ListBuckets).Methods("GET")
+	storageRouter.HandleFunc("/buckets", handler.CreateBucket).Methods("POST")
+
+	// GET|PUT|DELETE /api/v1/projects/{project_id}/storage/buckets/{bucket}
+	storageRouter.HandleFunc("/buckets/{bucket}", handler.GetBucket).Methods("GET")
+	storageRouter.HandleFunc("/buckets/{bucket}", handler.UpdateBucket).Methods("PUT")
+	storageRouter.HandleFunc("/buckets/{bucket}", handler.DeleteBucket).Methods("DELETE")
+
+	// GET /api/v1/projects/{project_id}/storage/buckets/{bucket}/objects?prefix=avatars/&delimiter=/
+	// Files-ka + folders-ka (prefixes) heerka la codsaday, bogag ahaan (cursor)
+	storageRouter.HandleFunc("/buckets/{bucket}/objects", handler.ListObjects).Methods("GET")
+
+	// --- ⏫ RESUMABLE UPLOADS (tus 1.0) ---
+
+	// OPTIONS|POST /api/v1/projects/{project_id}/storage/tus
+	// Discovery iyo abuurista upload (Upload-Length, Upload-Metadata)
+	storageRouter.HandleFunc("/tus", handler.TusOptions).Methods("OPTIONS")
+	storageRouter.HandleFunc("/tus", handler.TusCreate).Methods("POST")
+
+	// HEAD|PATCH|DELETE /api/v1/projects/{project_id}/storage/tus/{upload_id}
+	// Offset-ka, chunks-ka iyo joojinta; file-ka waxaa la abuuraa marka chunk-ga ugu dambeeya la helo
+	storageRouter.HandleFunc("/tus/{upload_id}", handler.TusOptions).Methods("OPTIONS")
+	storageRouter.HandleFunc("/tus/{upload_id}", handler.TusHead).Methods("HEAD")
+	storageRouter.HandleFunc("/tus/{upload_id}", handler.TusStatus).Methods("GET")
+	storageRouter.HandleFunc("/tus/{upload_id}", handler.TusPatch).Methods("PATCH")
+	storageRouter.HandleFunc("/tus/{upload_id}", handler.TusDelete).Methods("DELETE")
+	storageRouter.HandleFunc("/tus/{upload_id}", handler.TusMethodOverride).Methods("POST")
+
 	logger.Log.Info("✅ Storage routes (including /upload) registered with API Key security.")
 }
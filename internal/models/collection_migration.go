@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+type MigrationOperation string
+
+const (
+	MigrationRenameField MigrationOperation = "rename_field"
+	MigrationSetDefault  MigrationOperation = "set_default"
+	MigrationCastType    MigrationOperation = "cast_type"
+	MigrationDropField   MigrationOperation = "drop_field"
+	MigrationMergePatch  MigrationOperation = "merge_patch"
+)
+
+type MigrationStatus string
+
+const (
+	MigrationPending   MigrationStatus = "pending"
+	MigrationRunning   MigrationStatus = "running"
+	MigrationCompleted MigrationStatus = "completed"
+	MigrationFailed    MigrationStatus = "failed"
+	MigrationCancelled MigrationStatus = "cancelled"
+)
+
+// CollectionMigration: Hal job oo wax ka bedela dhamaan documents-ka collection (Run History)
+type CollectionMigration struct {
+	ID           uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	ProjectID    string    `gorm:"type:uuid;index;not null" json:"project_id"`
+	CollectionID uuid.UUID `gorm:"type:uuid;index;not null" json:"collection_id"`
+
+	Operation MigrationOperation `gorm:"type:varchar(50);not null" json:"operation"`
+	Field     string             `gorm:"type:varchar(255)" json:"field,omitempty"`
+	NewField  string             `gorm:"type:varchar(255)" json:"new_field,omitempty"` // rename_field
+	Value     datatypes.JSON     `gorm:"type:jsonb" json:"value,omitempty"`            // set_default / merge_patch template
+	CastTo    string             `gorm:"type:varchar(20)" json:"cast_to,omitempty"`    // string | number | boolean
+	Filters   datatypes.JSON     `gorm:"type:jsonb;default:'[]'" json:"filters"`       // Kaliya documents-ka u dhigma
+	DryRun    bool               `gorm:"default:false" json:"dry_run"`
+	BatchSize int                `gorm:"default:500" json:"batch_size"`
+
+	// 🚀 PROGRESS (Resumable Batches)
+	Status       MigrationStatus `gorm:"type:varchar(20);default:'pending';index" json:"status"`
+	Cursor       *uuid.UUID      `gorm:"type:uuid" json:"cursor,omitempty"` // Document-kii ugu dambeeyay ee la dhameeyay
+	Total        int64           `gorm:"default:0" json:"total"`
+	Processed    int64           `gorm:"default:0" json:"processed"`
+	Modified     int64           `gorm:"default:0" json:"modified"`
+	Failed       int64           `gorm:"default:0" json:"failed"`
+	Preview      datatypes.JSON  `gorm:"type:jsonb;default:'[]'" json:"preview"` // Dry-run: tusaalooyin before/after
+	ErrorMessage *string         `json:"error_message,omitempty"`
+	StartedAt    *time.Time      `json:"started_at,omitempty"`
+	FinishedAt   *time.Time      `json:"finished_at,omitempty"`
+	LeaseOwner   string          `gorm:"type:varchar(64);index" json:"-"` // Worker-ka hadda haysta job-ka (claim kasta token cusub)
+	CreatedAt    time.Time       `json:"created_at"`
+	UpdatedAt    time.Time       `json:"updated_at"`
+}
+
+func (m *CollectionMigration) BeforeCreate(tx *gorm.DB) (err error) {
+	if m.ID == uuid.Nil {
+		m.ID = uuid.New()
+	}
+	return
+}
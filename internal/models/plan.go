@@ -18,9 +18,11 @@ type Plan struct {
 	MaxNotifications    int       `gorm:"default:0" json:"max_notifications"`
 	MaxRealtimeChannels int       `gorm:"default:0" json:"max_realtime_channels"` // Xadka qolalka
 	MaxRealtimeEvents   int       `gorm:"default:0" json:"max_realtime_events"`   // Xadka fariimaha
-	IsActive            bool      `gorm:"default:true" json:"is_active"`
-	CreatedAt           time.Time `json:"created_at"`
-	UpdatedAt           time.Time `json:"updated_at"`
+	// Connections hal channel marka channel-ku MaxClients lahayn (0 ama -1 = xad la'aan)
+	MaxRealtimeChannelClients int       `gorm:"default:0" json:"max_realtime_channel_clients"`
+	IsActive                  bool      `gorm:"default:true" json:"is_active"`
+	CreatedAt                 time.Time `json:"created_at"`
+	UpdatedAt                 time.Time `json:"updated_at"`
 }
 
 func (p *Plan) BeforeCreate(tx *gorm.DB) (err error) {
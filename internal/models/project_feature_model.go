@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -28,6 +29,62 @@ type ProjectFeature struct {
 	UpdatedAt time.Time          `json:"updated_at"`
 }
 
+// featureSecretKeys: Config keys-ka server-ka kaliya (HMAC keys, cloud credentials). Route-ka /features waxaa ilaaliya
+// API key-ga kaliya (SDK kasta wuu haystaa), markaa responses-ka lagama soo celiyo; "<key>_set": true ayaa
+// dashboard-ka u sheegaya inuu jiro.
+var featureSecretKeys = []string{
+	"auth_secret",       // realtime: channel tokens + auth endpoint signature
+	"secret_access_key", // storage: S3
+	"api_secret",        // storage: Cloudinary
+}
+
+// MarshalJSON: Config-ga waxaa laga saaraa secrets-ka (features + project responses oo dhan)
+func (f ProjectFeature) MarshalJSON() ([]byte, error) {
+	type plain ProjectFeature
+	out := plain(f)
+	out.Config = RedactFeatureConfig(f.Config)
+	return json.Marshal(out)
+}
+
+// RedactFeatureConfig: Nuqul config ah oo secrets-ka laga saaray
+func RedactFeatureConfig(config datatypes.JSON) datatypes.JSON {
+	var cfg map[string]interface{}
+	if len(config) == 0 || json.Unmarshal(config, &cfg) != nil || cfg == nil {
+		return config
+	}
+	for _, key := range featureSecretKeys {
+		if v, ok := cfg[key]; ok {
+			delete(cfg, key)
+			cfg[key+"_set"] = v != nil && v != ""
+		}
+	}
+	out, _ := json.Marshal(cfg)
+	return out
+}
+
+// MergeFeatureSecrets: Config cusub oo secrets-kii hore la hayo haddii aan la soo dirin (ama "" la soo diray);
+// null = tirtir. "<key>_set" markers-ka response-ka waa la iska tuuraa.
+func MergeFeatureSecrets(stored, incoming datatypes.JSON) datatypes.JSON {
+	var in map[string]interface{}
+	if len(incoming) == 0 || json.Unmarshal(incoming, &in) != nil || in == nil {
+		return incoming
+	}
+	var old map[string]interface{}
+	_ = json.Unmarshal(stored, &old)
+	for _, key := range featureSecretKeys {
+		delete(in, key+"_set")
+		v, ok := in[key]
+		switch {
+		case ok && v == nil:
+			delete(in, key)
+		case (!ok || v == "") && old[key] != nil:
+			in[key] = old[key]
+		}
+	}
+	out, _ := json.Marshal(in)
+	return out
+}
+
 // SeedDefaultFeatures: Kani wuxuu dhalinayaa dhammaan adeegyada mashruucu leeyahay marka la dhisayo
 func SeedDefaultFeatures(projectID string) []ProjectFeature {
 	featureTypes := []ProjectFeatureType{
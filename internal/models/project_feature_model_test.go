@@ -0,0 +1,55 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"gorm.io/datatypes"
+)
+
+func TestMergeFeatureSecrets(t *testing.T) {
+	stored := datatypes.JSON(`{"auth_secret":"old","auth_endpoint":"https://a.example"}`)
+	tests := []struct {
+		name     string
+		incoming string
+		want     map[string]interface{}
+	}{
+		{"omitted keeps", `{"auth_endpoint":"https://b.example"}`, map[string]interface{}{"auth_secret": "old", "auth_endpoint": "https://b.example"}},
+		{"empty keeps", `{"auth_secret":""}`, map[string]interface{}{"auth_secret": "old"}},
+		{"redacted echo keeps", `{"auth_secret_set":true}`, map[string]interface{}{"auth_secret": "old"}},
+		{"new value replaces", `{"auth_secret":"new"}`, map[string]interface{}{"auth_secret": "new"}},
+		{"null removes", `{"auth_secret":null}`, map[string]interface{}{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got map[string]interface{}
+			if err := json.Unmarshal(MergeFeatureSecrets(stored, datatypes.JSON(tt.incoming)), &got); err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Fatalf("got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+// Project responses-ka (project_features preload) sidoo kale secrets ma sitaan
+func TestProjectJSONOmitsFeatureSecrets(t *testing.T) {
+	p := Project{ID: "p", ProjectFeatures: []ProjectFeature{{
+		Type:   FeatureTypeStorage,
+		Config: datatypes.JSON(`{"driver":"cloudinary","cloud_name":"demo","api_key":"k","api_secret":"hidden-cloudinary"}`),
+	}}}
+	raw, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := string(raw); strings.Contains(s, "hidden-cloudinary") || !strings.Contains(s, `"api_secret_set":true`) {
+		t.Fatalf("unexpected project JSON: %s", s)
+	}
+}
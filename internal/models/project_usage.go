@@ -28,6 +28,11 @@ type ProjectUsage struct {
 	LimitNotifications    int     `gorm:"default:0" json:"limit_notifications"`
 	LimitRealtimeChannels int     `gorm:"default:0" json:"limit_realtime_channels"` // 👈 Hubi inuu yahay 'limit_realtime_channels'
 	LimitRealtimeEvents   int     `gorm:"default:0" json:"limit_realtime_events"`   // 👈 Hubi inuu yahay 'limit_realtime_events'
+	// Connections hal channel (channel.MaxClients = 0 → kan; 0 ama -1 = xad la'aan)
+	LimitRealtimeChannelClients int `gorm:"default:0" json:"limit_realtime_channel_clients"`
+
+	// 📅 Bilowga bisha realtime_events_count la tirinayo (bil kasta 0 ayuu ka bilaabmaa)
+	RealtimePeriodStart *time.Time `json:"realtime_period_start,omitempty"`
 
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
@@ -16,6 +16,14 @@ const (
 	SubscriptionPrivate   RealtimeSubscriptionType = "private"
 )
 
+// RealtimeChannelMode: "stream" = fariimo caadi ah; "shared_state" = server-ku wuxuu hayaa JSON state (JSON Patch)
+type RealtimeChannelMode string
+
+const (
+	ChannelModeStream      RealtimeChannelMode = "stream"
+	ChannelModeSharedState RealtimeChannelMode = "shared_state"
+)
+
 type RealtimeRetentionPolicy string
 
 const (
@@ -27,21 +35,27 @@ type RealtimeChannel struct {
 	ID        uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
 	ProjectID string    `gorm:"type:uuid;index;not null" json:"project_id"`
 
-	Name             string                   `gorm:"type:varchar(255);uniqueIndex:idx_project_channel_name;not null" json:"name"`
-	Description      *string                  `json:"description,omitempty"`
-	IsPrivate        bool                     `gorm:"default:false" json:"is_private"`
-	ConnectedClients int                      `gorm:"default:0" json:"connected_clients"`
-	MaxClients       int                      `gorm:"default:100" json:"max_clients"`
-	SubscriptionType RealtimeSubscriptionType `gorm:"type:varchar(50);default:'public'" json:"subscription_type"`
-	CreatedAt        time.Time                `json:"created_at"`
-	UpdatedAt        time.Time                `json:"updated_at"`
-	LastMessageAt    *time.Time               `json:"last_message_at,omitempty"`
-	Metadata         datatypes.JSON           `gorm:"type:jsonb;default:'{}'" json:"metadata"`
-	RetentionPolicy  RealtimeRetentionPolicy  `gorm:"type:varchar(50);default:'ephemeral'" json:"retention_policy"`
-	Archived         bool                     `gorm:"default:false" json:"archived"`
-	ArchivedAt       *time.Time               `json:"archived_at,omitempty"`
-	Tags             datatypes.JSON           `gorm:"type:jsonb;default:'[]'" json:"tags"`
-	Events           []RealtimeEvent          `gorm:"foreignKey:ChannelID" json:"-"` // Relation
+	Name               string                   `gorm:"type:varchar(255);uniqueIndex:idx_project_channel_name;not null" json:"name"`
+	Description        *string                  `json:"description,omitempty"`
+	IsPrivate          bool                     `gorm:"default:false" json:"is_private"`
+	ConnectedClients   int                      `gorm:"default:0" json:"connected_clients"`
+	MaxClients         int                      `gorm:"default:0" json:"max_clients"` // 0 = limit-ka plan-ka (limit_realtime_channel_clients)
+	SubscriptionType   RealtimeSubscriptionType `gorm:"type:varchar(50);default:'public'" json:"subscription_type"`
+	CreatedAt          time.Time                `json:"created_at"`
+	UpdatedAt          time.Time                `json:"updated_at"`
+	LastMessageAt      *time.Time               `json:"last_message_at,omitempty"`
+	LastSequence       int64                    `gorm:"default:0" json:"last_sequence"` // Sequence-kii ugu dambeeyay ee event-yada
+	Metadata           datatypes.JSON           `gorm:"type:jsonb;default:'{}'" json:"metadata"`
+	Mode               RealtimeChannelMode      `gorm:"type:varchar(20);default:'stream'" json:"mode"`
+	State              datatypes.JSON           `gorm:"type:jsonb" json:"state,omitempty"` // Shared state (mode = shared_state)
+	StateRevision      int64                    `gorm:"default:0" json:"state_revision"`   // Patch kasta +1
+	RetentionPolicy    RealtimeRetentionPolicy  `gorm:"type:varchar(50);default:'ephemeral'" json:"retention_policy"`
+	RetentionMaxAge    int                      `gorm:"default:0" json:"retention_max_age"`    // Ilbiriqsi; events ka da' weyn waa la tirtiraa (0 = xad la'aan)
+	RetentionMaxEvents int                      `gorm:"default:0" json:"retention_max_events"` // Inta ugu badan ee la hayo (0 = xad la'aan)
+	Archived           bool                     `gorm:"default:false" json:"archived"`
+	ArchivedAt         *time.Time               `json:"archived_at,omitempty"`
+	Tags               datatypes.JSON           `gorm:"type:jsonb;default:'[]'" json:"tags"`
+	Events             []RealtimeEvent          `gorm:"foreignKey:ChannelID" json:"-"` // Relation
 }
 
 func (c *RealtimeChannel) BeforeCreate(tx *gorm.DB) (err error) {
@@ -21,7 +21,8 @@ const (
 
 type RealtimeEvent struct {
 	ID                uuid.UUID         `gorm:"type:uuid;primaryKey" json:"id"`
-	ChannelID         uuid.UUID         `gorm:"type:uuid;index;not null" json:"channel_id"`
+	ChannelID         uuid.UUID         `gorm:"type:uuid;index;index:idx_event_channel_seq;not null" json:"channel_id"`
+	Sequence          int64             `gorm:"index:idx_event_channel_seq;default:0" json:"seq"` // Tirsi kordhaya channel kasta (replay & gap detection)
 	EventType         RealtimeEventType `gorm:"type:varchar(50);default:'custom'" json:"event_type"`
 	Payload           datatypes.JSON    `gorm:"type:jsonb;not null" json:"payload"`
 	SenderID          *string           `gorm:"type:uuid" json:"sender_id,omitempty"`
@@ -33,6 +34,8 @@ type RealtimeEvent struct {
 	UserAgent         *string           `json:"user_agent,omitempty"`
 	LatencyMs         float64           `json:"latency_ms"`
 	ErrorMessage      *string           `json:"error_message,omitempty"`
+	EditedAt          *time.Time        `json:"edited_at,omitempty"`         // Markii ugu dambeysay ee payload-ka la beddelay
+	EditCount         int               `gorm:"default:0" json:"edit_count"` // Inta jeer ee la beddelay
 	CreatedAt         time.Time         `json:"created_at"`
 	UpdatedAt         time.Time         `json:"updated_at"`
 }
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type RealtimeModerationAction string
+
+const (
+	ModerationBan  RealtimeModerationAction = "ban"  // Ma subscribe gareyn karo, mana dirayo
+	ModerationMute RealtimeModerationAction = "mute" // Wuu dhagaysan karaa laakiin ma dirayo
+	ModerationKick RealtimeModerationAction = "kick" // Hal mar: connections-ka hadda ayaa laga saarayaa (lama keydiyo)
+)
+
+// RealtimeModeration: Ban ama mute; ChannelID nil = project-ka oo dhan. ExpiresAt nil = weligeed.
+type RealtimeModeration struct {
+	ID          uuid.UUID                `gorm:"type:uuid;primaryKey" json:"id"`
+	ProjectID   string                   `gorm:"type:uuid;not null;index:idx_moderation_lookup" json:"project_id"`
+	UserID      string                   `gorm:"type:uuid;not null;index:idx_moderation_lookup" json:"user_id"`
+	ChannelID   *uuid.UUID               `gorm:"type:uuid;index" json:"channel_id,omitempty"`
+	ChannelName string                   `gorm:"type:varchar(255)" json:"channel_name,omitempty"`
+	Action      RealtimeModerationAction `gorm:"type:varchar(20);not null" json:"action"`
+	Reason      string                   `gorm:"type:text" json:"reason,omitempty"`
+	ExpiresAt   *time.Time               `gorm:"index" json:"expires_at,omitempty"`
+	CreatedAt   time.Time                `json:"created_at"`
+}
+
+func (m *RealtimeModeration) BeforeCreate(tx *gorm.DB) (err error) {
+	if m.ID == uuid.Nil {
+		m.ID = uuid.New()
+	}
+	return
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// RealtimePresence: Hal connection (tab/device) oo ku jira channel.
+// Isla user-ku wuxuu yeelan karaa rows badan (tabs badan); PresenceKey ayaa la isku xiraa.
+type RealtimePresence struct {
+	ID           uuid.UUID      `gorm:"type:uuid;primaryKey" json:"id"`
+	ProjectID    string         `gorm:"type:uuid;index;not null" json:"project_id"`
+	ChannelID    uuid.UUID      `gorm:"type:uuid;not null;uniqueIndex:idx_presence_channel_conn;index:idx_presence_channel_key" json:"channel_id"`
+	ChannelName  string         `gorm:"type:varchar(255);not null" json:"channel_name"`
+	ConnectionID string         `gorm:"type:varchar(64);not null;uniqueIndex:idx_presence_channel_conn;index" json:"connection_id"`
+	PresenceKey  string         `gorm:"type:varchar(100);not null;index:idx_presence_channel_key" json:"presence_key"` // user_id ama "anon:<connection_id>"
+	UserID       *string        `gorm:"type:uuid" json:"user_id,omitempty"`
+	NodeID       string         `gorm:"type:varchar(100);index" json:"node_id"`
+	Metadata     datatypes.JSON `gorm:"type:jsonb;default:'{}'" json:"metadata"`
+	JoinedAt     time.Time      `json:"joined_at"`
+	LastSeenAt   time.Time      `gorm:"index" json:"last_seen_at"`
+}
+
+func (p *RealtimePresence) BeforeCreate(tx *gorm.DB) (err error) {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return
+}
+
+// PresenceMember: Hal qof oo online ah (tabs-kiisa oo la isku daray)
+type PresenceMember struct {
+	PresenceKey string         `json:"presence_key"`
+	UserID      *string        `json:"user_id,omitempty"`
+	Metadata    datatypes.JSON `json:"metadata"`
+	Connections int            `json:"connections"`
+	JoinedAt    time.Time      `json:"joined_at"`
+	LastSeenAt  time.Time      `json:"last_seen_at"`
+}
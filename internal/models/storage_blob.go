@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// StorageBlob: Content la wadaago (deduplication). Files-ka project-ka ee SHA-256 isku mid ah waxay
+// tixraacaan hal blob oo driver-ka ah; RefCount waa inta file ee tixraacda. Blob-ka driver-ka waxaa
+// la tirtiraa kaliya marka tixraaca ugu dambeeya la tirtiro, usage-kuna bytes-ka unique ah ayuu xisaabiyaa.
+type StorageBlob struct {
+	ID         uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	ProjectID  string    `gorm:"type:uuid;not null;uniqueIndex:idx_storage_blobs_content" json:"project_id"`
+	Driver     string    `gorm:"type:varchar(20);not null;uniqueIndex:idx_storage_blobs_content" json:"driver"`
+	Checksum   string    `gorm:"type:varchar(64);not null;uniqueIndex:idx_storage_blobs_content" json:"checksum"` // SHA-256 (hex)
+	StorageKey string    `gorm:"type:varchar(1024);not null" json:"storage_key"`
+	URL        string    `json:"url,omitempty"` // URL-ka driver-ka (Cloudinary, S3 public_url)
+	SizeBytes  int64     `json:"size_bytes"`
+	RefCount   int64     `gorm:"not null;default:1" json:"ref_count"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+func (b *StorageBlob) BeforeCreate(tx *gorm.DB) (err error) {
+	if b.ID == uuid.Nil {
+		b.ID = uuid.New()
+	}
+	return
+}
+
+func (StorageBlob) TableName() string {
+	return "storage_blobs"
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// StorageBucket: Kooxda files-ka project-ka (avatars, receipts, exports, ...). Bucket kasta wuxuu
+// leeyahay visibility-ga default-ka ah, MIME types-ka la ogol yahay, xadka size-ka iyo folders-ka
+// (prefixes) la ogol yahay. Files-ku waxay ku jiraan Path (avatars/u1/me.png) gudaha bucket-ka.
+type StorageBucket struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	ProjectID string    `gorm:"type:uuid;not null;uniqueIndex:idx_storage_buckets_project_name" json:"project_id"`
+	Name      string    `gorm:"type:varchar(63);not null;uniqueIndex:idx_storage_buckets_project_name" json:"name"`
+
+	Visibility       string         `gorm:"type:varchar(20);not null;default:'public'" json:"visibility"` // Files-ka upload-ka ee aan visibility sheegin
+	AllowedMimeTypes datatypes.JSON `gorm:"type:jsonb;default:'[]'" json:"allowed_mime_types"`            // ["image/*", "application/pdf"]; [] = dhammaan
+	MaxFileSize      int64          `gorm:"not null;default:0" json:"max_file_size"`                      // Bytes, 0 = xad la'aan
+	AllowedPrefixes  datatypes.JSON `gorm:"type:jsonb;default:'[]'" json:"allowed_prefixes"`              // ["avatars/", "receipts/2024/"]; [] = meel kasta
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// MimeTypes: AllowedMimeTypes-ka la kala saaray
+func (b *StorageBucket) MimeTypes() []string {
+	var types []string
+	_ = json.Unmarshal(b.AllowedMimeTypes, &types)
+	return types
+}
+
+// Prefixes: AllowedPrefixes-ka la kala saaray
+func (b *StorageBucket) Prefixes() []string {
+	var prefixes []string
+	_ = json.Unmarshal(b.AllowedPrefixes, &prefixes)
+	return prefixes
+}
+
+func (b *StorageBucket) BeforeCreate(tx *gorm.DB) (err error) {
+	if b.ID == uuid.Nil {
+		b.ID = uuid.New()
+	}
+	return
+}
+
+func (StorageBucket) TableName() string {
+	return "storage_buckets"
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"encoding/json"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -13,22 +15,103 @@ type StorageFile struct {
 	ID        uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
 	ProjectID string    `gorm:"type:uuid;index;not null" json:"project_id"`
 
+	// 🗂️ Bucket + path-ka gudaha bucket-ka (avatars/u1/me.png). Files-kii hore: bucket la'aan (liiska flat-ka ah)
+	BucketID *uuid.UUID `gorm:"type:uuid;index:idx_storage_files_bucket_path,unique,where:deleted_at IS NULL" json:"bucket_id,omitempty"`
+	Path     string     `gorm:"type:varchar(1024);index:idx_storage_files_bucket_path,unique,where:deleted_at IS NULL" json:"path,omitempty"`
+
 	FileName       string         `gorm:"type:varchar(255);not null" json:"file_name"`
 	FileType       string         `gorm:"type:varchar(100);not null" json:"file_type"` // MIME type
 	SizeMB         float64        `gorm:"not null" json:"size_mb"`
 	URL            string         `gorm:"not null" json:"url"`
+	Driver         string         `gorm:"type:varchar(20)" json:"driver,omitempty"`        // local | s3 | cloudinary ("" = legacy Cloudinary upload)
+	StorageKey     string         `gorm:"type:varchar(1024)" json:"storage_key,omitempty"` // Key-ga driver-ka (download/stat/delete)
+	SizeBytes      int64          `json:"size_bytes"`
 	UploadedBy     *string        `gorm:"type:uuid" json:"uploaded_by,omitempty"`
 	UploadedAt     time.Time      `json:"uploaded_at"`
 	LastAccessedAt *time.Time     `json:"last_accessed_at,omitempty"`
 	AccessControl  datatypes.JSON `gorm:"type:jsonb;default:'{}'" json:"access_control"`
 	Version        int            `gorm:"default:1" json:"version"`
 	Metadata       datatypes.JSON `gorm:"type:jsonb;default:'{}'" json:"metadata"`
-	Checksum       *string        `gorm:"type:varchar(255)" json:"checksum,omitempty"`
-	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"` // GORM soft delete support
+	Checksum       *string        `gorm:"type:varchar(255)" json:"checksum,omitempty"` // SHA-256 (hex) ee content-ka
+	BlobID         *uuid.UUID     `gorm:"type:uuid;index" json:"blob_id,omitempty"`    // Blob la wadaago (dedup); nil = StorageKey-ga file-kan kaliya ayaa leh
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`                              // GORM soft delete support
+}
+
+// File visibility (AccessControl.visibility)
+const (
+	FileVisibilityPublic        = "public"        // URL-ka waa joogto (default, files-kii hore)
+	FileVisibilityAuthenticated = "authenticated" // Auth user kasta oo project-ka ah (signed URL)
+	FileVisibilityPrivate       = "private"       // Uploader-ka + allowed_users kaliya (signed URL)
+)
+
+// StorageAccessControl: Qaabka AccessControl JSON-ka
+//
+//	{"visibility": "private", "allowed_users": ["<auth user id>", ...]}
+type StorageAccessControl struct {
+	Visibility   string   `json:"visibility"`
+	AllowedUsers []string `json:"allowed_users,omitempty"`
+}
+
+// Access: AccessControl-ka la kala saaray ("" ama JSON khaldan = public)
+func (sf *StorageFile) Access() StorageAccessControl {
+	var acl StorageAccessControl
+	_ = json.Unmarshal(sf.AccessControl, &acl)
+	if acl.Visibility == "" {
+		acl.Visibility = FileVisibilityPublic
+	}
+	return acl
+}
+
+// RequiresSignedURL: Bytes-ka waxaa lagu heli karaa kaliya signed URL
+func (sf *StorageFile) RequiresSignedURL() bool {
+	return sf.Access().Visibility != FileVisibilityPublic
+}
+
+// storageFileMetadata: Qaybaha Metadata-ga ee server-ku qoro
+type storageFileMetadata struct {
+	Variants     map[string]string `json:"variants"`      // Magaca variant-ka (w256_h256_cover-center_q80.jpg) → key-ga driver-ka
+	VariantBytes int64             `json:"variant_bytes"` // Wadarta bytes-ka variants-ka (storage_used_mb)
+}
+
+// VariantKey: Key-ga image variant la kaydiyay ("" haddii aan weli la abuurin)
+func (sf *StorageFile) VariantKey(name string) string {
+	var meta storageFileMetadata
+	_ = json.Unmarshal(sf.Metadata, &meta)
+	if key := meta.Variants[name]; strings.HasPrefix(key, sf.variantPrefix()) {
+		return key
+	}
+	return ""
+}
+
+// VariantKeys: Dhammaan variants-ka (DeleteFile)
+func (sf *StorageFile) VariantKeys() []string {
+	var meta storageFileMetadata
+	_ = json.Unmarshal(sf.Metadata, &meta)
+	keys := make([]string, 0, len(meta.Variants))
+	for _, key := range meta.Variants {
+		if strings.HasPrefix(key, sf.variantPrefix()) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// VariantMB: Bytes-ka variants-ka ee lagu daray storage_used_mb (DeleteFile ayaa ka jara)
+func (sf *StorageFile) VariantMB() float64 {
+	var meta storageFileMetadata
+	_ = json.Unmarshal(sf.Metadata, &meta)
+	return float64(meta.VariantBytes) / (1024 * 1024)
+}
+
+// variantPrefix: Variants-ku waxay ku jiraan folder-ka file-ka kaliya (key kale oo metadata-ga lagu qoray waa la iska indhatiraa)
+func (sf *StorageFile) variantPrefix() string {
+	return sf.ProjectID + "/" + sf.ID.String() + "/variants/"
 }
 
 func (sf *StorageFile) BeforeCreate(tx *gorm.DB) (err error) {
-	sf.ID = uuid.New()
+	if sf.ID == uuid.Nil {
+		sf.ID = uuid.New() // Upload-ku horay ayuu u sameeyaa (storage key-ga ayaa ku jira)
+	}
 	sf.UploadedAt = time.Now()
 	sf.Version = 1
 	return
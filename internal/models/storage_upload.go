@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// StorageUpload: Upload resumable ah (tus 1.0) oo socda. Bytes-ka waxay ku jiraan staging file-ka
+// node-ka (STORAGE_UPLOAD_DIR); marka UploadOffset == UploadLength ayaa driver-ka loo gudbiyaa
+// oo StorageFile la abuuraa (FileID). Row-ku wuu sii jiraa ilaa ExpiresAt si HEAD-ku u sheego dhammaadka.
+type StorageUpload struct {
+	ID            uuid.UUID      `gorm:"type:uuid;primaryKey" json:"id"`
+	ProjectID     string         `gorm:"type:uuid;index;not null" json:"project_id"`
+	FileName      string         `gorm:"type:varchar(255);not null" json:"file_name"`
+	FileType      string         `gorm:"type:varchar(100)" json:"file_type"`
+	UploadLength  int64          `gorm:"not null" json:"upload_length"`
+	UploadOffset  int64          `gorm:"not null;default:0" json:"upload_offset"`
+	Metadata      string         `gorm:"type:text" json:"metadata,omitempty"` // Upload-Metadata header-kii asalka ahaa (HEAD ayaa dib u celiya)
+	UploadedBy    *string        `gorm:"type:uuid" json:"uploaded_by,omitempty"`
+	AccessControl datatypes.JSON `gorm:"type:jsonb;default:'{}'" json:"access_control"`
+	Bucket        string         `gorm:"type:varchar(63)" json:"bucket,omitempty"` // Magaca bucket-ka ("" = bucket la'aan)
+	Path          string         `gorm:"type:varchar(1024)" json:"path,omitempty"`
+	Checksum      string         `gorm:"type:varchar(64)" json:"checksum,omitempty"` // SHA-256 (hex) ee file-ka oo dhan (X-Checksum-Sha256); finalize-ka ayaa hubiya
+	FileID        *uuid.UUID     `gorm:"type:uuid" json:"file_id,omitempty"`         // StorageFile-ka marka upload-ku dhammaado
+	ExpiresAt     time.Time      `gorm:"index" json:"expires_at"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+}
+
+// Completed: Bytes-ka oo dhan waa la helay, StorageFile-kana waa la abuuray
+func (u *StorageUpload) Completed() bool {
+	return u.FileID != nil
+}
+
+func (u *StorageUpload) BeforeCreate(tx *gorm.DB) (err error) {
+	if u.ID == uuid.Nil {
+		u.ID = uuid.New()
+	}
+	return
+}
+
+func (StorageUpload) TableName() string {
+	return "storage_uploads"
+}
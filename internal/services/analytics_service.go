@@ -5,6 +5,7 @@ import (
 	"superaib/internal/core/logger"
 	"superaib/internal/models"
 	"superaib/internal/storage/repo"
+	"sync"
 	"time"
 
 	"gorm.io/datatypes"
@@ -12,11 +13,14 @@ import (
 )
 
 type AnalyticsTracker struct {
-	repo repo.AnalyticsRepository
+	repo     repo.AnalyticsRepository
+	inflight sync.WaitGroup // Writes-ka socda (graceful shutdown)
 }
 
 func (t *AnalyticsTracker) TrackEvent(ctx context.Context, projectID string, aType models.AnalyticsType, key string, value float64) {
+	t.inflight.Add(1)
 	go func() {
+		defer t.inflight.Done()
 		now := time.Now()
 		periodStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
 		err := t.repo.IncrementMetric(context.Background(), projectID, aType, periodStart, key, value)
@@ -26,6 +30,26 @@ func (t *AnalyticsTracker) TrackEvent(ctx context.Context, projectID string, aTy
 	}()
 }
 
+// Shutdown: Sug analytics writes-ka weli socda si aan tirooyin u lumin marka server-ku istaagayo
+func (t *AnalyticsTracker) Shutdown(ctx context.Context) error {
+	return waitGroupWithContext(ctx, &t.inflight)
+}
+
+// waitGroupWithContext: wg.Wait() oo leh deadline
+func waitGroupWithContext(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 type AnalyticsService interface {
 	CreateInitialAnalyticsForProject(ctx context.Context, tx *gorm.DB, projectID string) error
 	GetAnalyticsForCurrentMonth(ctx context.Context, projectID string) ([]models.Analytics, error)
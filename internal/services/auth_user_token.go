@@ -0,0 +1,37 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"superaib/internal/core/security"
+	"superaib/internal/models"
+	"superaib/internal/storage/repo"
+
+	"github.com/google/uuid"
+)
+
+var ErrInvalidAuthUserToken = errors.New("invalid or expired user token")
+
+// verifyAuthUserToken: JWT-ga auth user-ka (role = auth_user) → user_id.
+// User-ku waa inuu ka tirsan yahay project-ka oo uusan xirnayn (realtime iyo storage way wadaagaan).
+func verifyAuthUserToken(ctx context.Context, users repo.AuthUserRepository, projectID, token string) (string, error) {
+	claims, err := security.ValidateJWT(token)
+	if err != nil {
+		return "", ErrInvalidAuthUserToken
+	}
+	if role, _ := claims["role"].(string); role != "auth_user" {
+		return "", ErrInvalidAuthUserToken
+	}
+
+	userID, _ := claims["user_id"].(string)
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return "", ErrInvalidAuthUserToken
+	}
+
+	user, err := users.GetByID(ctx, id)
+	if err != nil || user.ProjectID != projectID || user.Status != models.AuthUserActive {
+		return "", ErrInvalidAuthUserToken
+	}
+	return user.ID.String(), nil
+}
@@ -0,0 +1,630 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"superaib/internal/core/logger"
+	"superaib/internal/models"
+	"superaib/internal/storage/repo"
+	"superaib/pkg/utils"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultMigrationBatchSize = 500
+	maxMigrationBatchSize     = 5000
+	maxMigrationPreview       = 10
+
+	// Worker-ku checkpoint ayuu qoraa batch kasta iyo heartbeat migrationHeartbeat kasta (updated_at), sidaas
+	// darteed batch weyn oo daahay lease-ka ma lumiyo. Job "running" ah oo aan la taaban migrationLease
+	// waxaa loo haystaa in worker-kiisii (replica kale ama process hore) dhintay.
+	migrationLease         = 5 * time.Minute
+	migrationHeartbeat     = time.Minute
+	migrationSweepInterval = time.Minute
+)
+
+// errMigrationLeaseLost: Job-ka waa la joojiyay (Cancel) ama worker kale ayaa qaatay
+var errMigrationLeaseLost = errors.New("migration lease lost")
+
+// CollectionMigrationRequest: Waxa developer-ku soo diro marka uu job cusub bilaabayo
+type CollectionMigrationRequest struct {
+	Operation models.MigrationOperation `json:"operation"`
+	Field     string                    `json:"field"`
+	NewField  string                    `json:"new_field"`
+	Value     interface{}               `json:"value"`
+	CastTo    string                    `json:"cast_to"`
+	Filters   []repo.Filter             `json:"filters"`
+	DryRun    bool                      `json:"dry_run"`
+	BatchSize int                       `json:"batch_size"`
+}
+
+type CollectionMigrationService interface {
+	Start(ctx context.Context, projectID, collectionName string, req CollectionMigrationRequest) (*models.CollectionMigration, error)
+	Get(ctx context.Context, projectID, migrationID string) (*models.CollectionMigration, error)
+	History(ctx context.Context, projectID, collectionName string) ([]models.CollectionMigration, error)
+	Cancel(ctx context.Context, projectID, migrationID string) error
+	Resume(ctx context.Context, projectID, migrationID string) (*models.CollectionMigration, error)
+
+	// ResumeInterrupted: Background sweep; sii wad jobs-kii "running" ahaa ee worker-koodu dhintay (ctx-ka ayaa joojiya)
+	ResumeInterrupted(ctx context.Context)
+	// Shutdown: Workers-ka jooji (batch-ka socda waa la checkpoint gareeyaa, status-ku "running" ayuu ahaanayaa
+	// si replica kale ama process-ka xiga uu u sii wado) kadibna sug inta ay dhammaanayaan (ama ctx-ku dhaco)
+	Shutdown(ctx context.Context) error
+}
+
+type collectionMigrationService struct {
+	repo    repo.CollectionMigrationRepository
+	docRepo repo.DocumentRepository
+	tracker *AnalyticsTracker
+
+	mu      sync.Mutex
+	cancels map[uuid.UUID]*migrationWorker
+
+	// 🛑 Shutdown: workers-ka oo dhan waxay ka dhashaan ctx-kan; workers waa la sugaa
+	ctx     context.Context
+	stop    context.CancelFunc
+	workers sync.WaitGroup
+}
+
+func NewCollectionMigrationService(r repo.CollectionMigrationRepository, dr repo.DocumentRepository, tracker *AnalyticsTracker) CollectionMigrationService {
+	ctx, stop := context.WithCancel(context.Background())
+	return &collectionMigrationService{
+		repo:    r,
+		docRepo: dr,
+		tracker: tracker,
+		cancels: make(map[uuid.UUID]*migrationWorker),
+		ctx:     ctx,
+		stop:    stop,
+	}
+}
+
+func (s *collectionMigrationService) Start(ctx context.Context, pID, collName string, req CollectionMigrationRequest) (*models.CollectionMigration, error) {
+	if err := validateMigrationRequest(req); err != nil {
+		return nil, err
+	}
+
+	coll, err := s.docRepo.GetCollectionByName(ctx, pID, collName)
+	if err != nil {
+		return nil, errors.New("collection not found")
+	}
+
+	batchSize := req.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultMigrationBatchSize
+	}
+	if batchSize > maxMigrationBatchSize {
+		batchSize = maxMigrationBatchSize
+	}
+
+	filters, _ := json.Marshal(req.Filters)
+	if req.Filters == nil {
+		filters = []byte("[]")
+	}
+
+	m := &models.CollectionMigration{
+		ProjectID:    pID,
+		CollectionID: coll.ID,
+		Operation:    req.Operation,
+		Field:        req.Field,
+		NewField:     req.NewField,
+		CastTo:       req.CastTo,
+		Filters:      datatypes.JSON(filters),
+		DryRun:       req.DryRun,
+		BatchSize:    batchSize,
+		Status:       models.MigrationPending,
+		Preview:      datatypes.JSON([]byte("[]")),
+		LeaseOwner:   uuid.NewString(),
+	}
+	if req.Value != nil {
+		v, _ := json.Marshal(req.Value)
+		m.Value = datatypes.JSON(v)
+	}
+
+	total, err := s.docRepo.CountMatching(ctx, pID, coll.ID, req.Filters, nil)
+	if err != nil {
+		return nil, err
+	}
+	m.Total = total
+
+	if err := s.repo.Create(ctx, m); err != nil {
+		return nil, err
+	}
+
+	// Nuqul ayaa la celiyaa: worker-ku m ayuu wax ka beddelaa
+	out := *m
+	s.launch(m)
+	return &out, nil
+}
+
+func (s *collectionMigrationService) Get(ctx context.Context, pID, migrationID string) (*models.CollectionMigration, error) {
+	id, err := uuid.Parse(migrationID)
+	if err != nil {
+		return nil, errors.New("invalid migration id")
+	}
+	return s.repo.GetByID(ctx, pID, id)
+}
+
+func (s *collectionMigrationService) History(ctx context.Context, pID, collName string) ([]models.CollectionMigration, error) {
+	coll, err := s.docRepo.GetCollectionByName(ctx, pID, collName)
+	if err != nil {
+		return nil, errors.New("collection not found")
+	}
+	return s.repo.ListByCollection(ctx, pID, coll.ID)
+}
+
+func (s *collectionMigrationService) Cancel(ctx context.Context, pID, migrationID string) error {
+	m, err := s.Get(ctx, pID, migrationID)
+	if err != nil {
+		return err
+	}
+	if m.Status != models.MigrationRunning && m.Status != models.MigrationPending {
+		return fmt.Errorf("migration is already %s", m.Status)
+	}
+
+	// 🔒 DB-ga ayaa marka hore la beddelaa: worker-ka replica kale ku jira checkpoint/heartbeat-kiisa xiga
+	// wuxuu helaa 0 rows (status-ku ma aha "running") wuuna istaagaa, status-kana dib uma qori karo
+	if err := s.repo.Cancel(ctx, pID, m.ID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("migration is no longer running")
+		}
+		return err
+	}
+
+	s.mu.Lock()
+	worker, live := s.cancels[m.ID]
+	s.mu.Unlock()
+	if live {
+		worker.cancel()
+	}
+	return nil
+}
+
+func (s *collectionMigrationService) Resume(ctx context.Context, pID, migrationID string) (*models.CollectionMigration, error) {
+	m, err := s.Get(ctx, pID, migrationID)
+	if err != nil {
+		return nil, err
+	}
+	if m.Status == models.MigrationCompleted {
+		return nil, errors.New("migration already completed")
+	}
+
+	// 🔒 Claim-ka DB-ga ayaa go'aamiya cidda worker-ka bilaabaysa (laba Resume oo isku mar ah / replicas kale)
+	claimed, err := s.repo.Claim(ctx, m.ID, []models.MigrationStatus{
+		models.MigrationFailed, models.MigrationCancelled,
+	}, time.Now().Add(-migrationLease), uuid.NewString())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return m, nil // Horay ayuu u socdaa
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	out := *claimed
+	s.launch(claimed)
+	return &out, nil
+}
+
+func (s *collectionMigrationService) ResumeInterrupted(ctx context.Context) {
+	s.resumeStale(ctx)
+	s.workers.Add(1)
+	go func() {
+		defer s.workers.Done()
+		ticker := time.NewTicker(migrationSweepInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.resumeStale(ctx)
+			}
+		}
+	}()
+}
+
+func (s *collectionMigrationService) Shutdown(ctx context.Context) error {
+	s.stop()
+	return waitGroupWithContext(ctx, &s.workers)
+}
+
+// resumeStale: Replica kasta wuu sameeyaa; Claim-ka ayaa hubiya in job kasta hal worker kaliya helo
+func (s *collectionMigrationService) resumeStale(ctx context.Context) {
+	staleBefore := time.Now().Add(-migrationLease)
+	jobs, err := s.repo.GetInterrupted(ctx, staleBefore)
+	if err != nil {
+		logger.Log.Errorf("❌ [Migrations] Failed to load interrupted jobs: %v", err)
+		return
+	}
+	for i := range jobs {
+		m, err := s.repo.Claim(ctx, jobs[i].ID, nil, staleBefore, uuid.NewString())
+		if err != nil {
+			continue // Replica kale ayaa qaatay
+		}
+		logger.Log.Infof("🔁 [Migrations] Resuming job %s from cursor %v", m.ID, m.Cursor)
+		s.launch(m)
+	}
+}
+
+// =========================================================================
+// 🚀 WORKER (Batch loop)
+// =========================================================================
+
+// migrationWorker: Worker-ka job-ka ee node-kan (pointer-ka ayaa lagu kala saaraa worker-kii hore iyo kan cusub)
+type migrationWorker struct {
+	cancel context.CancelFunc
+}
+
+func (s *collectionMigrationService) launch(m *models.CollectionMigration) {
+	ctx, cancel := context.WithCancel(s.ctx)
+	s.mu.Lock()
+	worker := &migrationWorker{cancel: cancel}
+	s.cancels[m.ID] = worker
+	s.mu.Unlock()
+
+	s.workers.Add(1)
+	go func() {
+		defer s.workers.Done()
+		defer func() {
+			s.mu.Lock()
+			// Resume ayaa laga yaabaa inuu worker cusub bilaabay ka dib finish-ka; kan kaliya tirtir
+			if s.cancels[m.ID] == worker {
+				delete(s.cancels, m.ID)
+			}
+			s.mu.Unlock()
+			cancel()
+		}()
+		go s.heartbeat(ctx, cancel, m.ID, m.LeaseOwner)
+		s.run(ctx, m)
+	}()
+}
+
+// heartbeat: Lease-ka cusboonaysii inta batch-ku socdo; haddii job-ka la joojiyo (replica kasta)
+// ama worker kale qaato, worker-kan waa la joojiyaa isla markiiba
+func (s *collectionMigrationService) heartbeat(ctx context.Context, cancel context.CancelFunc, id uuid.UUID, owner string) {
+	ticker := time.NewTicker(migrationHeartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := s.repo.Heartbeat(context.Background(), id, owner)
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				logger.Log.Infof("🛑 [Migrations] Job %s was cancelled or taken over; stopping worker", id)
+				cancel()
+				return
+			}
+			if err != nil {
+				logger.Log.Errorf("❌ [Migrations] Heartbeat for job %s failed: %v", id, err)
+			}
+		}
+	}
+}
+
+func (s *collectionMigrationService) run(ctx context.Context, m *models.CollectionMigration) {
+	var filters []repo.Filter
+	_ = json.Unmarshal(m.Filters, &filters)
+
+	var value interface{}
+	if len(m.Value) > 0 {
+		_ = json.Unmarshal(m.Value, &value)
+	}
+
+	var preview []map[string]interface{}
+	_ = json.Unmarshal(m.Preview, &preview)
+
+	now := time.Now()
+	if m.StartedAt == nil {
+		m.StartedAt = &now
+	}
+	m.Status = models.MigrationRunning
+	if err := s.checkpoint(m); err != nil {
+		return
+	}
+
+	for {
+		if ctx.Err() != nil {
+			s.interrupted(m)
+			return
+		}
+
+		docs, err := s.docRepo.ScanBatch(ctx, m.ProjectID, m.CollectionID, filters, m.Cursor, m.BatchSize)
+		if err != nil {
+			if ctx.Err() != nil {
+				s.interrupted(m)
+				return
+			}
+			s.finish(m, models.MigrationFailed, err)
+			return
+		}
+		if len(docs) == 0 {
+			s.finish(m, models.MigrationCompleted, nil)
+			return
+		}
+
+		var written int64
+		done := 0
+		for i := range docs {
+			// Cancel/lease lost: jooji dhexda batch-ka; cursor-ku waa document-kii ugu dambeeyay ee la dhameeyay
+			if ctx.Err() != nil {
+				break
+			}
+			done = i + 1
+			doc := &docs[i]
+			var data map[string]interface{}
+			if err := json.Unmarshal(doc.Data, &data); err != nil || data == nil {
+				data = map[string]interface{}{}
+			}
+
+			out, changed, err := applyMigration(m, value, data)
+			m.Processed++
+			if err != nil {
+				m.Failed++
+				continue
+			}
+			if !changed {
+				continue
+			}
+			m.Modified++
+
+			if m.DryRun {
+				if len(preview) < maxMigrationPreview {
+					preview = append(preview, map[string]interface{}{"id": doc.ID, "before": data, "after": out})
+				}
+				continue
+			}
+
+			b, _ := json.Marshal(out)
+			doc.Data = datatypes.JSON(b)
+			if err := s.docRepo.ReplaceData(context.Background(), doc); err != nil {
+				m.Failed++
+				m.Modified--
+				continue
+			}
+			written++
+		}
+
+		// 💾 Checkpoint: kaydi cursor-ka si job-ku uga sii socdo halkan haddii la joojiyo
+		if done > 0 {
+			lastID := docs[done-1].ID
+			m.Cursor = &lastID
+		}
+		if m.DryRun {
+			p, _ := json.Marshal(preview)
+			m.Preview = datatypes.JSON(p)
+		}
+		if err := s.checkpoint(m); err != nil {
+			if !errors.Is(err, errMigrationLeaseLost) {
+				s.finish(m, models.MigrationFailed, err)
+			}
+			return
+		}
+		if written > 0 {
+			s.tracker.TrackEvent(context.Background(), m.ProjectID, models.AnalyticsTypeDatabaseUsage, "doc_writes", float64(written))
+		}
+
+		if done < len(docs) {
+			s.interrupted(m)
+			return
+		}
+		if len(docs) < m.BatchSize {
+			s.finish(m, models.MigrationCompleted, nil)
+			return
+		}
+	}
+}
+
+// interrupted: ctx-ka waa la joojiyay. Shutdown → job-ku "running" ayuu ahaanayaa (checkpoint-kii ugu dambeeyay
+// ayaa laga sii wadaa marka lease-ku dhaco); haddii kale waa Cancel ama lease lumay
+func (s *collectionMigrationService) interrupted(m *models.CollectionMigration) {
+	if s.ctx.Err() != nil {
+		logger.Log.Infof("⏸️ [Migrations] Job %s paused for shutdown at cursor %v (processed=%d)", m.ID, m.Cursor, m.Processed)
+		return
+	}
+	s.finish(m, models.MigrationCancelled, nil)
+}
+
+// checkpoint: errMigrationLeaseLost haddii job-ka la joojiyay ama worker kale qaatay (0 rows)
+func (s *collectionMigrationService) checkpoint(m *models.CollectionMigration) error {
+	err := s.repo.Checkpoint(context.Background(), m)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		logger.Log.Infof("🛑 [Migrations] Job %s was cancelled or taken over; stopping worker", m.ID)
+		return errMigrationLeaseLost
+	}
+	return err
+}
+
+func (s *collectionMigrationService) finish(m *models.CollectionMigration, status models.MigrationStatus, err error) {
+	now := time.Now()
+	m.Status = status
+	m.FinishedAt = &now
+	if err != nil {
+		msg := err.Error()
+		m.ErrorMessage = &msg
+	}
+	// Cancel-ka DB-ga horay ayuu u qoray status-ka; 0 rows halkan waa la filayaa
+	if uErr := s.repo.Finish(context.Background(), m); uErr != nil {
+		if errors.Is(uErr, gorm.ErrRecordNotFound) {
+			logger.Log.Infof("🛑 [Migrations] Job %s stopped (processed=%d modified=%d failed=%d)", m.ID, m.Processed, m.Modified, m.Failed)
+			return
+		}
+		logger.Log.Errorf("❌ [Migrations] Failed to save job %s: %v", m.ID, uErr)
+	}
+	logger.Log.Infof("✅ [Migrations] Job %s %s (processed=%d modified=%d failed=%d)", m.ID, status, m.Processed, m.Modified, m.Failed)
+}
+
+// =========================================================================
+// 🛠️ TRANSFORMS
+// =========================================================================
+
+func validateMigrationRequest(req CollectionMigrationRequest) error {
+	switch req.Operation {
+	case models.MigrationRenameField:
+		if req.Field == "" || req.NewField == "" {
+			return errors.New("rename_field requires 'field' and 'new_field'")
+		}
+		if req.Field == req.NewField {
+			return errors.New("'field' and 'new_field' must differ")
+		}
+	case models.MigrationSetDefault:
+		if req.Field == "" || req.Value == nil {
+			return errors.New("set_default requires 'field' and 'value'")
+		}
+	case models.MigrationCastType:
+		if req.Field == "" {
+			return errors.New("cast_type requires 'field'")
+		}
+		if req.CastTo != "string" && req.CastTo != "number" && req.CastTo != "boolean" {
+			return errors.New("cast_to must be one of: string, number, boolean")
+		}
+	case models.MigrationDropField:
+		if req.Field == "" {
+			return errors.New("drop_field requires 'field'")
+		}
+	case models.MigrationMergePatch:
+		if _, ok := req.Value.(map[string]interface{}); !ok {
+			return errors.New("merge_patch requires an object 'value'")
+		}
+	default:
+		return fmt.Errorf("unsupported operation: %s", req.Operation)
+	}
+	return nil
+}
+
+// applyMigration: Waxay soo celisaa xogta cusub iyo in wax is-beddeleen
+func applyMigration(m *models.CollectionMigration, value interface{}, data map[string]interface{}) (map[string]interface{}, bool, error) {
+	out := make(map[string]interface{}, len(data)+1)
+	for k, v := range data {
+		out[k] = v
+	}
+
+	switch m.Operation {
+	case models.MigrationRenameField:
+		v, ok := out[m.Field]
+		if !ok {
+			return data, false, nil
+		}
+		delete(out, m.Field)
+		out[m.NewField] = v
+		return out, true, nil
+
+	case models.MigrationSetDefault:
+		if v, ok := out[m.Field]; ok && v != nil {
+			return data, false, nil
+		}
+		out[m.Field] = value
+		return out, true, nil
+
+	case models.MigrationDropField:
+		if _, ok := out[m.Field]; !ok {
+			return data, false, nil
+		}
+		delete(out, m.Field)
+		return out, true, nil
+
+	case models.MigrationCastType:
+		v, ok := out[m.Field]
+		if !ok || v == nil {
+			return data, false, nil
+		}
+		cast, err := castValue(v, m.CastTo)
+		if err != nil {
+			return data, false, err
+		}
+		if reflect.DeepEqual(cast, v) {
+			return data, false, nil
+		}
+		out[m.Field] = cast
+		return out, true, nil
+
+	case models.MigrationMergePatch:
+		patch := renderPatchTemplate(value, data)
+		merged, _ := utils.MergePatch(out, patch).(map[string]interface{})
+		if reflect.DeepEqual(merged, data) {
+			return data, false, nil
+		}
+		return merged, true, nil
+	}
+	return data, false, fmt.Errorf("unsupported operation: %s", m.Operation)
+}
+
+func castValue(v interface{}, to string) (interface{}, error) {
+	switch to {
+	case "string":
+		switch t := v.(type) {
+		case string:
+			return t, nil
+		case float64:
+			return strconv.FormatFloat(t, 'f', -1, 64), nil
+		case bool:
+			return strconv.FormatBool(t), nil
+		}
+	case "number":
+		switch t := v.(type) {
+		case float64:
+			return t, nil
+		case string:
+			f, err := strconv.ParseFloat(strings.TrimSpace(t), 64)
+			if err != nil {
+				return nil, fmt.Errorf("cannot cast %q to number", t)
+			}
+			return f, nil
+		case bool:
+			if t {
+				return float64(1), nil
+			}
+			return float64(0), nil
+		}
+	case "boolean":
+		switch t := v.(type) {
+		case bool:
+			return t, nil
+		case float64:
+			return t != 0, nil
+		case string:
+			b, err := strconv.ParseBool(strings.TrimSpace(t))
+			if err != nil {
+				return nil, fmt.Errorf("cannot cast %q to boolean", t)
+			}
+			return b, nil
+		}
+	}
+	return nil, fmt.Errorf("cannot cast %T to %s", v, to)
+}
+
+// renderPatchTemplate: "{{field}}" waxay noqonaysaa qiimaha field-kaas ee document-ka
+func renderPatchTemplate(tpl interface{}, data map[string]interface{}) interface{} {
+	switch t := tpl.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(t))
+		for k, v := range t {
+			out[k] = renderPatchTemplate(v, data)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		for i, v := range t {
+			out[i] = renderPatchTemplate(v, data)
+		}
+		return out
+	case string:
+		if strings.HasPrefix(t, "{{") && strings.HasSuffix(t, "}}") && strings.Count(t, "{{") == 1 {
+			return data[strings.TrimSpace(t[2:len(t)-2])]
+		}
+		for k, v := range data {
+			t = strings.ReplaceAll(t, "{{"+k+"}}", fmt.Sprint(v))
+		}
+		return t
+	}
+	return tpl
+}
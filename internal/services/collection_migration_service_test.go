@@ -0,0 +1,197 @@
+package services
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"superaib/internal/core/logger"
+	"superaib/internal/models"
+	"superaib/internal/storage/repo"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+func TestMain(m *testing.M) {
+	logger.Init()
+	os.Exit(m.Run())
+}
+
+// memMigrationRepo: Xaaladda DB-ga (lease_owner + status) si replicas badan loo simulate gareeyo
+type memMigrationRepo struct {
+	repo.CollectionMigrationRepository
+	mu   sync.Mutex
+	rows map[uuid.UUID]models.CollectionMigration
+}
+
+func (r *memMigrationRepo) Create(ctx context.Context, m *models.CollectionMigration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	m.ID = uuid.New()
+	r.rows[m.ID] = *m
+	return nil
+}
+
+func (r *memMigrationRepo) get(id uuid.UUID) models.CollectionMigration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rows[id]
+}
+
+func (r *memMigrationRepo) leased(m *models.CollectionMigration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	row := r.rows[m.ID]
+	if row.LeaseOwner != m.LeaseOwner || (row.Status != models.MigrationPending && row.Status != models.MigrationRunning) {
+		return gorm.ErrRecordNotFound
+	}
+	r.rows[m.ID] = *m
+	return nil
+}
+
+func (r *memMigrationRepo) Checkpoint(ctx context.Context, m *models.CollectionMigration) error {
+	return r.leased(m)
+}
+
+func (r *memMigrationRepo) Finish(ctx context.Context, m *models.CollectionMigration) error {
+	return r.leased(m)
+}
+
+func (r *memMigrationRepo) Cancel(ctx context.Context, projectID string, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	row := r.rows[id]
+	if row.Status != models.MigrationPending && row.Status != models.MigrationRunning {
+		return gorm.ErrRecordNotFound
+	}
+	row.Status = models.MigrationCancelled
+	r.rows[id] = row
+	return nil
+}
+
+type gatedDocRepo struct {
+	repo.DocumentRepository
+	docs  []models.Document
+	gate  chan struct{} // Batch-ka labaad wuu sugayaa ilaa la furo
+	calls int
+}
+
+func (d *gatedDocRepo) GetCollectionByName(ctx context.Context, projectID, name string) (*models.Collection, error) {
+	return &models.Collection{ID: uuid.New()}, nil
+}
+
+func (d *gatedDocRepo) CountMatching(ctx context.Context, pID string, cID uuid.UUID, filters []repo.Filter, afterID *uuid.UUID) (int64, error) {
+	return int64(len(d.docs)), nil
+}
+
+func (d *gatedDocRepo) ScanBatch(ctx context.Context, pID string, cID uuid.UUID, filters []repo.Filter, afterID *uuid.UUID, limit int) ([]models.Document, error) {
+	d.calls++
+	if d.calls == 2 {
+		<-d.gate
+	}
+	start := 0
+	if afterID != nil {
+		for i, doc := range d.docs {
+			if doc.ID == *afterID {
+				start = i + 1
+			}
+		}
+	}
+	end := min(start+limit, len(d.docs))
+	return d.docs[start:end], nil
+}
+
+// Cancel replica kale ka yimid: worker-ku checkpoint-kiisa xiga wuu istaagaa, status-kana dib uma qoro "running"
+func TestMigrationStopsWhenCancelledElsewhere(t *testing.T) {
+	migrations := &memMigrationRepo{rows: map[uuid.UUID]models.CollectionMigration{}}
+	docs := &gatedDocRepo{gate: make(chan struct{})}
+	for i := 0; i < 3; i++ {
+		docs.docs = append(docs.docs, models.Document{ID: uuid.New(), Data: datatypes.JSON(`{"a":1}`)})
+	}
+	svc := NewCollectionMigrationService(migrations, docs, nil).(*collectionMigrationService)
+
+	m, err := svc.Start(context.Background(), "p1", "posts", CollectionMigrationRequest{
+		Operation: models.MigrationDropField, Field: "a", DryRun: true, BatchSize: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Batch-ka koowaad waa la dhameeyay; worker-ku wuxuu sugayaa batch-ka labaad
+	deadline := time.Now().Add(2 * time.Second)
+	for migrations.get(m.ID).Processed < 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("first batch was not checkpointed")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Replica kale: DB-ga kaliya ayuu beddelaa (worker-kan cancel func-kiisa ma haysto)
+	if err := migrations.Cancel(context.Background(), "p1", m.ID); err != nil {
+		t.Fatal(err)
+	}
+	close(docs.gate)
+
+	for {
+		svc.mu.Lock()
+		_, live := svc.cancels[m.ID]
+		svc.mu.Unlock()
+		if !live {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("worker kept running after cancel")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	got := migrations.get(m.ID)
+	if got.Status != models.MigrationCancelled || got.Processed != 1 {
+		t.Fatalf("status = %s, processed = %d; want cancelled after 1", got.Status, got.Processed)
+	}
+}
+
+// Shutdown: worker-ku wuu istaagaa laakiin job-ku "running" ayuu ahaanayaa (cancelled ma aha) si loo sii wado
+func TestMigrationShutdownLeavesJobResumable(t *testing.T) {
+	migrations := &memMigrationRepo{rows: map[uuid.UUID]models.CollectionMigration{}}
+	docs := &gatedDocRepo{gate: make(chan struct{})}
+	for i := 0; i < 3; i++ {
+		docs.docs = append(docs.docs, models.Document{ID: uuid.New(), Data: datatypes.JSON(`{"a":1}`)})
+	}
+	svc := NewCollectionMigrationService(migrations, docs, nil)
+
+	m, err := svc.Start(context.Background(), "p1", "posts", CollectionMigrationRequest{
+		Operation: models.MigrationDropField, Field: "a", DryRun: true, BatchSize: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for migrations.get(m.ID).Processed < 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("first batch was not checkpointed")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	stopped := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		stopped <- svc.Shutdown(ctx)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	close(docs.gate)
+	if err := <-stopped; err != nil {
+		t.Fatalf("Shutdown = %v", err)
+	}
+
+	got := migrations.get(m.ID)
+	if got.Status != models.MigrationRunning || got.Processed != 1 || got.Cursor == nil || *got.Cursor != docs.docs[0].ID {
+		t.Fatalf("status = %s, processed = %d, cursor = %v; want running after 1", got.Status, got.Processed, got.Cursor)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"superaib/internal/models"
 	"superaib/internal/storage/repo"
+	"sync"
 	"time"
 
 	firebase "firebase.google.com/go/v4"
@@ -16,6 +17,8 @@ import (
 type Broadcaster interface {
 	BroadcastToProject(projectID string, eventType string, payload interface{})
 	BroadcastToChannel(pID, channel, event string, payload interface{}, senderID string)
+	// SendToUsers: Direct message (connections-ka user-yadan kaliya, channel la'aan)
+	SendToUsers(projectID string, userIDs []string, eventType string, payload interface{})
 }
 
 type NotificationService interface {
@@ -29,7 +32,9 @@ type NotificationService interface {
 	DeleteNotification(ctx context.Context, projectID string, noteID string) error
 	GetNotification(ctx context.Context, projectID string, noteID string) (*models.Notification, error)
 	GetHistory(ctx context.Context, projectID string) ([]models.Notification, error)
-	StartScheduler(ctx context.Context) // 🚀 Mishiinka kicinaya
+	StartScheduler(ctx context.Context) // 🚀 Mishiinka kicinaya (ctx-ka oo la joojiyo ayuu istaagaa)
+	// Shutdown: Sug inta scheduler-ka iyo FCM sends-ka socda ay dhammaanayaan (ama ctx-ku dhaco)
+	Shutdown(ctx context.Context) error
 
 	// Push Configuration
 	UpdatePushConfig(ctx context.Context, projectID string, jsonStr string) error
@@ -42,6 +47,8 @@ type notificationService struct {
 	tracker       *AnalyticsTracker
 	usageService  ProjectUsageService
 	rtBroadcaster Broadcaster
+
+	workers sync.WaitGroup // Scheduler-ka + FCM goroutines (graceful shutdown)
 }
 
 func NewNotificationService(r repo.NotificationRepository, cr repo.PushConfigRepository, t *AnalyticsTracker, u ProjectUsageService, b Broadcaster) NotificationService {
@@ -88,24 +95,45 @@ func (s *notificationService) SendBroadcast(ctx context.Context, note *models.No
 	payload := map[string]interface{}{
 		"id": note.ID, "title": note.Title, "body": note.Body, "timestamp": time.Now(),
 	}
-	s.rtBroadcaster.BroadcastToProject(note.ProjectID, "PUSH_NOTIFICATION", payload)
-	go s.sendToFCM(note)
+	s.deliverLive(note, payload)
+	s.goFCM(note)
 
 	return nil
 }
 
+// deliverLive: Notification hal user loo diray → connections-kiisa kaliya; kuwa kale → project-ka oo dhan
+func (s *notificationService) deliverLive(note *models.Notification, payload map[string]interface{}) {
+	if note.UserID != nil && *note.UserID != "" {
+		s.rtBroadcaster.SendToUsers(note.ProjectID, []string{*note.UserID}, "PUSH_NOTIFICATION", payload)
+		return
+	}
+	s.rtBroadcaster.BroadcastToProject(note.ProjectID, "PUSH_NOTIFICATION", payload)
+}
+
 // 🚀 XALKA 2: MISHIINKA DHAGAYSTIGA WAQTIGA (The Scheduler)
 func (s *notificationService) StartScheduler(ctx context.Context) {
 	// Wuxuu isbaaraa 30-kii ilbiriqsiba fariimaha dhimman
 	ticker := time.NewTicker(30 * time.Second)
 
+	s.workers.Add(1)
 	go func() {
+		defer s.workers.Done()
+		defer ticker.Stop()
 		fmt.Println("⏰ [SYSTEM] Notification Scheduler is running (UTC Mode)...")
-		for range ticker.C {
+		// Pass-ka bilaabmay ha dhammaado xitaa haddii shutdown yimaado (status-ka 'sent' waa in la qoraa)
+		work := context.WithoutCancel(ctx)
+		for {
+			select {
+			case <-ctx.Done():
+				fmt.Println("⏰ [SYSTEM] Notification Scheduler stopped.")
+				return
+			case <-ticker.C:
+			}
+
 			// 🚀 MUHIIM: Had iyo jeer isticmaal UTC isbarbardhigga
 			now := time.Now().UTC()
 
-			pendingNotes, err := s.repo.GetPendingNotifications(ctx, now)
+			pendingNotes, err := s.repo.GetPendingNotifications(work, now)
 			if err != nil {
 				continue
 			}
@@ -125,15 +153,15 @@ func (s *notificationService) StartScheduler(ctx context.Context) {
 					"image_url": note.ImageURL,
 					"timestamp": now,
 				}
-				s.rtBroadcaster.BroadcastToProject(note.ProjectID, "PUSH_NOTIFICATION", payload)
+				s.deliverLive(&note, payload)
 
 				// 2. U dir Firebase (FCM) - haddii uu u jiro function-kaas
-				go s.sendToFCM(&note)
+				s.goFCM(&note)
 
 				// 3. 🚀 CUSBOONAYSIIN STATUS-KA (Tani waa tallaabada ugu muhiimsan)
 				// Waxaan u beddelaynaa 'sent' si uusan mishiinku mar labaad u soo qaadin
 				note.Status = "sent"
-				if err := s.repo.Update(ctx, &note); err != nil {
+				if err := s.repo.Update(work, &note); err != nil {
 					fmt.Printf("❌ Error updating status for note %s: %v\n", note.ID, err)
 				} else {
 					fmt.Printf("✅ [SUCCESS] Note '%s' status updated to SENT in DB.\n", note.Title)
@@ -214,3 +242,16 @@ func (s *notificationService) sendToFCM(note *models.Notification) {
 		_, _ = client.SendEachForMulticast(ctx, msg)
 	}
 }
+
+// goFCM: FCM send-ka waxaa la raacaa WaitGroup-ka si shutdown-ku u sugo
+func (s *notificationService) goFCM(note *models.Notification) {
+	s.workers.Add(1)
+	go func() {
+		defer s.workers.Done()
+		s.sendToFCM(note)
+	}()
+}
+
+func (s *notificationService) Shutdown(ctx context.Context) error {
+	return waitGroupWithContext(ctx, &s.workers)
+}
@@ -133,7 +133,7 @@ func (s *projectFeatureService) ToggleFeature(ctx context.Context, projectID str
 		feature.Enabled = enable
 		// Kaliya update-garee config-ga haddii xog cusub la soo diray
 		if config != nil && string(config) != "null" && string(config) != "{}" {
-			feature.Config = config
+			feature.Config = models.MergeFeatureSecrets(feature.Config, config)
 		}
 	}
 
@@ -149,6 +149,10 @@ func (s *projectFeatureService) ToggleFeature(ctx context.Context, projectID str
 
 // 7. UpdateFeature - Isbedel guud
 func (s *projectFeatureService) UpdateFeature(ctx context.Context, feature *models.ProjectFeature) error {
+	// Responses-ku secrets ma sitaan: dashboard-ka config-ka uu dib u soo diro secrets-kii hore ha tirtirin
+	if existing, err := s.repo.GetFeatureByID(ctx, feature.ID); err == nil {
+		feature.Config = models.MergeFeatureSecrets(existing.Config, feature.Config)
+	}
 	feature.UpdatedAt = time.Now()
 	return s.repo.UpdateFeature(ctx, feature)
 }
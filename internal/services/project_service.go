@@ -108,6 +108,11 @@ func (s *projectService) CreateProject(ctx context.Context, ownerID string, name
 		LimitStorageMB:     freePlan.MaxStorageMB,
 		LimitDocuments:     freePlan.MaxDocuments,
 		LimitNotifications: freePlan.MaxNotifications, // 👈 Halkan ayay xogtii ka soo gashay Plan-ka
+
+		LimitRealtimeChannels: freePlan.MaxRealtimeChannels,
+		LimitRealtimeEvents:   freePlan.MaxRealtimeEvents,
+
+		LimitRealtimeChannelClients: freePlan.MaxRealtimeChannelClients,
 	}
 	if err := tx.Create(usage).Error; err != nil {
 		tx.Rollback()
@@ -4,6 +4,7 @@ import (
 	"context"
 	"superaib/internal/models"
 	"superaib/internal/storage/repo"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -12,6 +13,11 @@ type ProjectUsageService interface {
 	GetUsage(ctx context.Context, projectUUID string) (*models.ProjectUsage, error)
 	UpdateUsage(ctx context.Context, projectUUID string, field string, value interface{}) error
 	CreateInitialUsageRecord(ctx context.Context, tx *gorm.DB, projectID string) error
+
+	// ConsumeQuota: Kordhi counter-ka haddii plan-ku oggol yahay; false = limit-ka waa la gaaray
+	ConsumeQuota(ctx context.Context, projectUUID string, field, limitField string, value int) (bool, error)
+	// ConsumeRealtimeEvent: Sida ConsumeQuota laakiin counter-ku bil kasta wuu is-bedelaa
+	ConsumeRealtimeEvent(ctx context.Context, projectUUID string) (bool, error)
 }
 
 type projectUsageService struct {
@@ -37,3 +43,20 @@ func (s *projectUsageService) CreateInitialUsageRecord(ctx context.Context, tx *
 	}
 	return s.repo.Create(ctx, tx, usage)
 }
+
+func (s *projectUsageService) ConsumeQuota(ctx context.Context, projectUUID string, field, limitField string, value int) (bool, error) {
+	// Project aan usage record lahayn lama xannibo (sida middleware-ka)
+	if _, err := s.repo.GetByProjectID(ctx, projectUUID); err != nil {
+		return true, nil
+	}
+	return s.repo.TryIncrement(ctx, projectUUID, field, limitField, value)
+}
+
+func (s *projectUsageService) ConsumeRealtimeEvent(ctx context.Context, projectUUID string) (bool, error) {
+	now := time.Now().UTC()
+	periodStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if err := s.repo.ResetRealtimePeriod(ctx, projectUUID, periodStart); err != nil {
+		return false, err
+	}
+	return s.ConsumeQuota(ctx, projectUUID, "realtime_events_count", "limit_realtime_events", 1)
+}
@@ -0,0 +1,180 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"superaib/internal/models"
+	"superaib/pkg/httpclient"
+	"time"
+)
+
+// =========================================================================
+// 🔑 10. CHANNEL AUTHORIZATION CALLBACKS (private channels, Pusher-style)
+// =========================================================================
+// Config-ka waxaa laga akhriyaa realtime ProjectFeature.Config:
+//
+//	{"auth_secret": "...", "auth_endpoint": "https://api.example.com/realtime/auth", "auth_timeout_ms": 3000}
+//
+// Private channel-ka (allowed_users-ka kuma jiro) SUBSCRIBE-kiisa waxaa lagu oggolaadaa:
+//  1. Signed token: backend-ka developer-ka ayaa bixiya
+//     auth = hex(HMAC-SHA256(auth_secret, connection_id + ":" + channel [+ ":" + channel_data]))
+//     channel_data (JSON string) waa xogta presence-ka ee la saxiixay.
+//  2. Auth endpoint (https kaliya, address public ah, redirects lama raaco): server-ku wuxuu POST u diraa {"project_id","connection_id","channel","user_id"}
+//     (header X-Superaib-Signature: sha256=<hmac body> haddii auth_secret jiro).
+//     2xx = oggol (body ikhtiyaari: {"channel_data": {...}}), wax kale = diid.
+
+var ErrRealtimeAuthUnavailable = errors.New("channel authorization endpoint unavailable")
+
+const (
+	defaultAuthTimeout  = 3 * time.Second
+	maxAuthTimeout      = 10 * time.Second
+	maxAuthResponseBody = 64 * 1024
+)
+
+// ChannelAuthRequest: Xogta SUBSCRIBE-ka ee loo baahan yahay callback-ga
+type ChannelAuthRequest struct {
+	ConnectionID string
+	UserID       string
+	Token        string // "auth" ee frame-ka (signed token)
+	ChannelData  string // JSON string oo token-ka lagu saxiixay (presence)
+}
+
+type channelAuthConfig struct {
+	Secret        string `json:"auth_secret"`
+	Endpoint      string `json:"auth_endpoint"`
+	TimeoutMillis int    `json:"auth_timeout_ms"`
+}
+
+// SignChannelAuth: Token-ka backend-ka developer-ka uu bixinayo (SDK-yada server-ka way la wadaagaan)
+func SignChannelAuth(secret, connectionID, channel, channelData string) string {
+	msg := connectionID + ":" + channel
+	if channelData != "" {
+		msg += ":" + channelData
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(msg))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// AuthorizeSubscription: AuthorizeChannel + callbacks-ka private channels.
+// Presence-ka la soo celiyo (channel_data) wuxuu ka horreeyaa kan client-ku soo diray.
+func (s *realtimeService) AuthorizeSubscription(ctx context.Context, channel *models.RealtimeChannel, req ChannelAuthRequest) (map[string]interface{}, error) {
+	err := s.AuthorizeChannel(ctx, channel, req.UserID, ChannelActionSubscribe)
+	if err == nil {
+		return nil, nil
+	}
+	private := channel.IsPrivate || channel.SubscriptionType == models.SubscriptionPrivate
+	if !private || (err != ErrRealtimeForbidden && err != ErrRealtimeAuthRequired) {
+		return nil, err
+	}
+
+	cfg, ok := s.channelAuthConfig(ctx, channel.ProjectID)
+	if !ok {
+		return nil, err
+	}
+
+	if req.Token != "" {
+		if cfg.Secret == "" {
+			return nil, ErrRealtimeForbidden
+		}
+		expected := SignChannelAuth(cfg.Secret, req.ConnectionID, channel.Name, req.ChannelData)
+		if !hmac.Equal([]byte(expected), []byte(req.Token)) {
+			return nil, ErrRealtimeForbidden
+		}
+		return decodeChannelData([]byte(req.ChannelData))
+	}
+
+	if cfg.Endpoint == "" {
+		return nil, err
+	}
+	return s.callAuthEndpoint(ctx, cfg, channel, req)
+}
+
+func (s *realtimeService) channelAuthConfig(ctx context.Context, projectID string) (*channelAuthConfig, bool) {
+	feature, err := s.featureRepo.GetFeatureByProjectIDAndType(ctx, projectID, models.FeatureTypeRealtime)
+	if err != nil {
+		return nil, false
+	}
+	var cfg channelAuthConfig
+	if err := json.Unmarshal(feature.Config, &cfg); err != nil {
+		return nil, false
+	}
+	return &cfg, cfg.Secret != "" || cfg.Endpoint != ""
+}
+
+func (s *realtimeService) callAuthEndpoint(ctx context.Context, cfg *channelAuthConfig, channel *models.RealtimeChannel, req ChannelAuthRequest) (map[string]interface{}, error) {
+	if err := httpclient.ValidateURL(cfg.Endpoint); err != nil {
+		fmt.Printf("⚠️ [Realtime] Auth endpoint of project %s rejected: %v\n", channel.ProjectID, err)
+		return nil, ErrRealtimeAuthUnavailable
+	}
+	timeout := defaultAuthTimeout
+	if cfg.TimeoutMillis > 0 {
+		timeout = min(time.Duration(cfg.TimeoutMillis)*time.Millisecond, maxAuthTimeout)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	body, _ := json.Marshal(map[string]string{
+		"project_id":    channel.ProjectID,
+		"connection_id": req.ConnectionID,
+		"channel":       channel.Name,
+		"user_id":       req.UserID,
+	})
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, ErrRealtimeAuthUnavailable
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if cfg.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(cfg.Secret))
+		mac.Write(body)
+		httpReq.Header.Set("X-Superaib-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := s.authClient.Do(httpReq)
+	if err != nil {
+		fmt.Printf("⚠️ [Realtime] Auth endpoint failed for %s: %v\n", channel.Name, err)
+		return nil, ErrRealtimeAuthUnavailable
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, ErrRealtimeForbidden
+	}
+	var grant struct {
+		ChannelData json.RawMessage `json:"channel_data"`
+	}
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, maxAuthResponseBody))
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return nil, nil
+	}
+	if err := json.Unmarshal(raw, &grant); err != nil {
+		return nil, nil
+	}
+
+	// channel_data wuxuu noqon karaa object ama JSON string (sida Pusher)
+	var str string
+	if json.Unmarshal(grant.ChannelData, &str) == nil {
+		return decodeChannelData([]byte(str))
+	}
+	return decodeChannelData(grant.ChannelData)
+}
+
+func decodeChannelData(data []byte) (map[string]interface{}, error) {
+	if len(bytes.TrimSpace(data)) == 0 || string(data) == "null" {
+		return nil, nil
+	}
+	var presence map[string]interface{}
+	if err := json.Unmarshal(data, &presence); err != nil {
+		return nil, errors.New("channel_data must be a JSON object")
+	}
+	return presence, nil
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"superaib/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// =========================================================================
+// 🛡️ 9. MODERATION (ban / mute; kick-ga waa live kaliya, handler-ka ayaa fuliya)
+// =========================================================================
+
+func (s *realtimeService) Moderate(ctx context.Context, m *models.RealtimeModeration, duration time.Duration) error {
+	if m.Action != models.ModerationBan && m.Action != models.ModerationMute {
+		return errors.New("action must be 'ban' or 'mute'")
+	}
+	if _, err := uuid.Parse(m.UserID); err != nil {
+		return errors.New("user_id must be a valid auth user id")
+	}
+	if duration < 0 {
+		return errors.New("duration cannot be negative")
+	}
+
+	// Channel-ku waa inuu ka tirsan yahay project-ka; magaca waa la kaydiyaa (live enforcement)
+	if m.ChannelID != nil {
+		channel, err := s.channelRepo.GetByID(ctx, *m.ChannelID)
+		if err != nil || channel.ProjectID != m.ProjectID {
+			return errors.New("channel not found")
+		}
+		m.ChannelName = channel.Name
+	}
+
+	if duration > 0 {
+		expires := time.Now().Add(duration)
+		m.ExpiresAt = &expires
+	}
+	m.Reason = strings.TrimSpace(m.Reason)
+	return s.modRepo.Create(ctx, m)
+}
+
+func (s *realtimeService) LiftModeration(ctx context.Context, projectID, moderationID string) (*models.RealtimeModeration, error) {
+	id, err := uuid.Parse(moderationID)
+	if err != nil {
+		return nil, err
+	}
+	return s.modRepo.Delete(ctx, projectID, id)
+}
+
+func (s *realtimeService) ListModerations(ctx context.Context, projectID, channelID string) ([]models.RealtimeModeration, error) {
+	var filter *uuid.UUID
+	if channelID != "" {
+		id, err := uuid.Parse(channelID)
+		if err != nil {
+			return nil, err
+		}
+		filter = &id
+	}
+	return s.modRepo.ListActive(ctx, projectID, filter, time.Now())
+}
+
+func (s *realtimeService) CheckModeration(ctx context.Context, projectID string, channel *models.RealtimeChannel, userID string, action ChannelAction) error {
+	if userID == "" {
+		return nil
+	}
+	var channelID *uuid.UUID
+	if channel != nil {
+		channelID = &channel.ID
+	}
+
+	rows, err := s.modRepo.ActiveForUser(ctx, projectID, userID, channelID, time.Now())
+	if err != nil {
+		return err
+	}
+	muted := false
+	for _, m := range rows {
+		switch m.Action {
+		case models.ModerationBan:
+			return ErrRealtimeBanned
+		case models.ModerationMute:
+			muted = true
+		}
+	}
+	if muted && action == ChannelActionBroadcast {
+		return ErrRealtimeMuted
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"superaib/internal/models"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+// RetentionOptions: Sida pruner-ku u shaqeeyo (config-ka ayaa laga buuxiyaa)
+type RetentionOptions struct {
+	Interval  time.Duration // Inta u dhaxaysa wareeg kasta (default 10 daqiiqo)
+	IdleAfter time.Duration // Channel aan fariin helin muddadan waa la archive gareeyaa (0 = dami; ExportDir waa khasab)
+	ExportDir string        // Taariikhda waxaa loo qoraa NDJSON ka hor tirtirka
+}
+
+// =========================================================================
+// 🗄️ 7. RETENTION & ARCHIVAL
+// =========================================================================
+
+func (s *realtimeService) StartRetentionPruner(ctx context.Context, opts RetentionOptions) {
+	if opts.Interval <= 0 {
+		opts.Interval = 10 * time.Minute
+	}
+	if opts.IdleAfter > 0 && opts.ExportDir == "" {
+		// Archive otomaatig ah oo export la'aan ah wuxuu tirtiri lahaa taariikhda persistent-ka ah si aamus ah
+		fmt.Println("⚠️ [Retention] Idle archival disabled: REALTIME_ARCHIVE_EXPORT_DIR is not set")
+		opts.IdleAfter = 0
+	}
+	s.retention = opts
+
+	ticker := time.NewTicker(opts.Interval)
+	go func() {
+		defer ticker.Stop()
+		fmt.Println("🗄️ [SYSTEM] Realtime retention pruner is running...")
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			if removed, err := s.PruneEvents(ctx); err != nil {
+				fmt.Printf("❌ [Retention] Prune failed: %v\n", err)
+			} else if removed > 0 {
+				fmt.Printf("🧹 [Retention] Pruned %d realtime events\n", removed)
+			}
+
+			if opts.IdleAfter > 0 {
+				s.archiveIdle(ctx, time.Now().Add(-opts.IdleAfter))
+			}
+		}
+	}()
+}
+
+// PruneEvents: Ku dabaq retention policy-ga channel kasta
+//   - ephemeral: wax taariikh ah lama hayo (kuwii hore ee la keydiyay waa la tirtiraa)
+//   - retention_max_age: events ka da' weyn ilbiriqsiyadan
+//   - retention_max_events: kaliya N-ta ugu dambeeya ayaa la hayaa
+func (s *realtimeService) PruneEvents(ctx context.Context) (int64, error) {
+	channels, err := s.channelRepo.ListWithRetention(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for _, ch := range channels {
+		if ch.RetentionPolicy == models.RetentionEphemeral {
+			count, err := s.eventRepo.CountByChannel(ctx, ch.ID)
+			if err == nil && count > 0 {
+				if err := s.eventRepo.DeleteByChannel(ctx, ch.ID); err == nil {
+					total += count
+				}
+			}
+			continue
+		}
+
+		if ch.RetentionMaxAge > 0 {
+			before := time.Now().Add(-time.Duration(ch.RetentionMaxAge) * time.Second)
+			n, err := s.eventRepo.DeleteOlderThan(ctx, ch.ID, before)
+			if err != nil {
+				fmt.Printf("❌ [Retention] Max age prune failed for %s: %v\n", ch.Name, err)
+			}
+			total += n
+		}
+		if ch.RetentionMaxEvents > 0 {
+			n, err := s.eventRepo.TrimToCount(ctx, ch.ID, ch.RetentionMaxEvents)
+			if err != nil {
+				fmt.Printf("❌ [Retention] Max count prune failed for %s: %v\n", ch.Name, err)
+			}
+			total += n
+		}
+	}
+	return total, nil
+}
+
+func (s *realtimeService) archiveIdle(ctx context.Context, before time.Time) {
+	channels, err := s.channelRepo.ListIdle(ctx, before)
+	if err != nil {
+		fmt.Printf("❌ [Retention] Idle lookup failed: %v\n", err)
+		return
+	}
+	for i := range channels {
+		if err := s.archive(ctx, &channels[i]); err != nil {
+			fmt.Printf("❌ [Retention] Archive failed for %s: %v\n", channels[i].Name, err)
+		}
+	}
+}
+
+// ArchiveChannel: Channel-ka waa inuu ka tirsan yahay project-ka codsiga (archive-ku taariikhda ayuu tirtiraa)
+func (s *realtimeService) ArchiveChannel(ctx context.Context, projectID, channelID string) (*models.RealtimeChannel, error) {
+	id, err := uuid.Parse(channelID)
+	if err != nil {
+		return nil, ErrRealtimeChannelNotFound
+	}
+	channel, err := s.channelRepo.GetByID(ctx, id)
+	if err != nil || channel.ProjectID != projectID {
+		return nil, ErrRealtimeChannelNotFound
+	}
+	if err := s.archive(ctx, channel); err != nil {
+		return nil, err
+	}
+	return s.channelRepo.GetByID(ctx, id)
+}
+
+// archive: (Ikhtiyaar) NDJSON export → tirtir taariikhda → calaamadee archived.
+// Haddii export-ku fashilmo wax lama tirtirayo.
+func (s *realtimeService) archive(ctx context.Context, channel *models.RealtimeChannel) error {
+	now := time.Now()
+	meta := map[string]interface{}{}
+	if len(channel.Metadata) > 0 {
+		_ = json.Unmarshal(channel.Metadata, &meta)
+	}
+
+	if s.retention.ExportDir != "" {
+		path, err := s.exportToFile(ctx, channel, now)
+		if err != nil {
+			return err
+		}
+		meta["archive_export"] = path
+	}
+
+	if err := s.eventRepo.DeleteByChannel(ctx, channel.ID); err != nil {
+		return err
+	}
+
+	metaJSON, _ := json.Marshal(meta)
+	return s.channelRepo.MarkArchived(ctx, channel.ID, now, datatypes.JSON(metaJSON))
+}
+
+// exportToFile: <dir>/<project_id>/<channel>-<timestamp>.ndjson
+func (s *realtimeService) exportToFile(ctx context.Context, channel *models.RealtimeChannel, at time.Time) (string, error) {
+	dir := filepath.Join(s.retention.ExportDir, channel.ProjectID)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	safe := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' || r == '.' {
+			return r
+		}
+		return '_'
+	}, channel.Name)
+	name := fmt.Sprintf("%s-%s.ndjson", safe, at.UTC().Format("20060102T150405Z"))
+	path := filepath.Join(dir, name)
+
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	if err := s.ExportEvents(ctx, channel.ID, f); err != nil {
+		f.Close()
+		os.Remove(path)
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+func (s *realtimeService) ExportEvents(ctx context.Context, channelID uuid.UUID, w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw) // Encode wuxuu ku daraa "\n" event kasta → NDJSON
+	err := s.eventRepo.StreamByChannel(ctx, channelID, func(e *models.RealtimeEvent) error {
+		return enc.Encode(e)
+	})
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
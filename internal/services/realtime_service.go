@@ -3,13 +3,48 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"strings"
 	"superaib/internal/models"
 	"superaib/internal/storage/repo"
+	"superaib/pkg/httpclient"
+	"superaib/pkg/utils"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrRealtimeAuthRequired = errors.New("authentication required for this channel")
+	ErrRealtimeForbidden    = errors.New("not allowed on this channel")
+	ErrRealtimeInvalidToken = errors.New("invalid or expired user token")
+
+	// 🛡️ Plan limits & capacity
+	ErrRealtimeChannelLimit = errors.New("realtime channel limit reached for this plan")
+	ErrRealtimeEventLimit   = errors.New("monthly realtime event limit reached for this plan")
+	ErrRealtimeChannelFull  = errors.New("channel has reached its maximum number of clients")
+
+	// 🌳 "*" waxaa loo hayaa wildcard subscriptions (orders:*), channel dhab ah kuma jiri karo
+	ErrRealtimeInvalidChannel = errors.New("channel names cannot contain '*'")
+
+	ErrRealtimeChannelNotFound = errors.New("channel not found")
+
+	// 🛡️ Moderation
+	ErrRealtimeBanned = errors.New("you are banned from this channel")
+	ErrRealtimeMuted  = errors.New("you are muted on this channel")
+)
+
+// ChannelAction: Waxa client-ku rabo inuu ku sameeyo channel-ka
+type ChannelAction string
+
+const (
+	ChannelActionSubscribe ChannelAction = "subscribe"
+	ChannelActionBroadcast ChannelAction = "broadcast"
 )
 
 type RealtimeService interface {
@@ -17,14 +52,16 @@ type RealtimeService interface {
 	CreateChannel(ctx context.Context, channel *models.RealtimeChannel) error
 	GetChannelsByProject(ctx context.Context, projectID string) ([]models.RealtimeChannel, error)
 	GetChannelByID(ctx context.Context, channelID string) (*models.RealtimeChannel, error)
-	UpdateChannel(ctx context.Context, channel *models.RealtimeChannel) error
-	DeleteChannel(ctx context.Context, channelID string) error
+	GetChannelByName(ctx context.Context, projectID, name string) (*models.RealtimeChannel, error)
+	UpdateChannel(ctx context.Context, projectID, channelID string, updates map[string]interface{}) (*models.RealtimeChannel, error)
+	DeleteChannel(ctx context.Context, projectID, channelID string) error
 
 	// --- EVENT MANAGEMENT ---
 	CreateEvent(ctx context.Context, projectID string, event *models.RealtimeEvent) error
 	GetEventsByChannel(ctx context.Context, channelID string) ([]models.RealtimeEvent, error)
-	UpdateEvent(ctx context.Context, eventID string, payload datatypes.JSON) error
-	DeleteEvent(ctx context.Context, eventID string) error
+	// UpdateEvent / DeleteEvent: Event-ka (kadib beddelka / kahor tirtirka) + channel-kiisa si subscribers-ka loogu sheego
+	UpdateEvent(ctx context.Context, projectID, eventID string, payload datatypes.JSON) (*models.RealtimeEvent, *models.RealtimeChannel, error)
+	DeleteEvent(ctx context.Context, projectID, eventID string) (*models.RealtimeEvent, *models.RealtimeChannel, error)
 
 	// --- 🚀 SDK & REALTIME LOGIC ---
 	JoinChannel(ctx context.Context, projectID, channelName, userID string) (*models.RealtimeChannel, error)
@@ -32,21 +69,81 @@ type RealtimeService interface {
 	BroadcastToChannel(ctx context.Context, projectID, channelName string, eventType models.RealtimeEventType, payload map[string]interface{}, senderID string) (*models.RealtimeEvent, error)
 	TrackPresence(ctx context.Context, projectID, channelName string) (int, error)
 	GetRecentHistory(ctx context.Context, channelID string, limit int) ([]models.RealtimeEvent, error)
+
+	// --- 🔐 AUTHORIZATION ---
+	// AuthenticateUser: Xaqiiji JWT-ga auth user-ka oo soo celi user_id-ga dhabta ah
+	AuthenticateUser(ctx context.Context, projectID, token string) (string, error)
+	AuthorizeChannel(ctx context.Context, channel *models.RealtimeChannel, userID string, action ChannelAction) error
+	// AuthorizeSubscription: AuthorizeChannel + signed token / auth endpoint (private channels); presence override
+	AuthorizeSubscription(ctx context.Context, channel *models.RealtimeChannel, req ChannelAuthRequest) (map[string]interface{}, error)
+
+	// --- 👥 PRESENCE ---
+	// TrackJoin: true haddii uu kani yahay connection-kii ugu horreeyay ee user-ka (presence_join)
+	TrackJoin(ctx context.Context, channel *models.RealtimeChannel, connectionID, userID, nodeID string, meta map[string]interface{}) (bool, error)
+	// TrackLeave: true haddii uu kani ahaa connection-kii ugu dambeeyay ee user-ka (presence_leave)
+	TrackLeave(ctx context.Context, channelID uuid.UUID, connectionID string) (*models.RealtimePresence, bool, error)
+	ListPresence(ctx context.Context, channelID string) ([]models.PresenceMember, error)
+	HeartbeatPresence(ctx context.Context, connectionIDs []string) error
+	// EvictStalePresence: Soo celi kuwa la saaray ee ahaa connection-kii ugu dambeeyay ee user-kooda
+	EvictStalePresence(ctx context.Context, before time.Time) ([]models.RealtimePresence, error)
+
+	// --- 📬 GUARANTEED DELIVERY ---
+	// ReplaySince: Fariimaha ka dambeeyay lastEventID (reconnect resume)
+	ReplaySince(ctx context.Context, channel *models.RealtimeChannel, lastEventID string, limit int) ([]models.RealtimeEvent, error)
+	AckEvent(ctx context.Context, eventID string) error
+	RecordRetry(ctx context.Context, eventID string) error
+
+	// --- ✉️ DIRECT MESSAGES ---
+	// ConsumeDirectMessage: Direct message kasta wuxuu ka mid yahay realtime events-ka bishan (plan limit)
+	ConsumeDirectMessage(ctx context.Context, projectID string) error
+
+	// --- 🗄️ RETENTION & ARCHIVAL ---
+	// StartRetentionPruner: Background worker (pruning + archiving idle channels)
+	StartRetentionPruner(ctx context.Context, opts RetentionOptions)
+	PruneEvents(ctx context.Context) (int64, error)
+	ArchiveChannel(ctx context.Context, projectID, channelID string) (*models.RealtimeChannel, error)
+	// ExportEvents: Taariikhda channel-ka oo NDJSON ah (hal event sadar kasta)
+	ExportEvents(ctx context.Context, channelID uuid.UUID, w io.Writer) error
+
+	// --- 🧩 SHARED STATE ---
+	// PatchState: Fuli JSON Patch (RFC 6902) si kala horreyn leh; channel-ka (state + revision cusub) ayaa la soo celiyaa
+	PatchState(ctx context.Context, projectID string, channelID uuid.UUID, ops []utils.PatchOperation) (*models.RealtimeChannel, error)
+	ResetState(ctx context.Context, projectID string, channelID uuid.UUID, state json.RawMessage) (*models.RealtimeChannel, error)
+
+	// --- 🛡️ MODERATION ---
+	// Moderate: Ban ama mute (duration 0 = weligeed); ChannelID nil = project-ka oo dhan
+	Moderate(ctx context.Context, m *models.RealtimeModeration, duration time.Duration) error
+	LiftModeration(ctx context.Context, projectID, moderationID string) (*models.RealtimeModeration, error)
+	ListModerations(ctx context.Context, projectID, channelID string) ([]models.RealtimeModeration, error)
+	// CheckModeration: ErrRealtimeBanned (subscribe + broadcast) ama ErrRealtimeMuted (broadcast kaliya); channel nil = connection-ka
+	CheckModeration(ctx context.Context, projectID string, channel *models.RealtimeChannel, userID string, action ChannelAction) error
 }
 
 type realtimeService struct {
 	channelRepo  repo.RealtimeChannelRepository
 	eventRepo    repo.RealtimeEventRepository
+	authUserRepo repo.AuthUserRepository
+	presenceRepo repo.RealtimePresenceRepository
+	modRepo      repo.RealtimeModerationRepository
+	featureRepo  repo.ProjectFeatureRepository
 	tracker      *AnalyticsTracker
 	usageService ProjectUsageService
+
+	retention  RetentionOptions
+	authClient *http.Client // Auth endpoint-ka project-ka (SSRF guard; timeout context-ka ayaa xakameeya)
 }
 
-func NewRealtimeService(cr repo.RealtimeChannelRepository, er repo.RealtimeEventRepository, tracker *AnalyticsTracker, usage ProjectUsageService) RealtimeService {
+func NewRealtimeService(cr repo.RealtimeChannelRepository, er repo.RealtimeEventRepository, ar repo.AuthUserRepository, pr repo.RealtimePresenceRepository, mr repo.RealtimeModerationRepository, fr repo.ProjectFeatureRepository, tracker *AnalyticsTracker, usage ProjectUsageService) RealtimeService {
 	return &realtimeService{
 		channelRepo:  cr,
 		eventRepo:    er,
+		authUserRepo: ar,
+		presenceRepo: pr,
+		modRepo:      mr,
+		featureRepo:  fr,
 		tracker:      tracker,
 		usageService: usage,
+		authClient:   httpclient.NewPublicClient(maxAuthTimeout),
 	}
 }
 
@@ -55,12 +152,22 @@ func NewRealtimeService(cr repo.RealtimeChannelRepository, er repo.RealtimeEvent
 // =========================================================================
 
 func (s *realtimeService) CreateChannel(ctx context.Context, channel *models.RealtimeChannel) error {
-	err := s.channelRepo.Create(ctx, channel)
-	if err == nil {
-		s.tracker.TrackEvent(ctx, channel.ProjectID, models.AnalyticsTypeRealtimeChannels, "total_channels", 1)
-		_ = s.usageService.UpdateUsage(ctx, channel.ProjectID, "api_calls", 1)
+	// 🛡️ Plan limit: realtime_channels_count waa la xijinayaa ka hor inta aan la abuurin
+	ok, err := s.usageService.ConsumeQuota(ctx, channel.ProjectID, "realtime_channels_count", "limit_realtime_channels", 1)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrRealtimeChannelLimit
 	}
-	return err
+
+	if err := s.channelRepo.Create(ctx, channel); err != nil {
+		_ = s.usageService.UpdateUsage(ctx, channel.ProjectID, "realtime_channels_count", -1)
+		return err
+	}
+	s.tracker.TrackEvent(ctx, channel.ProjectID, models.AnalyticsTypeRealtimeChannels, "total_channels", 1)
+	_ = s.usageService.UpdateUsage(ctx, channel.ProjectID, "api_calls", 1)
+	return nil
 }
 
 func (s *realtimeService) GetChannelsByProject(ctx context.Context, projectID string) ([]models.RealtimeChannel, error) {
@@ -75,16 +182,79 @@ func (s *realtimeService) GetChannelByID(ctx context.Context, channelID string)
 	return s.channelRepo.GetByID(ctx, id)
 }
 
-func (s *realtimeService) UpdateChannel(ctx context.Context, c *models.RealtimeChannel) error {
-	return s.channelRepo.Update(ctx, c)
+func (s *realtimeService) GetChannelByName(ctx context.Context, projectID, name string) (*models.RealtimeChannel, error) {
+	return s.channelRepo.GetByName(ctx, projectID, name)
+}
+
+// channelEditableFields: fields-ka uu developer-ku beddeli karo. Inta kale (sequence, presence,
+// shared state, archive, timestamps) server-ka ayaa maamula oo body-ga laga iska indhatiraa
+var channelEditableFields = map[string]bool{
+	"name":                 true,
+	"description":          true,
+	"is_private":           true,
+	"max_clients":          true,
+	"subscription_type":    true,
+	"metadata":             true,
+	"retention_policy":     true,
+	"retention_max_age":    true,
+	"retention_max_events": true,
+	"tags":                 true,
+}
+
+// UpdateChannel: partial update (PATCH semantics) — fields-ka body-ga ku jira oo kaliya ayaa isbeddela
+func (s *realtimeService) UpdateChannel(ctx context.Context, projectID, channelID string, updates map[string]interface{}) (*models.RealtimeChannel, error) {
+	id, err := uuid.Parse(channelID)
+	if err != nil {
+		return nil, ErrRealtimeChannelNotFound
+	}
+	channel, err := s.channelRepo.GetByID(ctx, id)
+	if err != nil || channel.ProjectID != projectID {
+		return nil, ErrRealtimeChannelNotFound
+	}
+
+	fields := map[string]interface{}{}
+	for key, value := range updates {
+		if channelEditableFields[key] {
+			fields[key] = value
+		}
+	}
+	if len(fields) == 0 {
+		return channel, nil
+	}
+	if err := utils.ApplyUpdates(channel, fields); err != nil {
+		return nil, err
+	}
+	if _, ok := fields["name"]; ok && (channel.Name == "" || strings.Contains(channel.Name, "*")) {
+		return nil, ErrRealtimeInvalidChannel
+	}
+
+	columns := []string{"updated_at"}
+	for key := range fields {
+		columns = append(columns, key)
+	}
+	channel.UpdatedAt = time.Now()
+	if err := s.channelRepo.Update(ctx, channel, columns); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRealtimeChannelNotFound
+		}
+		return nil, err
+	}
+	return s.channelRepo.GetByID(ctx, id)
 }
 
-func (s *realtimeService) DeleteChannel(ctx context.Context, channelID string) error {
+func (s *realtimeService) DeleteChannel(ctx context.Context, projectID, channelID string) error {
 	id, err := uuid.Parse(channelID)
 	if err != nil {
+		return ErrRealtimeChannelNotFound
+	}
+	channel, err := s.channelRepo.GetByID(ctx, id)
+	if err != nil || channel.ProjectID != projectID {
+		return ErrRealtimeChannelNotFound
+	}
+	if err := s.channelRepo.Delete(ctx, id); err != nil {
 		return err
 	}
-	return s.channelRepo.Delete(ctx, id)
+	return s.usageService.UpdateUsage(ctx, channel.ProjectID, "realtime_channels_count", -1)
 }
 
 // =========================================================================
@@ -92,20 +262,35 @@ func (s *realtimeService) DeleteChannel(ctx context.Context, channelID string) e
 // =========================================================================
 
 func (s *realtimeService) CreateEvent(ctx context.Context, projectID string, event *models.RealtimeEvent) error {
-	err := s.eventRepo.Create(ctx, event)
+	// 🛡️ Plan limit: fariimaha bishan
+	ok, err := s.usageService.ConsumeRealtimeEvent(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrRealtimeEventLimit
+	}
+
+	// 🔢 Sequence cusub (waxay sidoo kale cusboonaysiisaa channel-ka last_message_at)
+	channel, err := s.channelRepo.NextSequence(ctx, event.ChannelID)
 	if err == nil {
-		s.tracker.TrackEvent(ctx, projectID, models.AnalyticsTypeRealtimeEvents, "total_messages", 1)
-		_ = s.usageService.UpdateUsage(ctx, projectID, "api_calls", 1)
-
-		// Update Channel timestamp
-		channel, _ := s.channelRepo.GetByID(ctx, event.ChannelID)
-		if channel != nil {
-			now := time.Now()
-			channel.LastMessageAt = &now
-			_ = s.channelRepo.Update(ctx, channel)
+		event.Sequence = channel.LastSequence
+		if channel.RetentionPolicy == models.RetentionEphemeral {
+			// ⚡ Ephemeral: live kaliya, database-ka lama galiyo (id-ga waa ACK-ga darteed)
+			event.ID = uuid.New()
+			event.CreatedAt = time.Now()
+		} else {
+			err = s.eventRepo.Create(ctx, event)
 		}
 	}
-	return err
+	if err != nil {
+		_ = s.usageService.UpdateUsage(ctx, projectID, "realtime_events_count", -1)
+		return err
+	}
+
+	s.tracker.TrackEvent(ctx, projectID, models.AnalyticsTypeRealtimeEvents, "total_messages", 1)
+	_ = s.usageService.UpdateUsage(ctx, projectID, "api_calls", 1)
+	return nil
 }
 
 func (s *realtimeService) GetEventsByChannel(ctx context.Context, channelID string) ([]models.RealtimeEvent, error) {
@@ -116,31 +301,57 @@ func (s *realtimeService) GetEventsByChannel(ctx context.Context, channelID stri
 	return s.eventRepo.GetAllByChannel(ctx, id)
 }
 
-func (s *realtimeService) UpdateEvent(ctx context.Context, eventID string, payload datatypes.JSON) error {
-	id, err := uuid.Parse(eventID)
+func (s *realtimeService) UpdateEvent(ctx context.Context, projectID, eventID string, payload datatypes.JSON) (*models.RealtimeEvent, *models.RealtimeChannel, error) {
+	if len(payload) == 0 {
+		return nil, nil, errors.New("payload is required")
+	}
+	event, channel, err := s.eventInProject(ctx, projectID, eventID)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
-	event, err := s.eventRepo.GetByID(ctx, id)
+	edited, err := s.eventRepo.Edit(ctx, event.ID, payload, time.Now())
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
-	event.Payload = payload
-	return s.eventRepo.Update(ctx, event)
+	return edited, channel, nil
 }
 
-func (s *realtimeService) DeleteEvent(ctx context.Context, eventID string) error {
+func (s *realtimeService) DeleteEvent(ctx context.Context, projectID, eventID string) (*models.RealtimeEvent, *models.RealtimeChannel, error) {
+	event, channel, err := s.eventInProject(ctx, projectID, eventID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.eventRepo.Delete(ctx, event.ID); err != nil {
+		return nil, nil, err
+	}
+	return event, channel, nil
+}
+
+// eventInProject: Event-ka + channel-kiisa, kaliya haddii uu ka tirsan yahay project-kan
+func (s *realtimeService) eventInProject(ctx context.Context, projectID, eventID string) (*models.RealtimeEvent, *models.RealtimeChannel, error) {
 	id, err := uuid.Parse(eventID)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
-	return s.eventRepo.Delete(ctx, id)
+	event, err := s.eventRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	channel, err := s.channelRepo.GetByID(ctx, event.ChannelID)
+	if err != nil || channel.ProjectID != projectID {
+		return nil, nil, gorm.ErrRecordNotFound
+	}
+	return event, channel, nil
 }
 
 // =========================================================================
 // ✅ 3. SDK & REALTIME LOGIC
 // =========================================================================
 func (s *realtimeService) JoinChannel(ctx context.Context, projectID, channelName, userID string) (*models.RealtimeChannel, error) {
+	if strings.Contains(channelName, "*") {
+		return nil, ErrRealtimeInvalidChannel
+	}
+
 	// 🚀 1. Marka hore si degan u raadi qolka haddii uu jiro
 	channel, err := s.channelRepo.GetByName(ctx, projectID, channelName)
 
@@ -153,21 +364,41 @@ func (s *realtimeService) JoinChannel(ctx context.Context, projectID, channelNam
 			SubscriptionType: models.SubscriptionPublic,
 			RetentionPolicy:  models.RetentionPersistent, // Kani ayaa pgAdmin ku xaraynaya
 		}
-		if err := s.channelRepo.Create(ctx, newChannel); err != nil {
+		if err := s.CreateChannel(ctx, newChannel); err != nil {
+			if err == ErrRealtimeChannelLimit {
+				return nil, err
+			}
+			// Node kale ayaa laga yaabaa inuu isla mar abuuray (unique name)
+			if existing, getErr := s.channelRepo.GetByName(ctx, projectID, channelName); getErr == nil {
+				return existing, nil
+			}
 			return nil, err
 		}
 		return newChannel, nil
 	}
 
+	// 🗄️ Channel la archive gareeyay wuu soo noolaadaa marka mar kale la isticmaalo
+	if channel.Archived {
+		if err := s.channelRepo.Unarchive(ctx, channel.ID); err == nil {
+			channel.Archived = false
+			channel.ArchivedAt = nil
+		}
+	}
+
 	// 🚀 3. Haddii uu hore u jiray, isaga si toos ah u soo celi (Ha isku dayin inaa INSERT gareyso)
 	return channel, nil
 }
+
+// LeaveChannel: Ka saar user-ka dhamaan connections-kiisa channel-kan
 func (s *realtimeService) LeaveChannel(ctx context.Context, projectID, channelName, userID string) error {
 	channel, err := s.channelRepo.GetByName(ctx, projectID, channelName)
 	if err != nil {
 		return err
 	}
-	return s.channelRepo.UpdateConnectedCount(ctx, channel.ID, -1)
+	if _, err := s.presenceRepo.RemoveByKey(ctx, channel.ID, userID); err != nil {
+		return err
+	}
+	return s.syncConnectedCount(ctx, channel.ID)
 }
 
 func (s *realtimeService) BroadcastToChannel(ctx context.Context, projectID, channelName string, eventType models.RealtimeEventType, payload map[string]interface{}, senderID string) (*models.RealtimeEvent, error) {
@@ -198,7 +429,8 @@ func (s *realtimeService) TrackPresence(ctx context.Context, projectID, channelN
 	if err != nil {
 		return 0, err
 	}
-	return channel.ConnectedClients, nil
+	count, err := s.presenceRepo.CountMembers(ctx, channel.ID)
+	return int(count), err
 }
 
 func (s *realtimeService) GetRecentHistory(ctx context.Context, channelID string, limit int) ([]models.RealtimeEvent, error) {
@@ -208,3 +440,228 @@ func (s *realtimeService) GetRecentHistory(ctx context.Context, channelID string
 	}
 	return s.eventRepo.GetRecentEvents(ctx, id, limit)
 }
+
+// =========================================================================
+// ✅ 4. AUTHORIZATION
+// =========================================================================
+
+func (s *realtimeService) AuthenticateUser(ctx context.Context, projectID, token string) (string, error) {
+	userID, err := verifyAuthUserToken(ctx, s.authUserRepo, projectID, token)
+	if err != nil {
+		return "", ErrRealtimeInvalidToken
+	}
+	return userID, nil
+}
+
+// AuthorizeChannel:
+//   - public:    qof kasta oo haysta API key
+//   - protected: waa in la soo galay (auth user)
+//   - private / is_private: auth user oo ku jira metadata.allowed_users
+//
+// User-ka la ban gareeyay (ama la mute gareeyay marka uu dirayo) waa la diidayaa nooc kasta ha ahaado channel-ku.
+func (s *realtimeService) AuthorizeChannel(ctx context.Context, channel *models.RealtimeChannel, userID string, action ChannelAction) error {
+	if userID != "" {
+		if err := s.CheckModeration(ctx, channel.ProjectID, channel, userID, action); err != nil {
+			return err
+		}
+	}
+
+	private := channel.IsPrivate || channel.SubscriptionType == models.SubscriptionPrivate
+	if !private && channel.SubscriptionType != models.SubscriptionProtected {
+		return nil
+	}
+	if userID == "" {
+		return ErrRealtimeAuthRequired
+	}
+	if !private {
+		return nil
+	}
+
+	var meta struct {
+		AllowedUsers []string `json:"allowed_users"`
+	}
+	_ = json.Unmarshal(channel.Metadata, &meta)
+	for _, u := range meta.AllowedUsers {
+		if u == userID {
+			return nil
+		}
+	}
+	return ErrRealtimeForbidden
+}
+
+// =========================================================================
+// ✅ 5. PRESENCE (Members, Multi-tab, Heartbeats)
+// =========================================================================
+
+func presenceKey(userID, connectionID string) string {
+	if userID != "" {
+		return userID
+	}
+	return "anon:" + connectionID
+}
+
+func (s *realtimeService) TrackJoin(ctx context.Context, channel *models.RealtimeChannel, connectionID, userID, nodeID string, meta map[string]interface{}) (bool, error) {
+	if meta == nil {
+		meta = map[string]interface{}{}
+	}
+	metaJSON, _ := json.Marshal(meta)
+	now := time.Now()
+	p := &models.RealtimePresence{
+		ProjectID:    channel.ProjectID,
+		ChannelID:    channel.ID,
+		ChannelName:  channel.Name,
+		ConnectionID: connectionID,
+		PresenceKey:  presenceKey(userID, connectionID),
+		NodeID:       nodeID,
+		Metadata:     datatypes.JSON(metaJSON),
+		JoinedAt:     now,
+		LastSeenAt:   now,
+	}
+	if userID != "" {
+		p.UserID = &userID
+	}
+
+	// 🚪 Capacity + first tab: repo-ga ayaa hal lock gudaheed sameeya (replicas isku mar ma dhaafi karaan cap-ka)
+	first, err := s.presenceRepo.Join(ctx, p, s.channelCapacity(ctx, channel))
+	if errors.Is(err, repo.ErrPresenceFull) {
+		return false, ErrRealtimeChannelFull
+	}
+	if err != nil {
+		return false, err
+	}
+	_ = s.syncConnectedCount(ctx, channel.ID)
+	return first, nil
+}
+
+// channelCapacity: Connections-ka ugu badan ee channel-ka (0 = xad la'aan).
+// MaxClients 0 → limit-ka plan-ka; plan-ku sidoo kale waa saqafka MaxClients-ka channel-ka
+func (s *realtimeService) channelCapacity(ctx context.Context, channel *models.RealtimeChannel) int {
+	limit := 0
+	if usage, err := s.usageService.GetUsage(ctx, channel.ProjectID); err == nil && usage.LimitRealtimeChannelClients > 0 {
+		limit = usage.LimitRealtimeChannelClients
+	}
+	if channel.MaxClients > 0 && (limit == 0 || channel.MaxClients < limit) {
+		return channel.MaxClients
+	}
+	return limit
+}
+
+func (s *realtimeService) TrackLeave(ctx context.Context, channelID uuid.UUID, connectionID string) (*models.RealtimePresence, bool, error) {
+	p, last, err := s.presenceRepo.Leave(ctx, channelID, connectionID)
+	if err != nil || p == nil {
+		return nil, false, err
+	}
+	_ = s.syncConnectedCount(ctx, channelID)
+	return p, last, nil
+}
+
+func (s *realtimeService) ListPresence(ctx context.Context, channelID string) ([]models.PresenceMember, error) {
+	id, err := uuid.Parse(channelID)
+	if err != nil {
+		return nil, err
+	}
+	return s.presenceRepo.ListMembers(ctx, id)
+}
+
+func (s *realtimeService) HeartbeatPresence(ctx context.Context, connectionIDs []string) error {
+	return s.presenceRepo.Touch(ctx, connectionIDs, time.Now())
+}
+
+func (s *realtimeService) EvictStalePresence(ctx context.Context, before time.Time) ([]models.RealtimePresence, error) {
+	evicted, err := s.presenceRepo.EvictStale(ctx, before)
+	if err != nil {
+		return nil, err
+	}
+
+	var left []models.RealtimePresence
+	touched := map[uuid.UUID]bool{}
+	for _, p := range evicted {
+		touched[p.ChannelID] = true
+		remaining, err := s.presenceRepo.CountByKey(ctx, p.ChannelID, p.PresenceKey)
+		if err == nil && remaining == 0 {
+			left = append(left, p)
+		}
+	}
+	for id := range touched {
+		_ = s.syncConnectedCount(ctx, id)
+	}
+	return dedupePresence(left), nil
+}
+
+// dedupePresence: Haddii user-ku laba tab oo dhintay lahaa, hal presence_leave kaliya
+func dedupePresence(list []models.RealtimePresence) []models.RealtimePresence {
+	seen := map[string]bool{}
+	out := list[:0]
+	for _, p := range list {
+		k := p.ChannelID.String() + "|" + p.PresenceKey
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		out = append(out, p)
+	}
+	return out
+}
+
+func (s *realtimeService) syncConnectedCount(ctx context.Context, channelID uuid.UUID) error {
+	count, err := s.presenceRepo.CountMembers(ctx, channelID)
+	if err != nil {
+		return err
+	}
+	return s.channelRepo.SetConnectedCount(ctx, channelID, int(count))
+}
+
+// =========================================================================
+// ✅ 6. GUARANTEED DELIVERY (ACK & Replay)
+// =========================================================================
+
+func (s *realtimeService) ReplaySince(ctx context.Context, channel *models.RealtimeChannel, lastEventID string, limit int) ([]models.RealtimeEvent, error) {
+	// Ephemeral: taariikh lama hayo, wax la celiyo ma jiraan
+	if channel.RetentionPolicy == models.RetentionEphemeral {
+		return nil, nil
+	}
+	id, err := uuid.Parse(lastEventID)
+	if err != nil {
+		return nil, errors.New("invalid last_event_id")
+	}
+	last, err := s.eventRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, errors.New("last_event_id not found in channel history")
+	}
+	if last.ChannelID != channel.ID {
+		return nil, errors.New("last_event_id belongs to another channel")
+	}
+	return s.eventRepo.GetAfterSequence(ctx, channel.ID, last.Sequence, limit)
+}
+
+func (s *realtimeService) AckEvent(ctx context.Context, eventID string) error {
+	id, err := uuid.Parse(eventID)
+	if err != nil {
+		return err
+	}
+	return s.eventRepo.MarkDelivered(ctx, id, time.Now())
+}
+
+func (s *realtimeService) RecordRetry(ctx context.Context, eventID string) error {
+	id, err := uuid.Parse(eventID)
+	if err != nil {
+		return err
+	}
+	return s.eventRepo.IncrementRetries(ctx, id)
+}
+
+// =========================================================================
+// ✉️ 8. DIRECT MESSAGES
+// =========================================================================
+
+func (s *realtimeService) ConsumeDirectMessage(ctx context.Context, projectID string) error {
+	ok, err := s.usageService.ConsumeRealtimeEvent(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrRealtimeEventLimit
+	}
+	s.tracker.TrackEvent(ctx, projectID, models.AnalyticsTypeRealtimeEvents, "total_messages", 1)
+	return nil
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"superaib/internal/models"
+	"superaib/internal/storage/repo"
+
+	"github.com/google/uuid"
+)
+
+type stubUsageService struct {
+	ProjectUsageService
+	channelClients int
+}
+
+func (s *stubUsageService) GetUsage(ctx context.Context, projectUUID string) (*models.ProjectUsage, error) {
+	return &models.ProjectUsage{LimitRealtimeChannelClients: s.channelClients}, nil
+}
+
+// capPresenceRepo: Join wuxuu xusuustaa cap-ka la siiyay; full = repo-gu wuxuu sheegaa channel buuxa
+type capPresenceRepo struct {
+	repo.RealtimePresenceRepository
+	gotMax int
+	full   bool
+}
+
+func (r *capPresenceRepo) Join(ctx context.Context, p *models.RealtimePresence, maxConnections int) (bool, error) {
+	r.gotMax = maxConnections
+	if r.full {
+		return false, repo.ErrPresenceFull
+	}
+	return true, nil
+}
+
+func (r *capPresenceRepo) CountMembers(ctx context.Context, channelID uuid.UUID) (int64, error) {
+	return 1, nil
+}
+
+type countChannelRepo struct {
+	repo.RealtimeChannelRepository
+}
+
+func (countChannelRepo) SetConnectedCount(ctx context.Context, id uuid.UUID, count int) error {
+	return nil
+}
+
+func TestTrackJoinCapacity(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxClients int
+		planLimit  int
+		want       int
+	}{
+		{"unset uses plan", 0, 500, 500},
+		{"unset without plan limit", 0, 0, 0},
+		{"unlimited plan", 0, -1, 0},
+		{"channel below plan", 50, 500, 50},
+		{"channel capped by plan", 1000, 500, 500},
+		{"channel without plan limit", 50, 0, 50},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			presence := &capPresenceRepo{}
+			svc := &realtimeService{presenceRepo: presence, channelRepo: countChannelRepo{}, usageService: &stubUsageService{channelClients: tt.planLimit}}
+			ch := &models.RealtimeChannel{ID: uuid.New(), ProjectID: "p1", Name: "chat", MaxClients: tt.maxClients}
+
+			first, err := svc.TrackJoin(context.Background(), ch, "c1", "", "node", nil)
+			if err != nil || !first {
+				t.Fatalf("TrackJoin = %v, %v", first, err)
+			}
+			if presence.gotMax != tt.want {
+				t.Fatalf("capacity = %d, want %d", presence.gotMax, tt.want)
+			}
+		})
+	}
+
+	svc := &realtimeService{presenceRepo: &capPresenceRepo{full: true}, channelRepo: countChannelRepo{}, usageService: &stubUsageService{}}
+	ch := &models.RealtimeChannel{ID: uuid.New(), ProjectID: "p1", Name: "chat", MaxClients: 1}
+	if _, err := svc.TrackJoin(context.Background(), ch, "c2", "", "node", nil); err != ErrRealtimeChannelFull {
+		t.Fatalf("full channel: err = %v, want ErrRealtimeChannelFull", err)
+	}
+}
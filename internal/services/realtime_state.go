@@ -0,0 +1,99 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"superaib/internal/models"
+	"superaib/pkg/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+// =========================================================================
+// 🧩 11. SHARED STATE (server-authoritative JSON, RFC 6902 patches)
+// =========================================================================
+// Channel-ka mode = shared_state wuxuu hayaa hal JSON document (State) iyo revision.
+// Patches-ka waxaa lagu fuliyaa row lock gudihiis (nodes kasta) sidaas darteed revision kasta
+// wuxuu leeyahay hal patch oo kaliya; client-ku wuxuu iska indho tiraa patches-ka rev <= snapshot-kiisa.
+
+var (
+	ErrRealtimeNotSharedState = errors.New("channel is not a shared-state channel")
+	ErrRealtimeStateTooLarge  = errors.New("shared state exceeds the maximum size")
+	ErrRealtimeInvalidPatch   = errors.New("invalid JSON patch")
+)
+
+const (
+	maxSharedStateBytes = 256 * 1024
+	maxPatchOps         = 100
+)
+
+func (s *realtimeService) PatchState(ctx context.Context, projectID string, channelID uuid.UUID, ops []utils.PatchOperation) (*models.RealtimeChannel, error) {
+	if len(ops) == 0 || len(ops) > maxPatchOps {
+		return nil, fmt.Errorf("%w: between 1 and %d operations are required", ErrRealtimeInvalidPatch, maxPatchOps)
+	}
+
+	// 🛡️ Patch kasta waa realtime event (plan limit)
+	ok, err := s.usageService.ConsumeRealtimeEvent(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrRealtimeEventLimit
+	}
+
+	channel, err := s.channelRepo.UpdateState(ctx, channelID, func(ch *models.RealtimeChannel) (datatypes.JSON, error) {
+		if ch.ProjectID != projectID || ch.Mode != models.ChannelModeSharedState {
+			return nil, ErrRealtimeNotSharedState
+		}
+		var doc interface{} = map[string]interface{}{}
+		if len(ch.State) > 0 {
+			if err := json.Unmarshal(ch.State, &doc); err != nil {
+				return nil, err
+			}
+		}
+		next, err := utils.ApplyJSONPatch(doc, ops)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrRealtimeInvalidPatch, err)
+		}
+		return encodeState(next)
+	})
+	if err != nil {
+		_ = s.usageService.UpdateUsage(ctx, projectID, "realtime_events_count", -1)
+		return nil, err
+	}
+
+	s.tracker.TrackEvent(ctx, projectID, models.AnalyticsTypeRealtimeEvents, "total_messages", 1)
+	return channel, nil
+}
+
+// ResetState: Beddel state-ka oo dhan (dashboard); channel-ka wuxuu noqdaa shared_state
+func (s *realtimeService) ResetState(ctx context.Context, projectID string, channelID uuid.UUID, state json.RawMessage) (*models.RealtimeChannel, error) {
+	var doc interface{}
+	if len(state) == 0 {
+		doc = map[string]interface{}{}
+	} else if err := json.Unmarshal(state, &doc); err != nil {
+		return nil, fmt.Errorf("%w: state must be valid JSON", ErrRealtimeInvalidPatch)
+	}
+
+	return s.channelRepo.UpdateState(ctx, channelID, func(ch *models.RealtimeChannel) (datatypes.JSON, error) {
+		if ch.ProjectID != projectID {
+			return nil, errors.New("channel not found")
+		}
+		ch.Mode = models.ChannelModeSharedState
+		return encodeState(doc)
+	})
+}
+
+func encodeState(doc interface{}) (datatypes.JSON, error) {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxSharedStateBytes {
+		return nil, ErrRealtimeStateTooLarge
+	}
+	return datatypes.JSON(data), nil
+}
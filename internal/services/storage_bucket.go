@@ -0,0 +1,400 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"path"
+	"regexp"
+	"strings"
+	"superaib/internal/models"
+	"unicode/utf8"
+)
+
+// =========================================================================
+// 🗂️ BUCKETS + FOLDERS (avatars/, receipts/, exports/ ...)
+// =========================================================================
+// Folders-ku waa prefixes-ka Path-ka (S3 oo kale): ma jiraan rows gaar ah. Move/rename waa
+// metadata kaliya: blob-ka driver-ka (<project>/<file_id>/<name>) meeshiisa ayuu joogaa.
+
+var (
+	ErrBucketNotFound     = errors.New("bucket not found")
+	ErrBucketExists       = errors.New("a bucket with this name already exists")
+	ErrBucketNotEmpty     = errors.New("bucket is not empty")
+	ErrInvalidBucket      = errors.New("invalid bucket")
+	ErrInvalidPath        = errors.New("invalid path")
+	ErrPathExists         = errors.New("a file already exists at this path")
+	ErrBucketMimeType     = errors.New("file type is not allowed in this bucket")
+	ErrBucketFileTooLarge = errors.New("file exceeds the bucket's maximum size")
+)
+
+const (
+	maxObjectPathLen   = 1024
+	defaultObjectLimit = 100
+	maxObjectLimit     = 1000
+)
+
+var bucketNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{1,62}$`)
+
+// BucketInput: Settings-ka bucket-ka (POST /buckets, PUT /buckets/{bucket}: dhammaan waa la beddelaa)
+type BucketInput struct {
+	Name             string   `json:"name"`
+	Visibility       string   `json:"visibility"`         // public (default) | authenticated | private
+	AllowedMimeTypes []string `json:"allowed_mime_types"` // "image/*", "application/pdf"
+	MaxFileSize      int64    `json:"max_file_size"`      // Bytes, 0 = xad la'aan
+	AllowedPrefixes  []string `json:"allowed_prefixes"`   // "avatars/"
+}
+
+// ObjectListQuery: GET /buckets/{bucket}/objects?prefix=&delimiter=&cursor=&limit=
+type ObjectListQuery struct {
+	Prefix    string
+	Delimiter string // "/" = folders; "" = files-ka hoos yaal prefix-ka oo dhan
+	Cursor    string // next_cursor-kii bogga hore
+	Limit     int
+}
+
+// ObjectListing: Files-ka iyo folders-ka (common prefixes) ee heerka la codsaday
+type ObjectListing struct {
+	Bucket     string               `json:"bucket"`
+	Prefix     string               `json:"prefix"`
+	Delimiter  string               `json:"delimiter,omitempty"`
+	Files      []models.StorageFile `json:"files"`
+	Prefixes   []string             `json:"prefixes"`
+	NextCursor string               `json:"next_cursor,omitempty"`
+}
+
+// MoveFileInput: POST /files/{id}/move. Bucket "" = bucket-ka hadda; Path "folder/" = magaca ayaa la hayaa
+type MoveFileInput struct {
+	Bucket string `json:"bucket"`
+	Path   string `json:"path"`
+}
+
+func (s *storageService) CreateBucket(ctx context.Context, projectID string, in BucketInput) (*models.StorageBucket, error) {
+	feature, err := s.featureRepo.GetFeatureByProjectIDAndType(ctx, projectID, models.FeatureTypeStorage)
+	if err != nil || !feature.Enabled {
+		return nil, ErrStorageDisabled
+	}
+	if !bucketNamePattern.MatchString(in.Name) {
+		return nil, fmt.Errorf("%w: name must be 2-63 lowercase letters, digits, '.', '_' or '-'", ErrInvalidBucket)
+	}
+	if _, err := s.bucketRepo.GetByName(ctx, projectID, in.Name); err == nil {
+		return nil, ErrBucketExists
+	}
+
+	bucket := &models.StorageBucket{ProjectID: projectID, Name: in.Name}
+	if err := applyBucketInput(bucket, in); err != nil {
+		return nil, err
+	}
+	if err := s.bucketRepo.Create(ctx, bucket); err != nil {
+		if isDuplicateKey(err) {
+			return nil, ErrBucketExists
+		}
+		return nil, err
+	}
+	return bucket, nil
+}
+
+func (s *storageService) ListBuckets(ctx context.Context, projectID string) ([]models.StorageBucket, error) {
+	return s.bucketRepo.ListByProject(ctx, projectID)
+}
+
+func (s *storageService) GetBucket(ctx context.Context, projectID, name string) (*models.StorageBucket, error) {
+	return s.projectBucket(ctx, projectID, name)
+}
+
+// UpdateBucket: Xeerarka cusub waxay khuseeyaan uploads/moves-ka xiga; files-ka hore lama taabto
+func (s *storageService) UpdateBucket(ctx context.Context, projectID, name string, in BucketInput) (*models.StorageBucket, error) {
+	bucket, err := s.projectBucket(ctx, projectID, name)
+	if err != nil {
+		return nil, err
+	}
+	if in.Name != "" && in.Name != bucket.Name {
+		return nil, fmt.Errorf("%w: buckets cannot be renamed", ErrInvalidBucket)
+	}
+	if err := applyBucketInput(bucket, in); err != nil {
+		return nil, err
+	}
+	if err := s.bucketRepo.Update(ctx, bucket); err != nil {
+		return nil, err
+	}
+	return bucket, nil
+}
+
+// DeleteBucket: Bucket madhan kaliya (files-ka marka hore ha la tirtiro ama ha la raro)
+func (s *storageService) DeleteBucket(ctx context.Context, projectID, name string) error {
+	bucket, err := s.projectBucket(ctx, projectID, name)
+	if err != nil {
+		return err
+	}
+	count, err := s.repo.CountByBucket(ctx, bucket.ID)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrBucketNotEmpty
+	}
+	return s.bucketRepo.Delete(ctx, bucket.ID)
+}
+
+// ListObjects: Heerka folder-ka (prefix) ee bucket-ka; delimiter-ku wuxuu isu uruuriyaa sub-folders-ka
+func (s *storageService) ListObjects(ctx context.Context, projectID, name string, q ObjectListQuery) (*ObjectListing, error) {
+	bucket, err := s.projectBucket(ctx, projectID, name)
+	if err != nil {
+		return nil, err
+	}
+	limit := q.Limit
+	if limit <= 0 {
+		limit = defaultObjectLimit
+	}
+	limit = min(limit, maxObjectLimit)
+	prefix := strings.TrimLeft(q.Prefix, "/")
+
+	entries, err := s.repo.ListBucketEntries(ctx, bucket.ID, prefix, q.Delimiter, q.Cursor, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	listing := &ObjectListing{
+		Bucket:    bucket.Name,
+		Prefix:    prefix,
+		Delimiter: q.Delimiter,
+		Files:     []models.StorageFile{},
+		Prefixes:  []string{},
+	}
+	if len(entries) > limit {
+		entries = entries[:limit]
+		listing.NextCursor = entries[limit-1].Path
+	}
+
+	var paths []string
+	for _, entry := range entries {
+		if entry.IsPrefix {
+			listing.Prefixes = append(listing.Prefixes, entry.Path)
+		} else {
+			paths = append(paths, entry.Path)
+		}
+	}
+	files, err := s.repo.GetByPaths(ctx, bucket.ID, paths)
+	if err != nil {
+		return nil, err
+	}
+	// Kala horreynta entries-ka (byte order) ayaa la ilaaliyaa
+	byPath := make(map[string]models.StorageFile, len(files))
+	for _, file := range files {
+		byPath[file.Path] = file
+	}
+	for _, p := range paths {
+		if file, ok := byPath[p]; ok {
+			listing.Files = append(listing.Files, file)
+		}
+	}
+	return listing, nil
+}
+
+// MoveFile: Rename (isla bucket-ka) ama u rar bucket kale. Bucket-ka cusub xeerarkiisa (MIME, size,
+// prefixes) waa in file-ku buuxiyaa; AccessControl-ka file-ka isma beddelo.
+func (s *storageService) MoveFile(ctx context.Context, projectID, fileID string, in MoveFileInput) (*models.StorageFile, error) {
+	file, err := s.projectFile(ctx, projectID, fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	var bucket *models.StorageBucket
+	switch {
+	case in.Bucket != "":
+		bucket, err = s.projectBucket(ctx, projectID, in.Bucket)
+	case file.BucketID != nil:
+		bucket, err = s.bucketRepo.GetByID(ctx, *file.BucketID)
+		if err != nil {
+			err = ErrBucketNotFound
+		}
+	default:
+		err = fmt.Errorf("%w: bucket is required for files outside a bucket", ErrInvalidBucket)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	objectPath, err := cleanObjectPath(in.Path, file.FileName)
+	if err != nil {
+		return nil, err
+	}
+	if file.BucketID != nil && *file.BucketID == bucket.ID && file.Path == objectPath {
+		return file, nil
+	}
+	if err := checkBucketRules(bucket, objectPath, file.FileType, file.SizeBytes); err != nil {
+		return nil, err
+	}
+	if existing, err := s.repo.GetByPath(ctx, bucket.ID, objectPath); err == nil && existing.ID != file.ID {
+		return nil, ErrPathExists
+	}
+
+	name := safeFileName(path.Base(objectPath))
+	if err := s.repo.Move(ctx, file.ID, bucket.ID, objectPath, name); err != nil {
+		if isDuplicateKey(err) {
+			return nil, ErrPathExists
+		}
+		return nil, err
+	}
+	file.BucketID, file.Path, file.FileName = &bucket.ID, objectPath, name
+	return file, nil
+}
+
+// bucketTarget: Bucket-ka iyo path-ka upload cusub (nil, "" = bucket la'aan). Xeerarka bucket-ka waa la
+// hubiyaa ka hor inta aan bytes la dirin; visibility la'aan → default-ka bucket-ka.
+func (s *storageService) bucketTarget(ctx context.Context, projectID string, opts *UploadOptions) (*models.StorageBucket, string, error) {
+	if opts.Bucket == "" {
+		if opts.Path != "" {
+			return nil, "", fmt.Errorf("%w: path requires a bucket", ErrInvalidPath)
+		}
+		return nil, "", nil
+	}
+	bucket, err := s.projectBucket(ctx, projectID, opts.Bucket)
+	if err != nil {
+		return nil, "", err
+	}
+	objectPath, err := cleanObjectPath(opts.Path, opts.FileName)
+	if err != nil {
+		return nil, "", err
+	}
+	fileType := fileTypeFor(path.Base(objectPath), opts.FileType)
+	if err := checkBucketRules(bucket, objectPath, fileType, opts.Size); err != nil {
+		return nil, "", err
+	}
+	if _, err := s.repo.GetByPath(ctx, bucket.ID, objectPath); err == nil {
+		return nil, "", ErrPathExists
+	}
+	if opts.AccessControl == nil {
+		opts.AccessControl = &models.StorageAccessControl{Visibility: bucket.Visibility}
+	}
+	return bucket, objectPath, nil
+}
+
+func (s *storageService) projectBucket(ctx context.Context, projectID, name string) (*models.StorageBucket, error) {
+	bucket, err := s.bucketRepo.GetByName(ctx, projectID, name)
+	if err != nil {
+		return nil, ErrBucketNotFound
+	}
+	return bucket, nil
+}
+
+// checkBucketRules: Prefixes-ka, MIME-ka iyo size-ka (size < 0 = lama yaqaan, UploadFile ayaa hubiya)
+func checkBucketRules(bucket *models.StorageBucket, objectPath, fileType string, size int64) error {
+	if prefixes := bucket.Prefixes(); len(prefixes) > 0 {
+		allowed := false
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(objectPath, prefix) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("%w: %q is outside the bucket's folders (%s)", ErrInvalidPath, objectPath, strings.Join(prefixes, ", "))
+		}
+	}
+	if !mimeAllowed(bucket.MimeTypes(), fileType) {
+		return fmt.Errorf("%w: %s", ErrBucketMimeType, fileType)
+	}
+	if bucket.MaxFileSize > 0 && size > bucket.MaxFileSize {
+		return fmt.Errorf("%w (%d bytes)", ErrBucketFileTooLarge, bucket.MaxFileSize)
+	}
+	return nil
+}
+
+// mimeAllowed: "image/png", "image/*" ama "*/*"; liis madhan = dhammaan
+func mimeAllowed(allowed []string, fileType string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(fileType)
+	if err != nil {
+		mediaType = strings.ToLower(fileType)
+	}
+	for _, pattern := range allowed {
+		if pattern == "*/*" || pattern == mediaType ||
+			(strings.HasSuffix(pattern, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(pattern, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+// cleanObjectPath: "avatars/u1/me.png"; "" ama "avatars/" (folder) → magaca file-ka ayaa lagu daraa.
+// Segments madhan, "." iyo ".." iyo control characters lama ogola.
+func cleanObjectPath(p, fileName string) (string, error) {
+	p = strings.TrimLeft(strings.ReplaceAll(p, "\\", "/"), "/")
+	if p == "" || strings.HasSuffix(p, "/") {
+		p += safeFileName(fileName)
+	}
+	if len(p) > maxObjectPathLen || !utf8.ValidString(p) {
+		return "", fmt.Errorf("%w: must be valid UTF-8 of at most %d bytes", ErrInvalidPath, maxObjectPathLen)
+	}
+	for _, segment := range strings.Split(p, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return "", fmt.Errorf("%w: empty, '.' and '..' segments are not allowed", ErrInvalidPath)
+		}
+		if strings.IndexFunc(segment, func(r rune) bool { return r < 0x20 || r == 0x7f }) >= 0 {
+			return "", fmt.Errorf("%w: control characters are not allowed", ErrInvalidPath)
+		}
+	}
+	return p, nil
+}
+
+// applyBucketInput: Hubi oo ku qor settings-ka (magaca mooyee)
+func applyBucketInput(bucket *models.StorageBucket, in BucketInput) error {
+	acl, err := normalizeAccessControl(&models.StorageAccessControl{Visibility: in.Visibility})
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBucket, err)
+	}
+	if in.MaxFileSize < 0 {
+		return fmt.Errorf("%w: max_file_size cannot be negative", ErrInvalidBucket)
+	}
+
+	mimeTypes := []string{}
+	for _, t := range in.AllowedMimeTypes {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if major, minor, ok := strings.Cut(t, "/"); !ok || major == "" || minor == "" {
+			return fmt.Errorf("%w: %q is not a MIME type (use type/subtype or type/*)", ErrInvalidBucket, t)
+		}
+		mimeTypes = append(mimeTypes, t)
+	}
+
+	prefixes := []string{}
+	for _, prefix := range in.AllowedPrefixes {
+		prefix = strings.TrimLeft(strings.TrimSpace(prefix), "/")
+		if prefix == "" {
+			continue
+		}
+		if !strings.HasSuffix(prefix, "/") {
+			prefix += "/"
+		}
+		if _, err := cleanObjectPath(strings.TrimSuffix(prefix, "/"), ""); err != nil {
+			return fmt.Errorf("%w: folder %q: %v", ErrInvalidBucket, prefix, err)
+		}
+		prefixes = append(prefixes, prefix)
+	}
+
+	bucket.Visibility = acl.Visibility
+	bucket.MaxFileSize = in.MaxFileSize
+	bucket.AllowedMimeTypes, _ = json.Marshal(mimeTypes)
+	bucket.AllowedPrefixes, _ = json.Marshal(prefixes)
+	return nil
+}
+
+// fileTypeFor: MIME-ka la sheegay ama kan extension-ka
+func fileTypeFor(name, fileType string) string {
+	if fileType == "" {
+		fileType = mime.TypeByExtension(path.Ext(name))
+	}
+	if fileType == "" {
+		fileType = "application/octet-stream"
+	}
+	return fileType
+}
+
+// isDuplicateKey: Unique index-ka Postgres (23505)
+func isDuplicateKey(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "duplicate key") || strings.Contains(msg, "23505")
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"superaib/internal/models"
+	"superaib/pkg/blobstore"
+
+	"github.com/google/uuid"
+)
+
+// =========================================================================
+// 🔐 CHECKSUMS + DEDUPLICATION
+// =========================================================================
+// Upload kasta SHA-256 ayaa loo xisaabiyaa inta uu stream-ku socdo (StorageFile.Checksum); Content-MD5 /
+// X-Checksum-Sha256 client-ka haddii ay khaldan yihiin blob-ka waa la tirtiraa. Dedup-ku waa ikhtiyaari
+// (storage feature config "deduplicate": true): files-ka content isku mid ah waxay wadaagaan hal StorageBlob,
+// usage-kuna bytes-ka unique ah kaliya ayuu xisaabiyaa. Dedup-ku wuxuu muujinayaa in content-ku horay
+// ugu jiray project-ka (storage_key-ga) — project-yada users kala duwan ku shubaa ha u fiirsadaan.
+
+var ErrChecksumMismatch = errors.New("content checksum does not match")
+
+// storageDedupConfig: Qaybta dedup-ka ee storage feature config-ka
+type storageDedupConfig struct {
+	Deduplicate bool `json:"deduplicate"`
+}
+
+func (s *storageService) dedupEnabled(ctx context.Context, projectID string) bool {
+	feature, err := s.featureRepo.GetFeatureByProjectIDAndType(ctx, projectID, models.FeatureTypeStorage)
+	if err != nil || len(feature.Config) == 0 {
+		return false
+	}
+	var cfg storageDedupConfig
+	return json.Unmarshal(feature.Config, &cfg) == nil && cfg.Deduplicate
+}
+
+// contentHasher: SHA-256 had iyo jeer; MD5 kaliya marka Content-MD5 la soo diray
+type contentHasher struct {
+	sha hash.Hash
+	md5 hash.Hash
+}
+
+func newContentHasher(opts UploadOptions) *contentHasher {
+	h := &contentHasher{sha: sha256.New()}
+	if len(opts.ContentMD5) > 0 {
+		h.md5 = md5.New()
+	}
+	return h
+}
+
+func (h *contentHasher) Write(p []byte) (int, error) {
+	h.sha.Write(p)
+	if h.md5 != nil {
+		h.md5.Write(p)
+	}
+	return len(p), nil
+}
+
+// verify: Digests-ka client-ka; natiijadu waa SHA-256 (hex)
+func (h *contentHasher) verify(opts UploadOptions) (string, error) {
+	sum := h.sha.Sum(nil)
+	if len(opts.ChecksumSHA256) > 0 && !bytes.Equal(sum, opts.ChecksumSHA256) {
+		return "", fmt.Errorf("%w: sha256 is %s", ErrChecksumMismatch, hex.EncodeToString(sum))
+	}
+	if h.md5 != nil && !bytes.Equal(h.md5.Sum(nil), opts.ContentMD5) {
+		return "", fmt.Errorf("%w: Content-MD5", ErrChecksumMismatch)
+	}
+	return hex.EncodeToString(sum), nil
+}
+
+// shareBlob: Haddii content-kan project-ka horay ugu jiro, obj-ka cusub waa la tirtiraa oo kii hore ayaa
+// la tixraacaa (shared = true, bytes cusub ma jiraan). Haddii kale obj-ku wuxuu noqdaa blob cusub (ref 1).
+// Khaladka DB-ga: dedup la'aan (blobID nil), file-ku obj-kiisa ayuu leeyahay.
+func (s *storageService) shareBlob(ctx context.Context, driver blobstore.Driver, projectID, checksum string, obj *blobstore.Object, size int64) (*blobstore.Object, *uuid.UUID, bool) {
+	for attempt := 0; attempt < 2; attempt++ {
+		if blob, err := s.blobRepo.Acquire(ctx, projectID, driver.Name(), checksum); err == nil {
+			if err := driver.Delete(context.WithoutCancel(ctx), obj.Key); err != nil {
+				fmt.Printf("⚠️ [Storage] Duplicate blob %s not deleted: %v\n", obj.Key, err)
+			}
+			shared := *obj
+			shared.Key, shared.URL, shared.Size = blob.StorageKey, blob.URL, blob.SizeBytes
+			return &shared, &blob.ID, true
+		}
+		if attempt > 0 {
+			break
+		}
+
+		blob := &models.StorageBlob{
+			ProjectID:  projectID,
+			Driver:     driver.Name(),
+			Checksum:   checksum,
+			StorageKey: obj.Key,
+			URL:        obj.URL,
+			SizeBytes:  size,
+			RefCount:   1,
+		}
+		err := s.blobRepo.Create(ctx, blob)
+		if err == nil {
+			return obj, &blob.ID, false
+		}
+		if !isDuplicateKey(err) {
+			break
+		}
+		// Upload kale oo content isku mid ah ayaa hadda abuuray blob-ka: tixraac
+	}
+	return obj, nil, false
+}
+
+// releaseFileBlob: Sii daa content-ka file-ka. freed = bytes-ka driver-ka waa la tirtiray (tixraacii ugu
+// dambeeyay ama file aan blob la wadaago lahayn) → usage-ka waa laga jaraa.
+func (s *storageService) releaseFileBlob(ctx context.Context, driver blobstore.Driver, file *models.StorageFile) bool {
+	if file.BlobID != nil {
+		_, last, err := s.blobRepo.Release(ctx, file.ProjectID, *file.BlobID)
+		if err != nil && !last {
+			fmt.Printf("⚠️ [Storage] Releasing blob %s failed: %v\n", file.BlobID, err)
+			return false
+		}
+		if !last {
+			return false // Files kale ayaa weli tixraacaya
+		}
+	}
+	if err := driver.Delete(ctx, file.StorageKey); err != nil {
+		fmt.Printf("⚠️ [Storage] Blob %s not deleted: %v\n", file.StorageKey, err)
+	}
+	return true
+}
@@ -0,0 +1,340 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"slices"
+	"strings"
+	"superaib/internal/models"
+	"superaib/pkg/blobstore"
+	"superaib/pkg/imaging"
+	"sync"
+	"time"
+)
+
+// =========================================================================
+// 🖼️ IMAGE TRANSFORMS (resize, crop, format, quality) — pure Go (stdlib + x/image/webp)
+// =========================================================================
+//
+//	GET /storage/files/{id}/image?w=256&h=256&fit=cover&gravity=center&format=jpeg&q=80
+//
+// Variant kasta waxaa lagu kaydiyaa driver-ka project-ka (<project>/<file>/variants/<name>), key-giisana
+// waxaa lagu qoraa StorageFile.Metadata.variants (Cloudinary key-ga wuu beddelaa; DeleteFile ayaa tirtira).
+// w/h waa inay ka mid noqdaan allowlist-ka (feature config "image_sizes") si aan variants aan xad lahayn loo abuurin.
+// WebP: x/image ayaa decode gareeya; output-ku waa lossless VP8L (q lama isticmaalo, sida PNG).
+// Bytes-ka variants-ka waxaa lagu daraa storage_used_mb (metadata.variant_bytes; DeleteFile ayaa ka jara).
+
+var defaultImageSizes = []int{32, 48, 64, 96, 128, 160, 192, 256, 320, 384, 480, 512, 640, 720, 768, 800, 960, 1024, 1080, 1200, 1280, 1440, 1600, 1920, 2048}
+
+const (
+	maxImageSourceBytes  = 50 << 20   // Originals ka weyn lama decode gareeyo
+	maxImageSourcePixels = 50_000_000 // Decompression bombs (PNG yar oo 100k x 100k ah)
+)
+
+var (
+	ErrImageUnsupported = errors.New("file is not a transformable image (jpeg, png, gif or webp)")
+	ErrImageTransform   = errors.New("invalid image transform")
+)
+
+// ImageTransform: Query-ga endpoint-ka (0 / "" = default)
+type ImageTransform struct {
+	Width, Height int
+	Fit           string // cover | contain | fill (marka w iyo h labadaba la bixiyo)
+	Gravity       string // cover: center | top | bottom | left | right
+	Format        string // jpeg | png ("" = format-ka asalka)
+	Quality       int    // JPEG 1-100 (5 kasta ayaa loo soo koobaa)
+}
+
+// ImageVariant: Natiijada (Body waa io.ReadSeeker marka la abuuray ama local disk)
+type ImageVariant struct {
+	File   *models.StorageFile
+	Object *blobstore.Object
+	Body   io.ReadCloser
+	Format string // jpeg | png
+	Cached bool
+}
+
+// storageImageConfig: Qaybta sawirrada ee storage feature config-ka
+type storageImageConfig struct {
+	ImageSizes []int `json:"image_sizes"`
+}
+
+func (s *storageService) imageSizes(ctx context.Context, projectID string) []int {
+	feature, err := s.featureRepo.GetFeatureByProjectIDAndType(ctx, projectID, models.FeatureTypeStorage)
+	if err == nil && len(feature.Config) > 0 {
+		var cfg storageImageConfig
+		if json.Unmarshal(feature.Config, &cfg) == nil && len(cfg.ImageSizes) > 0 {
+			return cfg.ImageSizes
+		}
+	}
+	return defaultImageSizes
+}
+
+// imageSourceFormat: Formats-ka la decode gareyn karo ("" = maya)
+func imageSourceFormat(fileType string) string {
+	mediaType, _, _ := mime.ParseMediaType(fileType)
+	switch mediaType {
+	case "image/jpeg", "image/jpg", "image/pjpeg":
+		return imaging.FormatJPEG
+	case "image/png":
+		return imaging.FormatPNG
+	case "image/gif":
+		return "gif"
+	case "image/webp":
+		return imaging.FormatWebP
+	}
+	return ""
+}
+
+func isProbeableImage(fileType string) bool {
+	return imageSourceFormat(fileType) != ""
+}
+
+// normalizeImageTransform: Hubi allowlist-ka, buuxi defaults-ka, kadibna magaca variant-ka (cache key)
+func normalizeImageTransform(t ImageTransform, sizes []int, source string) (ImageTransform, string, error) {
+	for _, v := range []int{t.Width, t.Height} {
+		if v != 0 && !slices.Contains(sizes, v) {
+			return t, "", fmt.Errorf("%w: w and h must be one of %v", ErrImageTransform, sizes)
+		}
+	}
+
+	t.Fit, t.Gravity = strings.ToLower(t.Fit), strings.ToLower(t.Gravity)
+	if t.Width == 0 || t.Height == 0 {
+		t.Fit, t.Gravity = "", ""
+	} else {
+		switch t.Fit {
+		case "":
+			t.Fit = imaging.FitCover
+		case imaging.FitCover, imaging.FitContain, imaging.FitFill:
+		default:
+			return t, "", fmt.Errorf("%w: fit must be cover, contain or fill", ErrImageTransform)
+		}
+		if t.Fit != imaging.FitCover {
+			t.Gravity = ""
+		} else {
+			switch t.Gravity {
+			case "":
+				t.Gravity = imaging.GravityCenter
+			case imaging.GravityCenter, imaging.GravityTop, imaging.GravityBottom, imaging.GravityLeft, imaging.GravityRight:
+			default:
+				return t, "", fmt.Errorf("%w: gravity must be center, top, bottom, left or right", ErrImageTransform)
+			}
+		}
+	}
+
+	format, err := imaging.OutputFormat(strings.ToLower(t.Format), source)
+	if err != nil {
+		return t, "", fmt.Errorf("%w: format must be jpeg, png or webp", ErrImageTransform)
+	}
+	t.Format = format
+
+	if format == imaging.FormatJPEG {
+		if t.Quality == 0 {
+			t.Quality = imaging.DefaultQuality
+		}
+		if t.Quality < 1 || t.Quality > 100 {
+			return t, "", fmt.Errorf("%w: q must be between 1 and 100", ErrImageTransform)
+		}
+		t.Quality = max(5, (t.Quality+2)/5*5) // 5 kasta: cache-ka ha kala firdhin
+	} else {
+		t.Quality = 0 // PNG iyo WebP (VP8L) waa lossless
+	}
+
+	name := fmt.Sprintf("w%d_h%d", t.Width, t.Height)
+	if t.Fit != "" {
+		name += "_" + t.Fit
+	}
+	if t.Gravity != "" {
+		name += "-" + t.Gravity
+	}
+	if t.Quality != 0 {
+		name += fmt.Sprintf("_q%d", t.Quality)
+	}
+	ext := ".png"
+	switch format {
+	case imaging.FormatJPEG:
+		ext = ".jpg"
+	case imaging.FormatWebP:
+		ext = ".webp"
+	}
+	return t, name + ext, nil
+}
+
+// ImageVariant: GET /files/{id}/image (API key). Private files: signed URL-ka ayaa la isticmaalaa.
+func (s *storageService) ImageVariant(ctx context.Context, projectID, fileID string, t ImageTransform) (*ImageVariant, error) {
+	file, err := s.projectFile(ctx, projectID, fileID)
+	if err != nil {
+		return nil, err
+	}
+	if file.RequiresSignedURL() {
+		return nil, ErrStorageSignedURL
+	}
+	return s.imageVariant(ctx, file, t)
+}
+
+// SignedImageVariant: Signed URL + transform params (saxiixu wuxuu daboolaa file-ka, ma aha variant-ka)
+func (s *storageService) SignedImageVariant(ctx context.Context, req SignedFileRequest, t ImageTransform) (*ImageVariant, error) {
+	file, err := s.verifySignedRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return s.imageVariant(ctx, file, t)
+}
+
+func (s *storageService) imageVariant(ctx context.Context, file *models.StorageFile, t ImageTransform) (*ImageVariant, error) {
+	if file.Driver == "" {
+		return nil, ErrStorageExternalFile
+	}
+	source := imageSourceFormat(file.FileType)
+	if source == "" {
+		return nil, ErrImageUnsupported
+	}
+	t, name, err := normalizeImageTransform(t, s.imageSizes(ctx, file.ProjectID), source)
+	if err != nil {
+		return nil, err
+	}
+	driver, err := s.driverForFile(ctx, file)
+	if err != nil {
+		return nil, err
+	}
+	out := &ImageVariant{File: file, Format: t.Format}
+
+	// 1. Cache-ka driver-ka
+	if s.openCachedVariant(ctx, driver, file, name, out) {
+		return out, nil
+	}
+
+	// 2. Hal generation variant kasta (codsiyada kale way sugayaan kadibna cache-ka ayay ka helayaan)
+	unlock := s.lockVariant(file.ID.String() + "/" + name)
+	defer unlock()
+	if fresh, err := s.repo.GetByID(ctx, file.ID); err == nil && s.openCachedVariant(ctx, driver, fresh, name, out) {
+		return out, nil
+	}
+
+	// Decode-ku waa CPU + memory: inta CPU-ga la egna hal mar
+	select {
+	case s.imageSem <- struct{}{}:
+		defer func() { <-s.imageSem }()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	data, err := s.readOriginal(ctx, driver, file)
+	if err != nil {
+		return nil, err
+	}
+	img, _, err := imaging.Decode(data, maxImageSourcePixels)
+	if err != nil {
+		if err == imaging.ErrTooLarge || err == imaging.ErrUnsupportedFormat {
+			return nil, fmt.Errorf("%w: %v", ErrImageUnsupported, err)
+		}
+		return nil, err
+	}
+	orientation := 1
+	if info, err := imaging.Probe(data[:min(len(data), imaging.ProbeBytes)]); err == nil {
+		orientation = info.Orientation
+	}
+
+	var buf bytes.Buffer
+	result := imaging.Transform(img, orientation, imaging.Options{Width: t.Width, Height: t.Height, Fit: t.Fit, Gravity: t.Gravity})
+	if err := imaging.Encode(&buf, result, t.Format, t.Quality); err != nil {
+		return nil, err
+	}
+
+	contentType := "image/" + t.Format
+	key := fmt.Sprintf("%s/%s/variants/%s", file.ProjectID, file.ID, name)
+	obj, err := driver.Put(ctx, key, bytes.NewReader(buf.Bytes()), int64(buf.Len()), contentType)
+	if err != nil {
+		// Cache-ka ma shaqeyn: variant-ka weli waa la bixiyaa, codsiga xiga ayaa isku dayi doona
+		fmt.Printf("⚠️ [Storage] Caching variant %s failed: %v\n", key, err)
+		obj = &blobstore.Object{Key: key, Size: int64(buf.Len()), ContentType: contentType, ModifiedAt: time.Now()}
+	} else if added, err := s.repo.AddVariant(ctx, file.ID, name, obj.Key, obj.Size); err != nil {
+		fmt.Printf("⚠️ [Storage] Recording variant %s failed: %v\n", key, err)
+	} else if added {
+		// Variant-ku waa bytes la kaydiyay: plan-ka storage-ka ayuu ka mid yahay
+		mb := float64(obj.Size) / (1024 * 1024)
+		s.tracker.TrackEvent(ctx, file.ProjectID, models.AnalyticsTypeStorageUsage, "total_storage_mb", mb)
+		_ = s.usageService.UpdateUsage(ctx, file.ProjectID, "storage_used_mb", mb)
+	}
+	obj.ContentType = contentType
+
+	s.tracker.TrackEvent(ctx, file.ProjectID, models.AnalyticsTypeStorageUsage, "image_transforms", 1)
+	out.Object, out.Body = obj, readSeekNopCloser{bytes.NewReader(buf.Bytes())}
+	return out, nil
+}
+
+func (s *storageService) openCachedVariant(ctx context.Context, driver blobstore.Driver, file *models.StorageFile, name string, out *ImageVariant) bool {
+	key := file.VariantKey(name)
+	if key == "" {
+		return false
+	}
+	rc, obj, err := driver.Get(ctx, key)
+	if err != nil {
+		return false // La tirtiray ama driver-ka ayaa fashilmay: dib u abuur
+	}
+	obj.ContentType = "image/" + out.Format
+	out.Body, out.Object, out.Cached = rc, obj, true
+	s.tracker.TrackEvent(ctx, file.ProjectID, models.AnalyticsTypeStorageUsage, "files_read", 1)
+	return true
+}
+
+func (s *storageService) readOriginal(ctx context.Context, driver blobstore.Driver, file *models.StorageFile) ([]byte, error) {
+	if file.SizeBytes > maxImageSourceBytes {
+		return nil, fmt.Errorf("%w: source image is larger than %d MB", ErrImageUnsupported, maxImageSourceBytes>>20)
+	}
+	rc, _, err := driver.Get(ctx, file.StorageKey)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, maxImageSourceBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxImageSourceBytes {
+		return nil, fmt.Errorf("%w: source image is larger than %d MB", ErrImageUnsupported, maxImageSourceBytes>>20)
+	}
+	return data, nil
+}
+
+// lockVariant: Mutex variant kasta (node-kan); entry-ga waa la tirtiraa marka la dhammeeyo
+func (s *storageService) lockVariant(key string) func() {
+	v, _ := s.variantLocks.LoadOrStore(key, &sync.Mutex{})
+	mu := v.(*sync.Mutex)
+	mu.Lock()
+	return func() {
+		s.variantLocks.Delete(key)
+		mu.Unlock()
+	}
+}
+
+type readSeekNopCloser struct{ *bytes.Reader }
+
+func (readSeekNopCloser) Close() error { return nil }
+
+// imageHead: Bilowga stream-ka upload-ka (EXIF + dimensions) iyadoo aan file-ka oo dhan la xasuusan
+type imageHead struct {
+	buf []byte
+}
+
+func (h *imageHead) Write(p []byte) (int, error) {
+	if room := imaging.ProbeBytes - len(h.buf); room > 0 {
+		h.buf = append(h.buf, p[:min(room, len(p))]...)
+	}
+	return len(p), nil
+}
+
+// metadata: {"image": {"format", "width", "height", "orientation", "exif": {...}}} (nil = sawir ma aha)
+func (h *imageHead) metadata() []byte {
+	info, err := imaging.Probe(h.buf)
+	if err != nil {
+		return nil
+	}
+	raw, _ := json.Marshal(map[string]interface{}{"image": info})
+	return raw
+}
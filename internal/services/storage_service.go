@@ -1,16 +1,18 @@
 package services
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"mime/multipart"
-	"net/http"
+	"path"
+	"runtime"
+	"strings"
 	"superaib/internal/models"
 	"superaib/internal/storage/repo"
+	"superaib/pkg/blobstore"
+	"sync"
 
 	"github.com/google/uuid"
 )
@@ -19,24 +21,100 @@ type StorageService interface {
 	CreateFile(ctx context.Context, file *models.StorageFile) (*models.StorageFile, error)
 	GetFilesByProject(ctx context.Context, projectID string, page, pageSize int) ([]models.StorageFile, int64, error)
 	GetFileByID(ctx context.Context, fileID string) (*models.StorageFile, error)
-	DeleteFile(ctx context.Context, fileID string) error
-	UploadToCloud(ctx context.Context, projectID string, file []byte, fileName string, fileType string) (*models.StorageFile, error)
+	DeleteFile(ctx context.Context, projectID, fileID string) error
+
+	// 📦 Binary operations (driver-ka project-ka: local, s3, cloudinary)
+	UploadFile(ctx context.Context, projectID string, r io.Reader, opts UploadOptions) (*models.StorageFile, error)
+	OpenFile(ctx context.Context, projectID, fileID string) (io.ReadCloser, *models.StorageFile, *blobstore.Object, error)
+	StatFile(ctx context.Context, projectID, fileID string) (*models.StorageFile, *blobstore.Object, error)
+
+	// 🔏 Private files (signed, expiring URLs)
+	AuthenticateUser(ctx context.Context, projectID, token string) (string, error)
+	SetAccessControl(ctx context.Context, projectID, fileID string, acl models.StorageAccessControl) (*models.StorageFile, error)
+	SignFileURL(ctx context.Context, projectID, fileID, userID string, opts SignURLOptions) (*SignedFileURL, error)
+	OpenSignedFile(ctx context.Context, req SignedFileRequest, statOnly bool) (*FileDelivery, error)
+
+	// ⏫ Resumable uploads (tus 1.0)
+	CreateUpload(ctx context.Context, projectID string, opts UploadOptions, rawMetadata string) (*models.StorageUpload, error)
+	GetUpload(ctx context.Context, projectID, uploadID, userID string) (*models.StorageUpload, error)
+	WriteUploadChunk(ctx context.Context, projectID, uploadID, userID string, offset int64, r io.Reader) (*models.StorageUpload, error)
+	TerminateUpload(ctx context.Context, projectID, uploadID, userID string) error
+	MaxUploadSize() int64
+	StartUploadSweeper(ctx context.Context, opts ResumableOptions)
+
+	// 🖼️ Image transforms (variants-ka waxaa lagu kaydiyaa driver-ka)
+	ImageVariant(ctx context.Context, projectID, fileID string, t ImageTransform) (*ImageVariant, error)
+	SignedImageVariant(ctx context.Context, req SignedFileRequest, t ImageTransform) (*ImageVariant, error)
+
+	// 🗂️ Buckets + folders (prefix/delimiter listing, move/rename)
+	CreateBucket(ctx context.Context, projectID string, in BucketInput) (*models.StorageBucket, error)
+	ListBuckets(ctx context.Context, projectID string) ([]models.StorageBucket, error)
+	GetBucket(ctx context.Context, projectID, name string) (*models.StorageBucket, error)
+	UpdateBucket(ctx context.Context, projectID, name string, in BucketInput) (*models.StorageBucket, error)
+	DeleteBucket(ctx context.Context, projectID, name string) error
+	ListObjects(ctx context.Context, projectID, bucket string, q ObjectListQuery) (*ObjectListing, error)
+	MoveFile(ctx context.Context, projectID, fileID string, in MoveFileInput) (*models.StorageFile, error)
 }
 
+// UploadOptions: Xogta upload-ka (multipart, tus, ...)
+type UploadOptions struct {
+	FileName      string
+	FileType      string                       // MIME; "" = laga soo saaro extension-ka
+	Size          int64                        // -1 = lama yaqaan (local driver kaliya)
+	UploadedBy    string                       // Auth user (ikhtiyaari)
+	AccessControl *models.StorageAccessControl // nil = default-ka bucket-ka (ama public)
+	Bucket        string                       // Magaca bucket-ka ("" = bucket la'aan)
+	Path          string                       // Path-ka gudaha bucket-ka ("" = magaca file-ka, "folder/" = folder-kaas)
+
+	// 🔐 Digests-ka client-ka (ikhtiyaari): khilaaf → ErrChecksumMismatch, blob-ka waa la tirtiraa
+	ChecksumSHA256 []byte // X-Checksum-Sha256
+	ContentMD5     []byte // Content-MD5
+
+	fileID uuid.UUID // tus finalize: ID go'an (upload-ka ID-giisa) si dib-u-isku-day uusan file labaad u abuurin
+}
+
+var (
+	ErrStorageDisabled     = errors.New("storage feature is disabled for this project")
+	ErrStorageFileNotFound = errors.New("file not found")
+	// Files-kii hore (Cloudinary, driver la'aan) kaliya URL-kooda ayaa la hayaa
+	ErrStorageExternalFile = errors.New("file is only available at its external URL")
+	ErrStorageSignedURL    = errors.New("file is private; request a signed URL")
+	ErrStorageAccessDenied = errors.New("access to this file is denied")
+	ErrStorageBadSignature = errors.New("signed URL is invalid or has expired")
+	ErrStorageInvalidACL   = errors.New("invalid access control")
+)
+
 type storageService struct {
 	repo         repo.StorageRepository
 	featureRepo  repo.ProjectFeatureRepository
 	tracker      *AnalyticsTracker
 	usageService ProjectUsageService // ✅ KU DAR: Si aan u xino limits-ka
+	authUserRepo repo.AuthUserRepository
+	uploadRepo   repo.StorageUploadRepository
+	bucketRepo   repo.StorageBucketRepository
+	blobRepo     repo.StorageBlobRepository
+	blobOptions  blobstore.Options // Local root + HTTP client (drivers-ka project kasta)
+	signingKey   []byte            // HMAC-ka signed URLs
+	resumable    ResumableOptions  // tus staging (StartUploadSweeper)
+	uploadLocks  sync.Map          // upload_id -> PATCH socda
+	variantLocks sync.Map          // file/variant -> generation socda
+	imageSem     chan struct{}     // Decodes isku mar ah (CPU kasta hal)
 }
 
-// ✅ Constructor-ka hadda wuxuu aqbalayaa 4 dependencies
-func NewStorageService(r repo.StorageRepository, fr repo.ProjectFeatureRepository, tracker *AnalyticsTracker, usage ProjectUsageService) StorageService {
+// ✅ Constructor-ka: opts waa settings-ka server-ka (local disk root) ee drivers-ka
+func NewStorageService(r repo.StorageRepository, fr repo.ProjectFeatureRepository, ur repo.AuthUserRepository, uploads repo.StorageUploadRepository, buckets repo.StorageBucketRepository, blobs repo.StorageBlobRepository, tracker *AnalyticsTracker, usage ProjectUsageService, opts blobstore.Options, signingSecret string) StorageService {
 	return &storageService{
 		repo:         r,
 		featureRepo:  fr,
 		tracker:      tracker,
 		usageService: usage,
+		authUserRepo: ur,
+		uploadRepo:   uploads,
+		bucketRepo:   buckets,
+		blobRepo:     blobs,
+		blobOptions:  opts,
+		signingKey:   []byte(signingSecret),
+		imageSem:     make(chan struct{}, runtime.NumCPU()),
 	}
 }
 
@@ -47,6 +125,11 @@ func (s *storageService) CreateFile(ctx context.Context, file *models.StorageFil
 		return nil, errors.New("storage feature is disabled for this project")
 	}
 
+	// Record dibadda ah: server-ka ayaa maamula driver-ka, key-ga, blob-ka iyo metadata-ga
+	file.ID, file.Driver, file.StorageKey = uuid.Nil, "", ""
+	file.BlobID, file.Checksum, file.BucketID, file.Path = nil, nil, nil, ""
+	file.AccessControl, file.Metadata = nil, nil
+
 	// 2. Keydi xogta file-ka ee Database-ka
 	if err := s.repo.Create(ctx, file); err != nil {
 		return nil, err
@@ -82,102 +165,275 @@ func (s *storageService) GetFileByID(ctx context.Context, fileID string) (*model
 	return file, nil
 }
 
-func (s *storageService) DeleteFile(ctx context.Context, fileID string) error {
-	id, err := uuid.Parse(fileID)
+func (s *storageService) DeleteFile(ctx context.Context, projectID, fileID string) error {
+	file, err := s.projectFile(ctx, projectID, fileID)
 	if err != nil {
-		return errors.New("invalid file id")
+		return err
 	}
 
-	file, err := s.repo.GetByID(ctx, id)
-	if err != nil {
+	if err := s.repo.Delete(ctx, file.ID); err != nil {
 		return err
 	}
 
-	if err := s.repo.Delete(ctx, id); err != nil {
-		return err
+	// 🗑️ Blob-ka driver-ka + image variants (best effort: record-ka waa la tirtiray, orphan-ku ma xannibo user-ka).
+	// Blob la wadaago (dedup): bytes-ka waxaa la tirtiraa oo usage-ka laga jaraa kaliya tixraaca ugu dambeeya.
+	freed := true
+	var releasedMB float64
+	if file.Driver != "" && file.StorageKey != "" {
+		if driver, err := s.driverForFile(ctx, file); err != nil {
+			fmt.Printf("⚠️ [Storage] Blob %s not deleted: %v\n", file.StorageKey, err)
+		} else {
+			freed = s.releaseFileBlob(ctx, driver, file)
+			for _, key := range file.VariantKeys() {
+				if err := driver.Delete(ctx, key); err != nil {
+					fmt.Printf("⚠️ [Storage] Blob %s not deleted: %v\n", key, err)
+				}
+			}
+			releasedMB = file.VariantMB() // Variants-ku file-kan kaliya ayay leeyihiin (dedup ma taabto)
+		}
+	}
+	if freed {
+		releasedMB += file.SizeMB
 	}
 
 	// ✅ 1. TRACK ANALYTICS (Marka la tirtiro)
 	s.tracker.TrackEvent(ctx, file.ProjectID, models.AnalyticsTypeStorageUsage, "files_deleted", 1)
-	s.tracker.TrackEvent(ctx, file.ProjectID, models.AnalyticsTypeStorageUsage, "total_storage_mb", -file.SizeMB)
+	if releasedMB == 0 {
+		return nil
+	}
+	s.tracker.TrackEvent(ctx, file.ProjectID, models.AnalyticsTypeStorageUsage, "total_storage_mb", -releasedMB)
 
 	// ✅ 2. UPDATE PROJECT USAGE (Ka dhim MB-yada mashruuca hadda u xareysan)
 	// Waxaan u dhiibaynaa '-' si uu Database-ka uga jaro (Decrement)
-	_ = s.usageService.UpdateUsage(ctx, file.ProjectID, "storage_used_mb", -file.SizeMB)
+	_ = s.usageService.UpdateUsage(ctx, file.ProjectID, "storage_used_mb", -releasedMB)
 
 	return nil
 }
-func (s *storageService) UploadToCloud(ctx context.Context, projectID string, fileData []byte, fileName string, fileType string) (*models.StorageFile, error) {
-	// 1. Hel Feature Config-ga (Kii Developer-ku gashaday - Image 1)
+
+// =========================================================================
+// 📦 DRIVERS (config-ka storage ProjectFeature-ka ayaa doorta)
+// =========================================================================
+
+// driverFor: Driver-ka project-ka hadda (feature-ku waa inuu shidan yahay)
+func (s *storageService) driverFor(ctx context.Context, projectID string) (blobstore.Driver, error) {
 	feature, err := s.featureRepo.GetFeatureByProjectIDAndType(ctx, projectID, models.FeatureTypeStorage)
 	if err != nil || !feature.Enabled {
-		return nil, errors.New("storage feature is disabled for this project")
+		return nil, ErrStorageDisabled
 	}
-
-	// 2. Kasoo saar Config-ga (Cloud Name iyo Upload Preset)
-	var config map[string]string
-	if err := json.Unmarshal(feature.Config, &config); err != nil {
-		return nil, errors.New("invalid storage configuration")
+	cfg, err := blobstore.ParseConfig(feature.Config)
+	if err != nil {
+		return nil, err
 	}
+	return blobstore.Open(cfg, s.blobOptions)
+}
 
-	cloudName := config["cloud_name"]
-	uploadPreset := config["upload_preset"]
-
-	if cloudName == "" || uploadPreset == "" {
-		return nil, errors.New("cloudinary config (cloud_name or upload_preset) is missing")
+// driverForFile: Driver-ka file-ku ku kaydsan yahay. Local disk-ga had iyo jeer waa la heli karaa;
+// kuwa kale waxay u baahan yihiin credentials-ka config-ka hadda (haddii driver-ka la beddelay, file-kii hore lama gaari karo).
+func (s *storageService) driverForFile(ctx context.Context, file *models.StorageFile) (blobstore.Driver, error) {
+	if file.Driver == "" {
+		return nil, ErrStorageExternalFile
+	}
+	// Local root-ku waa la wadaagaa: key-gu waa inuu ku jiraa folder-ka project-ka
+	if !strings.HasPrefix(file.StorageKey, file.ProjectID+"/") {
+		return nil, ErrStorageFileNotFound
 	}
+	if file.Driver == blobstore.DriverLocal {
+		return blobstore.NewLocalDriver(s.blobOptions.LocalRoot)
+	}
+	driver, err := s.driverFor(ctx, file.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	if driver.Name() != file.Driver {
+		return nil, fmt.Errorf("file was stored with the %s driver but the project now uses %s", file.Driver, driver.Name())
+	}
+	return driver, nil
+}
 
-	// 🚀 3. UPLOAD TO CLOUDINARY (REAL API CALL)
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
+// projectFile: File-ka waa inuu ka tirsan yahay project-ka codsiga
+func (s *storageService) projectFile(ctx context.Context, projectID, fileID string) (*models.StorageFile, error) {
+	id, err := uuid.Parse(fileID)
+	if err != nil {
+		return nil, errors.New("invalid file id")
+	}
+	file, err := s.repo.GetByID(ctx, id)
+	if err != nil || file.ProjectID != projectID {
+		return nil, ErrStorageFileNotFound
+	}
+	return file, nil
+}
 
-	// Ku dar File-ka
-	part, _ := writer.CreateFormFile("file", fileName)
-	part.Write(fileData)
+// UploadFile: Stream-ka u gudbi driver-ka, kadibna keydi metadata-ga (haddii DB-gu fashilmo blob-ka waa la tirtiraa)
+func (s *storageService) UploadFile(ctx context.Context, projectID string, r io.Reader, opts UploadOptions) (*models.StorageFile, error) {
+	bucket, objectPath, err := s.bucketTarget(ctx, projectID, &opts)
+	if err != nil {
+		return nil, err
+	}
+	acl, err := normalizeAccessControl(opts.AccessControl)
+	if err != nil {
+		return nil, err
+	}
+	driver, err := s.driverFor(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
 
-	// Ku dar Upload Preset (Khasab ah Cloudinary)
-	writer.WriteField("upload_preset", uploadPreset)
-	writer.Close()
+	name, size := safeFileName(opts.FileName), opts.Size
+	if objectPath != "" {
+		name = safeFileName(path.Base(objectPath))
+	}
+	fileType := fileTypeFor(name, opts.FileType)
+	if bucket != nil && bucket.MaxFileSize > 0 && size < 0 {
+		// Size lama yaqaan: byte-ka xadka dhaafa ayaa muujinaya in file-ku weyn yahay
+		r = io.LimitReader(r, bucket.MaxFileSize+1)
+	}
 
-	cloudinaryURL := fmt.Sprintf("https://api.cloudinary.com/v1_1/%s/image/upload", cloudName)
-	req, _ := http.NewRequest("POST", cloudinaryURL, body)
-	req.Header.Set("Content-Type", writer.FormDataContentType())
+	// 🖼️ Sawirrada: bilowga stream-ka ayaa la hayaa (EXIF, dimensions) → Metadata
+	var head *imageHead
+	if isProbeableImage(fileType) {
+		head = &imageHead{}
+		r = io.TeeReader(r, head)
+	}
+	// 🔐 SHA-256 (+ MD5 marka la codsado) inta stream-ku socdo
+	hasher := newContentHasher(opts)
+	r = io.TeeReader(r, hasher)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	fileID := opts.fileID
+	if fileID == uuid.Nil {
+		fileID = uuid.New()
+	}
+	obj, err := driver.Put(ctx, projectID+"/"+fileID.String()+"/"+name, r, size, fileType)
 	if err != nil {
-		return nil, fmt.Errorf("cloudinary request failed: %v", err)
+		return nil, fmt.Errorf("%s upload failed: %w", driver.Name(), err)
 	}
-	defer resp.Body.Close()
-
-	// 4. Akhri Jawaabta Cloudinary
-	var result map[string]interface{}
-	respBody, _ := io.ReadAll(resp.Body)
-	json.Unmarshal(respBody, &result)
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("cloudinary upload failed: %v", result["error"])
+	if obj.Size >= 0 {
+		size = obj.Size
+	}
+	if bucket != nil && bucket.MaxFileSize > 0 && size > bucket.MaxFileSize {
+		_ = driver.Delete(context.WithoutCancel(ctx), obj.Key)
+		return nil, fmt.Errorf("%w (%d bytes)", ErrBucketFileTooLarge, bucket.MaxFileSize)
+	}
+	checksum, err := hasher.verify(opts)
+	if err != nil {
+		_ = driver.Delete(context.WithoutCancel(ctx), obj.Key)
+		return nil, err
 	}
 
-	// Hel URL-ka rasmiga ah (Secure URL)
-	finalURL := result["secure_url"].(string)
+	// ♻️ Dedup: content-kan haddii uu horay u jiray, blob-kii hore ayaa la tixraacaa (bytes cusub ma jiraan)
+	var blobID *uuid.UUID
+	shared := false
+	if s.dedupEnabled(ctx, projectID) {
+		obj, blobID, shared = s.shareBlob(ctx, driver, projectID, checksum, obj, size)
+	}
 
-	// 5. Kaydi Metadata-ga pgAdmin dhexdiisa
 	newFile := &models.StorageFile{
-		ProjectID: projectID,
-		FileName:  fileName,
-		FileType:  fileType,
-		SizeMB:    float64(len(fileData)) / (1024 * 1024),
-		URL:       finalURL, // 👈 Hadda waa URL dhab ah!
+		ID:         fileID,
+		ProjectID:  projectID,
+		FileName:   name,
+		FileType:   fileType,
+		SizeBytes:  size,
+		SizeMB:     float64(size) / (1024 * 1024),
+		URL:        obj.URL,
+		Driver:     driver.Name(),
+		StorageKey: obj.Key,
+		Checksum:   &checksum,
+		BlobID:     blobID,
+	}
+	if opts.UploadedBy != "" {
+		uploader := opts.UploadedBy
+		newFile.UploadedBy = &uploader
+	}
+	if bucket != nil {
+		newFile.BucketID, newFile.Path = &bucket.ID, objectPath
+	}
+	newFile.AccessControl, _ = json.Marshal(acl)
+	if head != nil {
+		newFile.Metadata = head.metadata()
+	}
+	if newFile.URL == "" || acl.Visibility != models.FileVisibilityPublic {
+		// Local / S3 public_url la'aan / private: URL joogto ah ma jiro, API-ga ayaa laga soo dejiyaa
+		newFile.URL = downloadPath(projectID, fileID.String())
 	}
 
 	if err := s.repo.Create(ctx, newFile); err != nil {
+		s.releaseFileBlob(context.WithoutCancel(ctx), driver, newFile)
+		if bucket != nil && isDuplicateKey(err) {
+			return nil, ErrPathExists // Upload kale ayaa path-ka qabsaday
+		}
 		return nil, err
 	}
 
-	// Analytics
+	// Analytics + usage (limits-ka plan-ka): bytes-ka unique ah kaliya
 	s.tracker.TrackEvent(ctx, projectID, models.AnalyticsTypeStorageUsage, "files_uploaded", 1)
-	s.usageService.UpdateUsage(ctx, projectID, "storage_used_mb", newFile.SizeMB)
+	if shared {
+		s.tracker.TrackEvent(ctx, projectID, models.AnalyticsTypeStorageUsage, "files_deduplicated", 1)
+		return newFile, nil
+	}
+	s.tracker.TrackEvent(ctx, projectID, models.AnalyticsTypeStorageUsage, "total_storage_mb", newFile.SizeMB)
+	_ = s.usageService.UpdateUsage(ctx, projectID, "storage_used_mb", newFile.SizeMB)
 
 	return newFile, nil
 }
+
+// OpenFile: Soo dejinta (caller-ka ayaa xira reader-ka)
+func (s *storageService) OpenFile(ctx context.Context, projectID, fileID string) (io.ReadCloser, *models.StorageFile, *blobstore.Object, error) {
+	file, err := s.projectFile(ctx, projectID, fileID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	// 🔏 Private: bytes-ka kaliya signed URL (API key-gu kuma filna)
+	if file.RequiresSignedURL() {
+		return nil, file, nil, ErrStorageSignedURL
+	}
+	driver, err := s.driverForFile(ctx, file)
+	if err != nil {
+		return nil, file, nil, err
+	}
+	rc, obj, err := driver.Get(ctx, file.StorageKey)
+	if err != nil {
+		return nil, file, nil, err
+	}
+
+	s.tracker.TrackEvent(ctx, projectID, models.AnalyticsTypeStorageUsage, "files_read", 1)
+	return rc, file, obj, nil
+}
+
+// StatFile: Metadata-ga backend-ka (size, etag, modified) iyadoo aan la soo dejin
+func (s *storageService) StatFile(ctx context.Context, projectID, fileID string) (*models.StorageFile, *blobstore.Object, error) {
+	file, err := s.projectFile(ctx, projectID, fileID)
+	if err != nil {
+		return nil, nil, err
+	}
+	driver, err := s.driverForFile(ctx, file)
+	if err != nil {
+		return file, nil, err
+	}
+	obj, err := driver.Stat(ctx, file.StorageKey)
+	if err != nil {
+		return file, nil, err
+	}
+	return file, obj, nil
+}
+
+// safeFileName: Magaca key-ga (path separators iyo control characters waa la saaraa)
+func safeFileName(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == ".." || name == "/" {
+		return "file"
+	}
+	if len(name) > 200 {
+		ext := path.Ext(name)
+		if len(ext) > 20 {
+			ext = ""
+		}
+		name = strings.ToValidUTF8(name[:200-len(ext)], "") + ext
+	}
+	return name
+}
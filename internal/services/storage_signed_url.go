@@ -0,0 +1,271 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"superaib/internal/models"
+	"superaib/pkg/blobstore"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// =========================================================================
+// 🔏 PRIVATE FILES: SIGNED, EXPIRING URLS
+// =========================================================================
+// File-ka visibility-giisu haddii uusan "public" ahayn, bytes-ka waxaa lagu helaa kaliya:
+//
+//	GET /api/v1/storage/signed/{file_id}?expires=<unix>&sig=<hex>[&uid=<auth user>]
+//
+// sig = hex(HMAC-SHA256(STORAGE_SIGNING_SECRET, file_id + ":" + expires + ":" + uid)).
+// uid-ku (bind_user) wuxuu URL-ka ku xiraa hal auth user: codsigu waa inuu wataa token-kiisa,
+// access control-kana mar kale ayaa la hubiyaa (user-ka laga saaray allowed_users wuu waayaa).
+// Driver-ka presign taageera (S3) waxaa loo redirect gareeyaa URL toos ah; kuwa kale backend-ka ayaa stream gareeya.
+
+const (
+	defaultSignedURLTTL = 15 * time.Minute
+	maxSignedURLTTL     = 7 * 24 * time.Hour // S3 presign limit
+)
+
+// SignURLOptions: Codsiga mint-ka
+type SignURLOptions struct {
+	TTL      time.Duration
+	BindUser bool // URL-ka waxaa isticmaali kara kaliya user-ka codsaday (token-kiisa ayuu wataa)
+}
+
+// SignedFileURL: Jawaabta mint-ka (Path waa relative; handler-ka ayaa host-ka ku dara)
+type SignedFileURL struct {
+	Path      string    `json:"path"`
+	ExpiresAt time.Time `json:"expires_at"`
+	BoundUser string    `json:"bound_user,omitempty"`
+}
+
+// SignedFileRequest: Query-ga signed URL-ka + token-ka codsiga (haddii uu jiro)
+type SignedFileRequest struct {
+	FileID    string
+	Expires   int64
+	UserID    string
+	Signature string
+	Token     string
+}
+
+// FileDelivery: Body (stream) ama RedirectURL (driver presign); statOnly = Body la'aan
+type FileDelivery struct {
+	File        *models.StorageFile
+	Object      *blobstore.Object
+	Body        io.ReadCloser
+	RedirectURL string
+}
+
+func downloadPath(projectID, fileID string) string {
+	return fmt.Sprintf("/api/v1/projects/%s/storage/files/%s/download", projectID, fileID)
+}
+
+func (s *storageService) AuthenticateUser(ctx context.Context, projectID, token string) (string, error) {
+	return verifyAuthUserToken(ctx, s.authUserRepo, projectID, token)
+}
+
+// normalizeAccessControl: nil = public; allowed_users waa inay noqdaan UUIDs
+func normalizeAccessControl(acl *models.StorageAccessControl) (models.StorageAccessControl, error) {
+	if acl == nil {
+		return models.StorageAccessControl{Visibility: models.FileVisibilityPublic}, nil
+	}
+	out := models.StorageAccessControl{Visibility: acl.Visibility}
+	switch out.Visibility {
+	case "":
+		out.Visibility = models.FileVisibilityPublic
+	case models.FileVisibilityPublic, models.FileVisibilityAuthenticated, models.FileVisibilityPrivate:
+	default:
+		return out, fmt.Errorf("%w: visibility must be %q, %q or %q", ErrStorageInvalidACL, models.FileVisibilityPublic, models.FileVisibilityAuthenticated, models.FileVisibilityPrivate)
+	}
+
+	seen := map[string]bool{}
+	for _, u := range acl.AllowedUsers {
+		id, err := uuid.Parse(u)
+		if err != nil {
+			return out, fmt.Errorf("%w: allowed_users contains an invalid user id %q", ErrStorageInvalidACL, u)
+		}
+		if !seen[id.String()] {
+			seen[id.String()] = true
+			out.AllowedUsers = append(out.AllowedUsers, id.String())
+		}
+	}
+	return out, nil
+}
+
+// canRead: public = qof kasta, authenticated = auth user kasta, private = uploader-ka + allowed_users
+func canRead(file *models.StorageFile, userID string) bool {
+	acl := file.Access()
+	switch acl.Visibility {
+	case models.FileVisibilityPublic:
+		return true
+	case models.FileVisibilityAuthenticated:
+		return userID != ""
+	}
+	if userID == "" {
+		return false
+	}
+	if file.UploadedBy != nil && *file.UploadedBy == userID {
+		return true
+	}
+	for _, allowed := range acl.AllowedUsers {
+		if allowed == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// SetAccessControl: PUT /files/{id}/access — URL-ka joogtada ah waa la qariyaa marka file-ku noqdo private
+func (s *storageService) SetAccessControl(ctx context.Context, projectID, fileID string, in models.StorageAccessControl) (*models.StorageFile, error) {
+	acl, err := normalizeAccessControl(&in)
+	if err != nil {
+		return nil, err
+	}
+	file, err := s.projectFile(ctx, projectID, fileID)
+	if err != nil {
+		return nil, err
+	}
+	if file.Driver == "" && acl.Visibility != models.FileVisibilityPublic {
+		return nil, fmt.Errorf("%w: legacy files cannot be made private", ErrStorageExternalFile)
+	}
+
+	fileURL := downloadPath(projectID, file.ID.String())
+	if acl.Visibility == models.FileVisibilityPublic {
+		fileURL = file.URL
+		// Driver-ka URL dadweyne leeyahay (S3 public_url, Cloudinary) dib u soo celi
+		if driver, err := s.driverForFile(ctx, file); err == nil {
+			if obj, err := driver.Stat(ctx, file.StorageKey); err == nil && obj.URL != "" {
+				fileURL = obj.URL
+			}
+		}
+	}
+
+	raw, _ := json.Marshal(acl)
+	if err := s.repo.UpdateAccess(ctx, file.ID, raw, fileURL); err != nil {
+		return nil, err
+	}
+	file.AccessControl, file.URL = raw, fileURL
+	return file, nil
+}
+
+// SignFileURL: Hubi AccessControl-ka (userID = auth user-ka codsiga, "" haddii uusan jirin) kadib saxiix URL
+func (s *storageService) SignFileURL(ctx context.Context, projectID, fileID, userID string, opts SignURLOptions) (*SignedFileURL, error) {
+	file, err := s.projectFile(ctx, projectID, fileID)
+	if err != nil {
+		return nil, err
+	}
+	if file.Driver == "" {
+		return nil, ErrStorageExternalFile
+	}
+	if !canRead(file, userID) {
+		return nil, ErrStorageAccessDenied
+	}
+	if opts.BindUser && userID == "" {
+		return nil, fmt.Errorf("%w: bind_user requires a user token", ErrStorageAccessDenied)
+	}
+
+	ttl := opts.TTL
+	if ttl <= 0 {
+		ttl = defaultSignedURLTTL
+	}
+	if ttl > maxSignedURLTTL {
+		ttl = maxSignedURLTTL
+	}
+	expiresAt := time.Now().Add(ttl).Truncate(time.Second)
+
+	bound := ""
+	if opts.BindUser {
+		bound = userID
+	}
+	q := url.Values{}
+	q.Set("expires", strconv.FormatInt(expiresAt.Unix(), 10))
+	if bound != "" {
+		q.Set("uid", bound)
+	}
+	q.Set("sig", s.fileSignature(file.ID.String(), expiresAt.Unix(), bound))
+
+	return &SignedFileURL{
+		Path:      "/api/v1/storage/signed/" + file.ID.String() + "?" + q.Encode(),
+		ExpiresAt: expiresAt,
+		BoundUser: bound,
+	}, nil
+}
+
+func (s *storageService) fileSignature(fileID string, expires int64, userID string) string {
+	mac := hmac.New(sha256.New, s.signingKey)
+	mac.Write([]byte(fileID + ":" + strconv.FormatInt(expires, 10) + ":" + userID))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verifySignedRequest: Saxiixa + expiry (+ user-ka la xiray iyo AccessControl-ka hadda)
+func (s *storageService) verifySignedRequest(ctx context.Context, req SignedFileRequest) (*models.StorageFile, error) {
+	expected := s.fileSignature(req.FileID, req.Expires, req.UserID)
+	if !hmac.Equal([]byte(expected), []byte(req.Signature)) {
+		return nil, ErrStorageBadSignature
+	}
+	if !time.Now().Before(time.Unix(req.Expires, 0)) {
+		return nil, ErrStorageBadSignature
+	}
+
+	id, err := uuid.Parse(req.FileID)
+	if err != nil {
+		return nil, ErrStorageFileNotFound
+	}
+	file, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, ErrStorageFileNotFound
+	}
+
+	if req.UserID != "" {
+		if req.Token == "" {
+			return nil, ErrStorageAccessDenied
+		}
+		userID, err := s.AuthenticateUser(ctx, file.ProjectID, req.Token)
+		if err != nil || userID != req.UserID || !canRead(file, userID) {
+			return nil, ErrStorageAccessDenied
+		}
+	}
+	return file, nil
+}
+
+// OpenSignedFile: Xaqiiji saxiixa, kadib driver-ka presign ama stream
+func (s *storageService) OpenSignedFile(ctx context.Context, req SignedFileRequest, statOnly bool) (*FileDelivery, error) {
+	file, err := s.verifySignedRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	driver, err := s.driverForFile(ctx, file)
+	if err != nil {
+		return nil, err
+	}
+	delivery := &FileDelivery{File: file}
+
+	// Driver-ka presign: redirect toos ah (expiry-ga hadhay kaliya)
+	if presigner, ok := driver.(blobstore.Presigner); ok {
+		remaining := time.Until(time.Unix(req.Expires, 0))
+		if remaining < time.Second {
+			remaining = time.Second
+		}
+		delivery.RedirectURL, err = presigner.PresignGet(ctx, file.StorageKey, remaining)
+		return delivery, err
+	}
+
+	if statOnly {
+		delivery.Object, err = driver.Stat(ctx, file.StorageKey)
+		return delivery, err
+	}
+	delivery.Body, delivery.Object, err = driver.Get(ctx, file.StorageKey)
+	if err != nil {
+		return nil, err
+	}
+	s.tracker.TrackEvent(ctx, file.ProjectID, models.AnalyticsTypeStorageUsage, "files_read", 1)
+	return delivery, nil
+}
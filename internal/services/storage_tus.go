@@ -0,0 +1,335 @@
+package services
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"superaib/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// =========================================================================
+// ⏫ RESUMABLE UPLOADS (tus 1.0: creation, HEAD offset, PATCH chunks, expiration, termination)
+// =========================================================================
+// Chunks-ka waxaa lagu daraa staging file (<Dir>/<upload_id>.part) node-ka; marka bytes-ka oo dhan
+// la helo ayaa file-ka driver-ka project-ka loo gudbiyaa (UploadFile), kaliya markaas ayaa
+// StorageFile-ka la abuuraa oo usage-ka la xisaabiyaa. Staging-ku waa disk-ga node-ka:
+// load balancer-ku waa inuu upload kasta u diraa isla node-ka (sticky) marka replicas badan jiraan.
+
+var (
+	ErrUploadNotFound       = errors.New("upload not found")
+	ErrUploadExpired        = errors.New("upload has expired")
+	ErrUploadOffsetMismatch = errors.New("upload offset does not match")
+	ErrUploadLocked         = errors.New("upload is being written by another request")
+	ErrUploadTooLarge       = errors.New("upload exceeds the maximum size")
+)
+
+// ResumableOptions: Settings-ka tus uploads-ka (config-ka server-ka)
+type ResumableOptions struct {
+	Dir           string        // Staging directory
+	Expiry        time.Duration // Inta upload aan dhammaan la hayo chunk-kii ugu dambeeyay kadib
+	MaxSize       int64         // Bytes (Tus-Max-Size), 0 = xad la'aan
+	SweepInterval time.Duration
+}
+
+func (o ResumableOptions) withDefaults() ResumableOptions {
+	if o.Dir == "" {
+		o.Dir = filepath.Join("data", "uploads")
+	}
+	if o.Expiry <= 0 {
+		o.Expiry = 24 * time.Hour
+	}
+	if o.SweepInterval <= 0 {
+		o.SweepInterval = 10 * time.Minute
+	}
+	return o
+}
+
+// StartUploadSweeper: Tirtir uploads-ka waqtigoodu dhacay (staging file + row) ilaa ctx la joojiyo
+func (s *storageService) StartUploadSweeper(ctx context.Context, opts ResumableOptions) {
+	s.resumable = opts.withDefaults()
+
+	ticker := time.NewTicker(s.resumable.SweepInterval)
+	go func() {
+		defer ticker.Stop()
+		fmt.Println("⏫ [SYSTEM] Resumable upload sweeper is running...")
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if removed := s.sweepExpiredUploads(ctx); removed > 0 {
+				fmt.Printf("🧹 [Storage] Removed %d expired uploads\n", removed)
+			}
+		}
+	}()
+}
+
+func (s *storageService) sweepExpiredUploads(ctx context.Context) int {
+	removed := 0
+	for {
+		uploads, err := s.uploadRepo.ListExpired(ctx, time.Now(), 100)
+		if err != nil {
+			fmt.Printf("❌ [Storage] Listing expired uploads failed: %v\n", err)
+			return removed
+		}
+		for _, upload := range uploads {
+			unlock, ok := s.lockUpload(upload.ID.String())
+			if !ok {
+				continue // PATCH socda: wareegga xiga
+			}
+			_ = os.Remove(s.stagingPath(upload.ID))
+			err := s.uploadRepo.Delete(ctx, upload.ID)
+			unlock()
+			if err != nil {
+				fmt.Printf("❌ [Storage] Deleting upload %s failed: %v\n", upload.ID, err)
+				return removed
+			}
+			removed++
+		}
+		if len(uploads) < 100 {
+			return removed
+		}
+	}
+}
+
+// MaxUploadSize: Tus-Max-Size (0 = xad la'aan)
+func (s *storageService) MaxUploadSize() int64 {
+	return s.resumable.withDefaults().MaxSize
+}
+
+func (s *storageService) stagingPath(id uuid.UUID) string {
+	return filepath.Join(s.resumable.withDefaults().Dir, id.String()+".part")
+}
+
+// lockUpload: Hal PATCH kaliya hal mar (offset-ka iyo staging file-ka). Key-gu waa UUID-ga canonical-ka ah.
+func (s *storageService) lockUpload(id string) (func(), bool) {
+	if parsed, err := uuid.Parse(id); err == nil {
+		id = parsed.String()
+	}
+	if _, busy := s.uploadLocks.LoadOrStore(id, struct{}{}); busy {
+		return nil, false
+	}
+	return func() { s.uploadLocks.Delete(id) }, true
+}
+
+// CreateUpload: POST /tus (Upload-Length + Upload-Metadata). Driver-ka iyo ACL-ka hadda ayaa la hubiyaa
+// si khaladku u soo baxo ka hor inta aan bytes la dirin.
+func (s *storageService) CreateUpload(ctx context.Context, projectID string, opts UploadOptions, rawMetadata string) (*models.StorageUpload, error) {
+	if opts.Size < 0 {
+		return nil, errors.New("upload length is required")
+	}
+	if max := s.MaxUploadSize(); max > 0 && opts.Size > max {
+		return nil, ErrUploadTooLarge
+	}
+	// Bucket: MIME/size/path hadda ayaa la hubiyaa (finalize-ku mar kale ayuu hubiyaa)
+	_, objectPath, err := s.bucketTarget(ctx, projectID, &opts)
+	if err != nil {
+		return nil, err
+	}
+	acl, err := normalizeAccessControl(opts.AccessControl)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.driverFor(ctx, projectID); err != nil {
+		return nil, err
+	}
+
+	upload := &models.StorageUpload{
+		ProjectID:    projectID,
+		FileName:     safeFileName(opts.FileName),
+		FileType:     opts.FileType,
+		UploadLength: opts.Size,
+		Metadata:     rawMetadata,
+		Bucket:       opts.Bucket,
+		Path:         objectPath,
+		Checksum:     hex.EncodeToString(opts.ChecksumSHA256),
+		ExpiresAt:    time.Now().Add(s.resumable.withDefaults().Expiry),
+	}
+	if opts.UploadedBy != "" {
+		uploader := opts.UploadedBy
+		upload.UploadedBy = &uploader
+	}
+	upload.AccessControl, _ = json.Marshal(acl)
+
+	if err := os.MkdirAll(s.resumable.withDefaults().Dir, 0o755); err != nil {
+		return nil, err
+	}
+	if err := s.uploadRepo.Create(ctx, upload); err != nil {
+		return nil, err
+	}
+	// Staging file madhan: HEAD/PATCH-ka ugu horreeya waxay helayaan offset 0
+	f, err := os.OpenFile(s.stagingPath(upload.ID), os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		_ = s.uploadRepo.Delete(ctx, upload.ID)
+		return nil, err
+	}
+	f.Close()
+
+	// Zero-length: isla markiiba dhammaystir (tus: PATCH looma baahna)
+	if upload.UploadLength == 0 {
+		if err := s.finalizeUpload(ctx, upload); err != nil {
+			return upload, err
+		}
+	}
+	return upload, nil
+}
+
+// GetUpload: HEAD /tus/{id}. userID = auth user-ka codsiga (uploads-ka user leh waa kuwiisa kaliya)
+func (s *storageService) GetUpload(ctx context.Context, projectID, uploadID, userID string) (*models.StorageUpload, error) {
+	id, err := uuid.Parse(uploadID)
+	if err != nil {
+		return nil, ErrUploadNotFound
+	}
+	upload, err := s.uploadRepo.GetByID(ctx, id)
+	if err != nil || upload.ProjectID != projectID {
+		return nil, ErrUploadNotFound
+	}
+	if upload.UploadedBy != nil && *upload.UploadedBy != userID {
+		return nil, ErrStorageAccessDenied
+	}
+	if time.Now().After(upload.ExpiresAt) {
+		return nil, ErrUploadExpired
+	}
+	return upload, nil
+}
+
+// WriteUploadChunk: PATCH /tus/{id}. Offset-ku waa inuu la mid yahay kan server-ka; bytes-ka la helay
+// xitaa haddii connection-ku go'o waa la hayaa (client-ku HEAD ayuu ku ogaanayaa meesha uu ka sii wado).
+func (s *storageService) WriteUploadChunk(ctx context.Context, projectID, uploadID, userID string, offset int64, r io.Reader) (*models.StorageUpload, error) {
+	unlock, ok := s.lockUpload(uploadID)
+	if !ok {
+		return nil, ErrUploadLocked
+	}
+	defer unlock()
+
+	upload, err := s.GetUpload(ctx, projectID, uploadID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if offset != upload.UploadOffset {
+		return upload, ErrUploadOffsetMismatch
+	}
+
+	// Client-ku wuu go'i karaa: offset-ka iyo finalize-ka ha raacin ctx-ka codsiga
+	ctx = context.WithoutCancel(ctx)
+
+	if upload.UploadOffset < upload.UploadLength {
+		written, writeErr := s.appendChunk(upload, r)
+		upload.UploadOffset += written
+		upload.ExpiresAt = time.Now().Add(s.resumable.withDefaults().Expiry)
+		if err := s.uploadRepo.UpdateOffset(ctx, upload.ID, upload.UploadOffset, upload.ExpiresAt); err != nil {
+			return nil, err
+		}
+		if writeErr != nil {
+			return upload, writeErr
+		}
+	}
+
+	// Dhammaad: (ama finalize hore oo fashilmay, PATCH madhan ayaa dib u isku dayaya)
+	if upload.UploadOffset == upload.UploadLength && !upload.Completed() {
+		if err := s.finalizeUpload(ctx, upload); err != nil {
+			return upload, err
+		}
+	}
+	return upload, nil
+}
+
+// appendChunk: Staging file-ka waxaa loo gooyaa offset-ka DB-ga (qoraal crash ku go'ay) kadibna lagu daraa
+func (s *storageService) appendChunk(upload *models.StorageUpload, r io.Reader) (int64, error) {
+	f, err := os.OpenFile(s.stagingPath(upload.ID), os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	if err := f.Truncate(upload.UploadOffset); err != nil {
+		return 0, err
+	}
+	if _, err := f.Seek(upload.UploadOffset, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	written, copyErr := io.Copy(f, io.LimitReader(r, upload.UploadLength-upload.UploadOffset))
+	if err := f.Sync(); err != nil && copyErr == nil {
+		copyErr = err
+	}
+	return written, copyErr
+}
+
+// finalizeUpload: Staging file-ka → driver-ka (UploadFile: StorageFile + analytics + usage)
+func (s *storageService) finalizeUpload(ctx context.Context, upload *models.StorageUpload) error {
+	f, err := os.Open(s.stagingPath(upload.ID))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var acl models.StorageAccessControl
+	_ = json.Unmarshal(upload.AccessControl, &acl)
+	opts := UploadOptions{
+		FileName:      upload.FileName,
+		FileType:      upload.FileType,
+		Size:          upload.UploadLength,
+		AccessControl: &acl,
+		Bucket:        upload.Bucket,
+		Path:          upload.Path,
+	}
+	if upload.Checksum != "" {
+		opts.ChecksumSHA256, _ = hex.DecodeString(upload.Checksum)
+	}
+	if upload.UploadedBy != nil {
+		opts.UploadedBy = *upload.UploadedBy
+	}
+
+	// File-ka waxaa la siiyaa ID-ga upload-ka: haddii Complete-kii hore fashilmay, retry-gu file-ka jira ayuu isticmaalaa
+	// (file labaad iyo billing labaad ma jiraan)
+	opts.fileID = upload.ID
+	file, err := s.repo.GetByID(ctx, upload.ID)
+	if err != nil || file.ProjectID != upload.ProjectID {
+		file, err = s.UploadFile(ctx, upload.ProjectID, f, opts)
+		if errors.Is(err, ErrChecksumMismatch) {
+			// Bytes-ka staging-ka ayaa khaldan: dib u isku dayku wax ma beddelo → upload-ka waa la tirtiraa (tus 460)
+			f.Close()
+			_ = os.Remove(s.stagingPath(upload.ID))
+			_ = s.uploadRepo.Delete(ctx, upload.ID)
+			return err
+		}
+		if err != nil {
+			return fmt.Errorf("finalizing upload: %w", err)
+		}
+	}
+	if err := s.uploadRepo.Complete(ctx, upload.ID, file.ID); err != nil {
+		return err
+	}
+	upload.FileID = &file.ID
+
+	f.Close()
+	if err := os.Remove(s.stagingPath(upload.ID)); err != nil {
+		fmt.Printf("⚠️ [Storage] Staging file of upload %s not removed: %v\n", upload.ID, err)
+	}
+	return nil
+}
+
+// TerminateUpload: DELETE /tus/{id} (tus termination). Upload dhammaaday: row-ka kaliya (file-ku wuu sii jiraa)
+func (s *storageService) TerminateUpload(ctx context.Context, projectID, uploadID, userID string) error {
+	unlock, ok := s.lockUpload(uploadID)
+	if !ok {
+		return ErrUploadLocked
+	}
+	defer unlock()
+
+	upload, err := s.GetUpload(ctx, projectID, uploadID, userID)
+	if err != nil {
+		return err
+	}
+	_ = os.Remove(s.stagingPath(upload.ID))
+	return s.uploadRepo.Delete(ctx, upload.ID)
+}
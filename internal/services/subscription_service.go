@@ -75,14 +75,15 @@ func (s *subscriptionService) UpgradeProject(ctx context.Context, req UpgradeReq
 		err := tx.Model(&models.ProjectUsage{}).
 			Where("project_id = ?", req.ProjectID).
 			Updates(map[string]interface{}{
-				"limit_api_calls":         nextPlan.MaxApiCalls,
-				"limit_auth_users":        nextPlan.MaxAuthUsers,
-				"limit_storage_mb":        nextPlan.MaxStorageMB,
-				"limit_documents":         nextPlan.MaxDocuments,
-				"limit_notifications":     nextPlan.MaxNotifications,
-				"limit_realtime_channels": nextPlan.MaxRealtimeChannels, // 🚀 CUSUB (Fixed)
-				"limit_realtime_events":   nextPlan.MaxRealtimeEvents,   // 🚀 CUSUB (Fixed)
-				"updated_at":              gorm.Expr("NOW()"),
+				"limit_api_calls":                nextPlan.MaxApiCalls,
+				"limit_auth_users":               nextPlan.MaxAuthUsers,
+				"limit_storage_mb":               nextPlan.MaxStorageMB,
+				"limit_documents":                nextPlan.MaxDocuments,
+				"limit_notifications":            nextPlan.MaxNotifications,
+				"limit_realtime_channels":        nextPlan.MaxRealtimeChannels, // 🚀 CUSUB (Fixed)
+				"limit_realtime_events":          nextPlan.MaxRealtimeEvents,   // 🚀 CUSUB (Fixed)
+				"limit_realtime_channel_clients": nextPlan.MaxRealtimeChannelClients,
+				"updated_at":                     gorm.Expr("NOW()"),
 			}).Error
 		if err != nil {
 			return err
@@ -0,0 +1,152 @@
+package repo
+
+import (
+	"context"
+	"superaib/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type CollectionMigrationRepository interface {
+	Create(ctx context.Context, m *models.CollectionMigration) error
+	GetByID(ctx context.Context, projectID string, id uuid.UUID) (*models.CollectionMigration, error)
+
+	// 🔒 Worker writes: kaliya haddii job-ku weli "pending"/"running" yahay oo m.LeaseOwner uu haysto.
+	// ErrRecordNotFound = job-ka waa la joojiyay (Cancel) ama worker kale ayaa qaatay → worker-ku waa inuu istaagaa.
+	Checkpoint(ctx context.Context, m *models.CollectionMigration) error
+	Finish(ctx context.Context, m *models.CollectionMigration) error
+	Heartbeat(ctx context.Context, id uuid.UUID, owner string) error
+
+	// Cancel: "pending"/"running" → "cancelled" (replica kasta ha haysto; worker-kiisu checkpoint-ka xiga ayuu ku ogaadaa)
+	Cancel(ctx context.Context, projectID string, id uuid.UUID) error
+
+	// ListByCollection: Run history-ga collection gaar ah (kan ugu dambeeyay ayaa horreeya)
+	ListByCollection(ctx context.Context, projectID string, collectionID uuid.UUID) ([]models.CollectionMigration, error)
+
+	// GetInterrupted: Jobs-ka "pending"/"running" ah oo checkpoint-koodii ugu dambeeyay ka horreeyo staleBefore
+	GetInterrupted(ctx context.Context, staleBefore time.Time) ([]models.CollectionMigration, error)
+
+	// Claim: UPDATE ... RETURNING → "running". Kaliya haddii status-ku ku jiro from, ama job "pending"/"running" ah
+	// oo aan la taaban tan iyo staleBefore (worker-kiisii wuu dhintay). ErrRecordNotFound = cid kale ayaa qaatay.
+	Claim(ctx context.Context, id uuid.UUID, from []models.MigrationStatus, staleBefore time.Time, owner string) (*models.CollectionMigration, error)
+}
+
+type gormCollectionMigrationRepository struct {
+	db *gorm.DB
+}
+
+func NewCollectionMigrationRepository(db *gorm.DB) CollectionMigrationRepository {
+	return &gormCollectionMigrationRepository{db: db}
+}
+
+func (r *gormCollectionMigrationRepository) Create(ctx context.Context, m *models.CollectionMigration) error {
+	return r.db.WithContext(ctx).Create(m).Error
+}
+
+func (r *gormCollectionMigrationRepository) GetByID(ctx context.Context, projectID string, id uuid.UUID) (*models.CollectionMigration, error) {
+	var m models.CollectionMigration
+	if err := r.db.WithContext(ctx).Where("project_id = ? AND id = ?", projectID, id).First(&m).Error; err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+var activeMigrationStatuses = []models.MigrationStatus{models.MigrationPending, models.MigrationRunning}
+
+// leased: UPDATE ... WHERE id AND lease_owner AND status active; 0 rows → ErrRecordNotFound
+func (r *gormCollectionMigrationRepository) leased(ctx context.Context, id uuid.UUID, owner string, fields map[string]interface{}) error {
+	fields["updated_at"] = time.Now()
+	res := r.db.WithContext(ctx).Model(&models.CollectionMigration{}).
+		Where("id = ? AND lease_owner = ? AND status IN ?", id, owner, activeMigrationStatuses).
+		Updates(fields)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *gormCollectionMigrationRepository) Checkpoint(ctx context.Context, m *models.CollectionMigration) error {
+	return r.leased(ctx, m.ID, m.LeaseOwner, map[string]interface{}{
+		"status":     m.Status,
+		"cursor":     m.Cursor,
+		"processed":  m.Processed,
+		"modified":   m.Modified,
+		"failed":     m.Failed,
+		"preview":    m.Preview,
+		"started_at": m.StartedAt,
+	})
+}
+
+func (r *gormCollectionMigrationRepository) Finish(ctx context.Context, m *models.CollectionMigration) error {
+	return r.leased(ctx, m.ID, m.LeaseOwner, map[string]interface{}{
+		"status":        m.Status,
+		"cursor":        m.Cursor,
+		"processed":     m.Processed,
+		"modified":      m.Modified,
+		"failed":        m.Failed,
+		"preview":       m.Preview,
+		"started_at":    m.StartedAt,
+		"finished_at":   m.FinishedAt,
+		"error_message": m.ErrorMessage,
+	})
+}
+
+func (r *gormCollectionMigrationRepository) Heartbeat(ctx context.Context, id uuid.UUID, owner string) error {
+	return r.leased(ctx, id, owner, map[string]interface{}{})
+}
+
+func (r *gormCollectionMigrationRepository) Cancel(ctx context.Context, projectID string, id uuid.UUID) error {
+	now := time.Now()
+	res := r.db.WithContext(ctx).Model(&models.CollectionMigration{}).
+		Where("project_id = ? AND id = ? AND status IN ?", projectID, id, activeMigrationStatuses).
+		Updates(map[string]interface{}{"status": models.MigrationCancelled, "finished_at": now, "updated_at": now})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *gormCollectionMigrationRepository) ListByCollection(ctx context.Context, projectID string, collectionID uuid.UUID) ([]models.CollectionMigration, error) {
+	var list []models.CollectionMigration
+	err := r.db.WithContext(ctx).
+		Where("project_id = ? AND collection_id = ?", projectID, collectionID).
+		Order("created_at DESC").
+		Find(&list).Error
+	return list, err
+}
+
+func (r *gormCollectionMigrationRepository) GetInterrupted(ctx context.Context, staleBefore time.Time) ([]models.CollectionMigration, error) {
+	var list []models.CollectionMigration
+	err := r.db.WithContext(ctx).
+		Where("status IN ? AND updated_at < ?", []models.MigrationStatus{models.MigrationPending, models.MigrationRunning}, staleBefore).
+		Order("created_at ASC").
+		Find(&list).Error
+	return list, err
+}
+
+func (r *gormCollectionMigrationRepository) Claim(ctx context.Context, id uuid.UUID, from []models.MigrationStatus, staleBefore time.Time, owner string) (*models.CollectionMigration, error) {
+	if len(from) == 0 {
+		from = []models.MigrationStatus{""}
+	}
+	var rows []models.CollectionMigration
+	err := r.db.WithContext(ctx).
+		Raw(`UPDATE collection_migrations SET status = ?, lease_owner = ?, error_message = NULL, finished_at = NULL, updated_at = ?
+			WHERE id = ? AND (status IN ? OR (status IN ? AND updated_at < ?)) RETURNING *`,
+			models.MigrationRunning, owner, time.Now(), id, from, activeMigrationStatuses, staleBefore).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &rows[0], nil
+}
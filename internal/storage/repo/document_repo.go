@@ -39,6 +39,11 @@ type DocumentRepository interface {
 	GetCollectionByName(ctx context.Context, projectID, name string) (*models.Collection, error)
 	RenameCollection(ctx context.Context, projectID, collectionID, newName string) error
 	DeleteCollection(ctx context.Context, projectID, collectionID string) error
+
+	// --- DATA MIGRATIONS (Batch Scan & Rewrite) ---
+	ScanBatch(ctx context.Context, pID string, cID uuid.UUID, filters []Filter, afterID *uuid.UUID, limit int) ([]models.Document, error)
+	CountMatching(ctx context.Context, pID string, cID uuid.UUID, filters []Filter, afterID *uuid.UUID) (int64, error)
+	ReplaceData(ctx context.Context, doc *models.Document) error
 }
 
 type documentRepository struct{ db *gorm.DB }
@@ -223,3 +228,36 @@ func (r *documentRepository) DeleteCollection(ctx context.Context, projectID, co
 		return tx.Where("project_id = ? AND id = ?", projectID, collectionID).Delete(&models.Collection{}).Error
 	})
 }
+
+// 🚀 DATA MIGRATIONS: Batch-yo lagu kala socdo ID (Keyset pagination si job-ku u sii wado meeshii uu ka istaagay)
+func (r *documentRepository) ScanBatch(ctx context.Context, pID string, cID uuid.UUID, filters []Filter, afterID *uuid.UUID, limit int) ([]models.Document, error) {
+	var docs []models.Document
+	q := r.db.WithContext(ctx).Where("project_id = ? AND collection_id = ? AND is_deleted = false", pID, cID)
+	if afterID != nil {
+		q = q.Where("id > ?", *afterID)
+	}
+	for _, f := range filters {
+		q = applyFilter(q, f)
+	}
+	err := q.Order("id ASC").Limit(limit).Find(&docs).Error
+	return docs, err
+}
+
+func (r *documentRepository) CountMatching(ctx context.Context, pID string, cID uuid.UUID, filters []Filter, afterID *uuid.UUID) (int64, error) {
+	var count int64
+	q := r.db.WithContext(ctx).Model(&models.Document{}).Where("project_id = ? AND collection_id = ? AND is_deleted = false", pID, cID)
+	if afterID != nil {
+		q = q.Where("id > ?", *afterID)
+	}
+	for _, f := range filters {
+		q = applyFilter(q, f)
+	}
+	err := q.Count(&count).Error
+	return count, err
+}
+
+// ReplaceData: Ku beddel JSON-ka oo dhan (ETag cusub + Version+1)
+func (r *documentRepository) ReplaceData(ctx context.Context, doc *models.Document) error {
+	sql := `UPDATE documents SET data = ?, etag = ?, version = version + 1, updated_at = ? WHERE project_id = ? AND collection_id = ? AND id = ?`
+	return r.db.WithContext(ctx).Exec(sql, doc.Data, uuid.New().String(), time.Now(), doc.ProjectID, doc.CollectionID, doc.ID).Error
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"superaib/internal/models"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -13,6 +14,13 @@ type ProjectUsageRepository interface {
 	GetByProjectID(ctx context.Context, projectUUID string) (*models.ProjectUsage, error)
 	Update(ctx context.Context, usage *models.ProjectUsage) error
 	IncrementField(ctx context.Context, projectUUID string, field string, value interface{}) error
+
+	// TryIncrement: Kordhi field-ka kaliya haddii limit-ka aan la gaarin (atomic, -1 = unlimited)
+	TryIncrement(ctx context.Context, projectUUID string, field, limitField string, value int) (bool, error)
+	// ResetRealtimePeriod: Eber ka dhig realtime_events_count haddii bil cusub la galay
+	ResetRealtimePeriod(ctx context.Context, projectUUID string, periodStart time.Time) error
+	// BackfillRealtimeUsage: Projects-kii hore (limits 0): limits-ka plan-ka + tirada channels-ka jira
+	BackfillRealtimeUsage(ctx context.Context) error
 }
 
 type gormProjectUsageRepository struct {
@@ -46,3 +54,49 @@ func (r *gormProjectUsageRepository) IncrementField(ctx context.Context, project
 	query := fmt.Sprintf("UPDATE project_usages SET %s = %s + ?, updated_at = NOW() WHERE project_id = ?", field, field)
 	return r.db.WithContext(ctx).Exec(query, value, projectUUID).Error
 }
+
+func (r *gormProjectUsageRepository) TryIncrement(ctx context.Context, projectUUID string, field, limitField string, value int) (bool, error) {
+	query := fmt.Sprintf(
+		"UPDATE project_usages SET %s = %s + ?, updated_at = NOW() WHERE project_id = ? AND (%s = -1 OR %s + ? <= %s)",
+		field, field, limitField, field, limitField,
+	)
+	res := r.db.WithContext(ctx).Exec(query, value, projectUUID, value)
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *gormProjectUsageRepository) ResetRealtimePeriod(ctx context.Context, projectUUID string, periodStart time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.ProjectUsage{}).
+		Where("project_id = ? AND (realtime_period_start IS NULL OR realtime_period_start < ?)", projectUUID, periodStart).
+		Updates(map[string]interface{}{"realtime_events_count": 0, "realtime_period_start": periodStart}).Error
+}
+
+// BackfillRealtimeUsage: Usage rows-kii la abuuray ka hor realtime limits-ka waxay leeyihiin limit 0 (TryIncrement
+// wuu diidayaa) iyo count 0 (DeleteChannel wuxuu u dhigayaa taban). Idempotent: rows-ka saxda ah lama taabto.
+func (r *gormProjectUsageRepository) BackfillRealtimeUsage(ctx context.Context) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`
+			UPDATE project_usages u
+			SET limit_realtime_channels = p.max_realtime_channels, limit_realtime_events = p.max_realtime_events, updated_at = NOW()
+			FROM projects pr JOIN plans p ON p.id = pr.plan_id
+			WHERE pr.id = u.project_id
+				AND u.limit_realtime_channels = 0 AND u.limit_realtime_events = 0
+				AND (p.max_realtime_channels <> 0 OR p.max_realtime_events <> 0)`).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Exec(`
+			UPDATE project_usages u
+			SET limit_realtime_channel_clients = p.max_realtime_channel_clients, updated_at = NOW()
+			FROM projects pr JOIN plans p ON p.id = pr.plan_id
+			WHERE pr.id = u.project_id
+				AND u.limit_realtime_channel_clients = 0 AND p.max_realtime_channel_clients <> 0`).Error
+		if err != nil {
+			return err
+		}
+		return tx.Exec(`
+			UPDATE project_usages u
+			SET realtime_channels_count = c.total, updated_at = NOW()
+			FROM (SELECT project_id, COUNT(*) AS total FROM realtime_channels GROUP BY project_id) c
+			WHERE c.project_id = u.project_id AND u.realtime_channels_count <= 0`).Error
+	})
+}
@@ -3,9 +3,12 @@ package repo
 import (
 	"context"
 	"superaib/internal/models"
+	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/datatypes"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type RealtimeChannelRepository interface {
@@ -13,7 +16,7 @@ type RealtimeChannelRepository interface {
 	Create(ctx context.Context, channel *models.RealtimeChannel) error
 	GetByID(ctx context.Context, id uuid.UUID) (*models.RealtimeChannel, error)
 	GetAllByProject(ctx context.Context, projectID string) ([]models.RealtimeChannel, error)
-	Update(ctx context.Context, channel *models.RealtimeChannel) error
+	Update(ctx context.Context, channel *models.RealtimeChannel, columns []string) error
 	Delete(ctx context.Context, id uuid.UUID) error
 
 	// 🚀 SDK & ENTERPRISE FUNCTIONS
@@ -22,6 +25,25 @@ type RealtimeChannelRepository interface {
 
 	// UpdateConnectedCount: Kordhi ama ka dhim tirada dadka Online-ka ah (Atomic Operation)
 	UpdateConnectedCount(ctx context.Context, id uuid.UUID, change int) error
+
+	// SetConnectedCount: Ku qor tirada saxda ah ee presence-ka (dib-u-xisaabin, ma aha +1/-1)
+	SetConnectedCount(ctx context.Context, id uuid.UUID, count int) error
+
+	// NextSequence: Sequence cusub oo atomic ah (isla markaana cusboonaysii last_message_at)
+	// Waxay soo celisaa channel-ka oo dhan si retention policy-ga loo ogaado
+	NextSequence(ctx context.Context, id uuid.UUID) (*models.RealtimeChannel, error)
+
+	// 🧩 SHARED STATE
+	// UpdateState: Row-ka waa la xiraa (FOR UPDATE) inta fn ay xisaabinayso state-ka cusub si patches-ku u kala horreeyaan
+	UpdateState(ctx context.Context, id uuid.UUID, fn func(ch *models.RealtimeChannel) (datatypes.JSON, error)) (*models.RealtimeChannel, error)
+
+	// 🗄️ RETENTION & ARCHIVAL
+	// ListWithRetention: Channels-ka u baahan pruning (ephemeral, max age ama max events)
+	ListWithRetention(ctx context.Context) ([]models.RealtimeChannel, error)
+	// ListIdle: Channels aan fariin la dirin tan iyo before oo aan cidi ku jirin
+	ListIdle(ctx context.Context, before time.Time) ([]models.RealtimeChannel, error)
+	MarkArchived(ctx context.Context, id uuid.UUID, at time.Time, metadata datatypes.JSON) error
+	Unarchive(ctx context.Context, id uuid.UUID) error
 }
 
 type gormRealtimeChannelRepository struct {
@@ -69,8 +91,20 @@ func (r *gormRealtimeChannelRepository) GetAllByProject(ctx context.Context, pro
 }
 
 // 5. Update: Bedel xogta channel-ka (Metadata, Privacy, iwm)
-func (r *gormRealtimeChannelRepository) Update(ctx context.Context, channel *models.RealtimeChannel) error {
-	return r.db.WithContext(ctx).Save(channel).Error
+// Kaliya columns-ka la doortay ayaa la qoraa (Save ma aha) si sequence/presence/state-ka
+// ay isla mar beddelayaan loops-ka kale aysan dib ugu qormin. Project-kale channel-kiisa → ErrRecordNotFound
+func (r *gormRealtimeChannelRepository) Update(ctx context.Context, channel *models.RealtimeChannel, columns []string) error {
+	res := r.db.WithContext(ctx).Model(channel).
+		Where("project_id = ?", channel.ProjectID).
+		Select(columns).
+		Updates(channel)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
 }
 
 // 6. UpdateConnectedCount: 🚀 ATOMIC PRESENCE LOGIC
@@ -82,7 +116,85 @@ func (r *gormRealtimeChannelRepository) UpdateConnectedCount(ctx context.Context
 		UpdateColumn("connected_clients", gorm.Expr("connected_clients + ?", change)).Error
 }
 
+// 6b. SetConnectedCount: Presence tracker-ka ayaa xisaabiya tirada dhabta ah
+func (r *gormRealtimeChannelRepository) SetConnectedCount(ctx context.Context, id uuid.UUID, count int) error {
+	return r.db.WithContext(ctx).Model(&models.RealtimeChannel{}).
+		Where("id = ?", id).
+		UpdateColumn("connected_clients", count).Error
+}
+
+// 6c. NextSequence: UPDATE ... RETURNING si laba BROADCAST oo isku mar ah aysan u helin isla tirsiga
+func (r *gormRealtimeChannelRepository) NextSequence(ctx context.Context, id uuid.UUID) (*models.RealtimeChannel, error) {
+	var rows []models.RealtimeChannel
+	err := r.db.WithContext(ctx).
+		Raw("UPDATE realtime_channels SET last_sequence = last_sequence + 1, last_message_at = ? WHERE id = ? RETURNING *", time.Now(), id).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &rows[0], nil
+}
+
+// 6d. UpdateState: Transaction + row lock; revision-ka waa la kordhiyaa marka state-ku is beddelo
+func (r *gormRealtimeChannelRepository) UpdateState(ctx context.Context, id uuid.UUID, fn func(ch *models.RealtimeChannel) (datatypes.JSON, error)) (*models.RealtimeChannel, error) {
+	var channel models.RealtimeChannel
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&channel, "id = ?", id).Error; err != nil {
+			return err
+		}
+		state, err := fn(&channel)
+		if err != nil {
+			return err
+		}
+		channel.State = state
+		channel.StateRevision++
+		return tx.Model(&models.RealtimeChannel{}).Where("id = ?", id).Updates(map[string]interface{}{
+			"state":          state,
+			"state_revision": channel.StateRevision,
+			"mode":           channel.Mode,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &channel, nil
+}
+
 // 7. Delete: Tirtir channel-ka gabi ahaanba
 func (r *gormRealtimeChannelRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	return r.db.WithContext(ctx).Delete(&models.RealtimeChannel{}, "id = ?", id).Error
 }
+
+// 8. ListWithRetention: Pruner-ka ayaa isticmaala
+func (r *gormRealtimeChannelRepository) ListWithRetention(ctx context.Context) ([]models.RealtimeChannel, error) {
+	var channels []models.RealtimeChannel
+	err := r.db.WithContext(ctx).
+		Where("retention_policy = ? OR retention_max_age > 0 OR retention_max_events > 0", models.RetentionEphemeral).
+		Find(&channels).Error
+	return channels, err
+}
+
+// 9. ListIdle: COALESCE si channels aan weligood fariin helin loo tixgeliyo created_at
+func (r *gormRealtimeChannelRepository) ListIdle(ctx context.Context, before time.Time) ([]models.RealtimeChannel, error) {
+	var channels []models.RealtimeChannel
+	err := r.db.WithContext(ctx).
+		Where("archived = ? AND connected_clients = 0 AND COALESCE(last_message_at, created_at) < ?", false, before).
+		Find(&channels).Error
+	return channels, err
+}
+
+// 10. MarkArchived / Unarchive: UpdateColumns si aan loo taaban sequence iyo presence counters
+func (r *gormRealtimeChannelRepository) MarkArchived(ctx context.Context, id uuid.UUID, at time.Time, metadata datatypes.JSON) error {
+	return r.db.WithContext(ctx).Model(&models.RealtimeChannel{}).
+		Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{"archived": true, "archived_at": at, "metadata": metadata}).Error
+}
+
+func (r *gormRealtimeChannelRepository) Unarchive(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Model(&models.RealtimeChannel{}).
+		Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{"archived": false, "archived_at": nil}).Error
+}
@@ -3,8 +3,10 @@ package repo
 import (
 	"context"
 	"superaib/internal/models"
+	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/datatypes"
 	"gorm.io/gorm"
 )
 
@@ -22,11 +24,27 @@ type RealtimeEventRepository interface {
 	// GetAllByChannel: Dhamaan fariimaha qol gaar ah
 	GetAllByChannel(ctx context.Context, channelID uuid.UUID) ([]models.RealtimeEvent, error)
 
+	// Edit: Payload cusub + edit_count/edited_at (atomic) oo soo celi row-ga
+	Edit(ctx context.Context, id uuid.UUID, payload datatypes.JSON, at time.Time) (*models.RealtimeEvent, error)
+
 	// DeleteByChannel: Nadiifinta xogta marka channel la tirtiro
 	DeleteByChannel(ctx context.Context, channelID uuid.UUID) error
 
 	// CountByChannel: Analytics (Immisa fariin ayaa qolkan dhex martay?)
 	CountByChannel(ctx context.Context, channelID uuid.UUID) (int64, error)
+
+	// --- 📬 GUARANTEED DELIVERY ---
+	// GetAfterSequence: Replay-ga fariimaha la seegay (reconnect + last_event_id)
+	GetAfterSequence(ctx context.Context, channelID uuid.UUID, afterSeq int64, limit int) ([]models.RealtimeEvent, error)
+	MarkDelivered(ctx context.Context, id uuid.UUID, at time.Time) error
+	IncrementRetries(ctx context.Context, id uuid.UUID) error
+
+	// --- 🗄️ RETENTION ---
+	DeleteOlderThan(ctx context.Context, channelID uuid.UUID, before time.Time) (int64, error)
+	// TrimToCount: Hay kaliya `keep` fariimood ee ugu dambeeyay
+	TrimToCount(ctx context.Context, channelID uuid.UUID, keep int) (int64, error)
+	// StreamByChannel: Fariimaha channel-ka (tartib ahaan) mid-mid, si export-ku aan xusuusta u buuxin
+	StreamByChannel(ctx context.Context, channelID uuid.UUID, fn func(*models.RealtimeEvent) error) error
 }
 
 type gormRealtimeEventRepository struct {
@@ -83,6 +101,22 @@ func (r *gormRealtimeEventRepository) Update(ctx context.Context, event *models.
 	return r.db.WithContext(ctx).Save(event).Error
 }
 
+// Edit: Kordhi edit_count isla query-ga si labo edit oo isku mar ah aysan isu dul marin
+func (r *gormRealtimeEventRepository) Edit(ctx context.Context, id uuid.UUID, payload datatypes.JSON, at time.Time) (*models.RealtimeEvent, error) {
+	var rows []models.RealtimeEvent
+	err := r.db.WithContext(ctx).
+		Raw(`UPDATE realtime_events SET payload = ?, edit_count = edit_count + 1, edited_at = ?, updated_at = ?
+			WHERE id = ? RETURNING *`, payload, at, at, id).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &rows[0], nil
+}
+
 // 6. Delete: Tirtir hal fariin
 func (r *gormRealtimeEventRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	return r.db.WithContext(ctx).Delete(&models.RealtimeEvent{}, "id = ?", id).Error
@@ -104,3 +138,77 @@ func (r *gormRealtimeEventRepository) CountByChannel(ctx context.Context, channe
 		Count(&count).Error
 	return count, err
 }
+
+// 9. GetAfterSequence: Fariimaha ka dambeeyay sequence gaar ah (tartibka saxda ah)
+func (r *gormRealtimeEventRepository) GetAfterSequence(ctx context.Context, channelID uuid.UUID, afterSeq int64, limit int) ([]models.RealtimeEvent, error) {
+	var events []models.RealtimeEvent
+	if limit <= 0 {
+		limit = 500
+	}
+	err := r.db.WithContext(ctx).
+		Where("channel_id = ? AND sequence > ?", channelID, afterSeq).
+		Order("sequence ASC").
+		Limit(limit).
+		Find(&events).Error
+	return events, err
+}
+
+// 10. MarkDelivered: ACK-gii ugu horreeyay ayaa go'aamiya delivery_timestamp
+func (r *gormRealtimeEventRepository) MarkDelivered(ctx context.Context, id uuid.UUID, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.RealtimeEvent{}).
+		Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{
+			"delivered":          true,
+			"delivery_timestamp": gorm.Expr("COALESCE(delivery_timestamp, ?)", at),
+		}).Error
+}
+
+// 11. IncrementRetries: Redelivery kasta waa la tiriyaa
+func (r *gormRealtimeEventRepository) IncrementRetries(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Model(&models.RealtimeEvent{}).
+		Where("id = ?", id).
+		UpdateColumn("retries", gorm.Expr("retries + 1")).Error
+}
+
+// 12. DeleteOlderThan: Max age pruning
+func (r *gormRealtimeEventRepository) DeleteOlderThan(ctx context.Context, channelID uuid.UUID, before time.Time) (int64, error) {
+	res := r.db.WithContext(ctx).
+		Where("channel_id = ? AND created_at < ?", channelID, before).
+		Delete(&models.RealtimeEvent{})
+	return res.RowsAffected, res.Error
+}
+
+// 13. TrimToCount: Max count pruning (kuwa ugu da'da weyn ayaa baxa)
+func (r *gormRealtimeEventRepository) TrimToCount(ctx context.Context, channelID uuid.UUID, keep int) (int64, error) {
+	res := r.db.WithContext(ctx).Exec(`
+		DELETE FROM realtime_events WHERE id IN (
+			SELECT id FROM realtime_events
+			WHERE channel_id = ?
+			ORDER BY sequence DESC, created_at DESC
+			OFFSET ?
+		)`, channelID, keep)
+	return res.RowsAffected, res.Error
+}
+
+// 14. StreamByChannel: Rows() cursor
+func (r *gormRealtimeEventRepository) StreamByChannel(ctx context.Context, channelID uuid.UUID, fn func(*models.RealtimeEvent) error) error {
+	rows, err := r.db.WithContext(ctx).Model(&models.RealtimeEvent{}).
+		Where("channel_id = ?", channelID).
+		Order("sequence ASC, created_at ASC").
+		Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var event models.RealtimeEvent
+		if err := r.db.ScanRows(rows, &event); err != nil {
+			return err
+		}
+		if err := fn(&event); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,71 @@
+package repo
+
+import (
+	"context"
+	"superaib/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type RealtimeModerationRepository interface {
+	Create(ctx context.Context, m *models.RealtimeModeration) error
+	Delete(ctx context.Context, projectID string, id uuid.UUID) (*models.RealtimeModeration, error)
+
+	// ListActive: Bans/mutes aan dhicin; channelID nil = dhamaan project-ka
+	ListActive(ctx context.Context, projectID string, channelID *uuid.UUID, now time.Time) ([]models.RealtimeModeration, error)
+
+	// ActiveForUser: Kuwa saameeya user-ka channel-kan (project-wide + channel-kan)
+	ActiveForUser(ctx context.Context, projectID, userID string, channelID *uuid.UUID, now time.Time) ([]models.RealtimeModeration, error)
+}
+
+type gormRealtimeModerationRepository struct {
+	db *gorm.DB
+}
+
+func NewGormRealtimeModerationRepository(db *gorm.DB) RealtimeModerationRepository {
+	return &gormRealtimeModerationRepository{db: db}
+}
+
+func (r *gormRealtimeModerationRepository) Create(ctx context.Context, m *models.RealtimeModeration) error {
+	return r.db.WithContext(ctx).Create(m).Error
+}
+
+func (r *gormRealtimeModerationRepository) Delete(ctx context.Context, projectID string, id uuid.UUID) (*models.RealtimeModeration, error) {
+	var rows []models.RealtimeModeration
+	err := r.db.WithContext(ctx).
+		Raw("DELETE FROM realtime_moderations WHERE project_id = ? AND id = ? RETURNING *", projectID, id).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &rows[0], nil
+}
+
+func (r *gormRealtimeModerationRepository) ListActive(ctx context.Context, projectID string, channelID *uuid.UUID, now time.Time) ([]models.RealtimeModeration, error) {
+	var rows []models.RealtimeModeration
+	q := r.db.WithContext(ctx).
+		Where("project_id = ? AND (expires_at IS NULL OR expires_at > ?)", projectID, now)
+	if channelID != nil {
+		q = q.Where("channel_id = ? OR channel_id IS NULL", *channelID)
+	}
+	err := q.Order("created_at DESC").Find(&rows).Error
+	return rows, err
+}
+
+func (r *gormRealtimeModerationRepository) ActiveForUser(ctx context.Context, projectID, userID string, channelID *uuid.UUID, now time.Time) ([]models.RealtimeModeration, error) {
+	var rows []models.RealtimeModeration
+	q := r.db.WithContext(ctx).
+		Where("project_id = ? AND user_id = ? AND (expires_at IS NULL OR expires_at > ?)", projectID, userID, now)
+	if channelID != nil {
+		q = q.Where("channel_id IS NULL OR channel_id = ?", *channelID)
+	} else {
+		q = q.Where("channel_id IS NULL")
+	}
+	err := q.Find(&rows).Error
+	return rows, err
+}
@@ -0,0 +1,164 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"superaib/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrPresenceFull: Channel-ku wuxuu gaaray maxConnections (Join)
+var ErrPresenceFull = errors.New("channel is full")
+
+type RealtimePresenceRepository interface {
+	// Join: Capacity check + upsert hal lock channel kasta gudaheed (maxConnections 0 = xad la'aan).
+	// first = tab-ka ugu horreeya ee presence_key-ga (presence_join hal mar kaliya)
+	Join(ctx context.Context, p *models.RealtimePresence, maxConnections int) (first bool, err error)
+	// Leave: Remove + inta tabs ka haray isla lock-ka; last = tab-kii ugu dambeeyay (presence_leave)
+	Leave(ctx context.Context, channelID uuid.UUID, connectionID string) (p *models.RealtimePresence, last bool, err error)
+	RemoveByKey(ctx context.Context, channelID uuid.UUID, presenceKey string) ([]models.RealtimePresence, error)
+
+	// CountByKey: Immisa connection ayuu user-kani ku leeyahay channel-ka (tabs badan)
+	CountByKey(ctx context.Context, channelID uuid.UUID, presenceKey string) (int64, error)
+	CountMembers(ctx context.Context, channelID uuid.UUID) (int64, error)
+	ListMembers(ctx context.Context, channelID uuid.UUID) ([]models.PresenceMember, error)
+
+	// 💓 HEARTBEAT & EVICTION
+	Touch(ctx context.Context, connectionIDs []string, now time.Time) error
+	EvictStale(ctx context.Context, before time.Time) ([]models.RealtimePresence, error)
+}
+
+type gormRealtimePresenceRepository struct {
+	db *gorm.DB
+}
+
+func NewGormRealtimePresenceRepository(db *gorm.DB) RealtimePresenceRepository {
+	return &gormRealtimePresenceRepository{db: db}
+}
+
+// lockChannel: Transaction-level advisory lock: joins/leaves isku channel ah way kala horreeyaan
+// (row-ka realtime_channels lama xiro si events-ka last_sequence-ka aysan u sugin)
+func lockChannel(tx *gorm.DB, channelID uuid.UUID) error {
+	return tx.Exec("SELECT pg_advisory_xact_lock(hashtextextended(?, 0))", "realtime_presence:"+channelID.String()).Error
+}
+
+func (r *gormRealtimePresenceRepository) Join(ctx context.Context, p *models.RealtimePresence, maxConnections int) (bool, error) {
+	var before int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockChannel(tx, p.ChannelID); err != nil {
+			return err
+		}
+		if maxConnections > 0 {
+			var others int64
+			err := tx.Model(&models.RealtimePresence{}).
+				Where("channel_id = ? AND connection_id <> ?", p.ChannelID, p.ConnectionID).
+				Count(&others).Error
+			if err != nil {
+				return err
+			}
+			if others >= int64(maxConnections) {
+				return ErrPresenceFull
+			}
+		}
+		err := tx.Model(&models.RealtimePresence{}).
+			Where("channel_id = ? AND presence_key = ?", p.ChannelID, p.PresenceKey).
+			Count(&before).Error
+		if err != nil {
+			return err
+		}
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "channel_id"}, {Name: "connection_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"metadata", "last_seen_at", "node_id"}),
+		}).Create(p).Error
+	})
+	return before == 0, err
+}
+
+func (r *gormRealtimePresenceRepository) Leave(ctx context.Context, channelID uuid.UUID, connectionID string) (*models.RealtimePresence, bool, error) {
+	var (
+		removed   *models.RealtimePresence
+		remaining int64
+	)
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockChannel(tx, channelID); err != nil {
+			return err
+		}
+		var rows []models.RealtimePresence
+		err := tx.Raw("DELETE FROM realtime_presences WHERE channel_id = ? AND connection_id = ? RETURNING *", channelID, connectionID).
+			Scan(&rows).Error
+		if err != nil || len(rows) == 0 {
+			return err
+		}
+		removed = &rows[0]
+		return tx.Model(&models.RealtimePresence{}).
+			Where("channel_id = ? AND presence_key = ?", channelID, removed.PresenceKey).
+			Count(&remaining).Error
+	})
+	if err != nil || removed == nil {
+		return nil, false, err
+	}
+	return removed, remaining == 0, nil
+}
+
+func (r *gormRealtimePresenceRepository) RemoveByKey(ctx context.Context, channelID uuid.UUID, presenceKey string) ([]models.RealtimePresence, error) {
+	var rows []models.RealtimePresence
+	err := r.db.WithContext(ctx).
+		Raw("DELETE FROM realtime_presences WHERE channel_id = ? AND presence_key = ? RETURNING *", channelID, presenceKey).
+		Scan(&rows).Error
+	return rows, err
+}
+
+func (r *gormRealtimePresenceRepository) CountByKey(ctx context.Context, channelID uuid.UUID, presenceKey string) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.RealtimePresence{}).
+		Where("channel_id = ? AND presence_key = ?", channelID, presenceKey).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *gormRealtimePresenceRepository) CountMembers(ctx context.Context, channelID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.RealtimePresence{}).
+		Where("channel_id = ?", channelID).
+		Distinct("presence_key").
+		Count(&count).Error
+	return count, err
+}
+
+// ListMembers: Hal row qof kasta; metadata-da waxaa laga qaadaa connection-kii ugu dambeeyay
+func (r *gormRealtimePresenceRepository) ListMembers(ctx context.Context, channelID uuid.UUID) ([]models.PresenceMember, error) {
+	var members []models.PresenceMember
+	err := r.db.WithContext(ctx).Raw(`
+		SELECT DISTINCT ON (presence_key)
+			presence_key, user_id, metadata,
+			COUNT(*) OVER (PARTITION BY presence_key) AS connections,
+			MIN(joined_at) OVER (PARTITION BY presence_key) AS joined_at,
+			MAX(last_seen_at) OVER (PARTITION BY presence_key) AS last_seen_at
+		FROM realtime_presences
+		WHERE channel_id = ?
+		ORDER BY presence_key, joined_at DESC`, channelID).
+		Scan(&members).Error
+	return members, err
+}
+
+func (r *gormRealtimePresenceRepository) Touch(ctx context.Context, connectionIDs []string, now time.Time) error {
+	if len(connectionIDs) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Model(&models.RealtimePresence{}).
+		Where("connection_id IN ?", connectionIDs).
+		UpdateColumn("last_seen_at", now).Error
+}
+
+// EvictStale: Tirtir connections-ka aan heartbeat soo dirin (sockets dhintay ama node burburay)
+func (r *gormRealtimePresenceRepository) EvictStale(ctx context.Context, before time.Time) ([]models.RealtimePresence, error) {
+	var rows []models.RealtimePresence
+	err := r.db.WithContext(ctx).
+		Raw("DELETE FROM realtime_presences WHERE last_seen_at < ? RETURNING *", before).
+		Scan(&rows).Error
+	return rows, err
+}
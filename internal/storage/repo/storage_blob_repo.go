@@ -0,0 +1,66 @@
+package repo
+
+import (
+	"context"
+	"superaib/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// StorageBlobRepository: Blobs-ka la wadaago iyo reference counts-kooda
+type StorageBlobRepository interface {
+	Create(ctx context.Context, blob *models.StorageBlob) error
+	Acquire(ctx context.Context, projectID, driver, checksum string) (*models.StorageBlob, error)
+	Release(ctx context.Context, projectID string, id uuid.UUID) (*models.StorageBlob, bool, error)
+}
+
+type GormStorageBlobRepository struct {
+	db *gorm.DB
+}
+
+func NewGormStorageBlobRepository(db *gorm.DB) StorageBlobRepository {
+	return &GormStorageBlobRepository{db: db}
+}
+
+func (r *GormStorageBlobRepository) Create(ctx context.Context, blob *models.StorageBlob) error {
+	return r.db.WithContext(ctx).Create(blob).Error
+}
+
+// Acquire: ref_count + 1 ee blob-ka content-kan (ErrRecordNotFound haddii aanu jirin ama la sii daayay)
+func (r *GormStorageBlobRepository) Acquire(ctx context.Context, projectID, driver, checksum string) (*models.StorageBlob, error) {
+	var blob models.StorageBlob
+	res := r.db.WithContext(ctx).Model(&blob).Clauses(clause.Returning{}).
+		Where("project_id = ? AND driver = ? AND checksum = ? AND ref_count > 0", projectID, driver, checksum).
+		Update("ref_count", gorm.Expr("ref_count + 1"))
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &blob, nil
+}
+
+// Release: ref_count - 1. last = tixraacii ugu dambeeyay: row-ka waa la tirtiraa (Acquire ma soo celiyo
+// blob ref_count-kiisu 0 yahay), caller-ka ayaana tirtira blob-ka driver-ka. Blob project kale leeyahay = ErrRecordNotFound.
+func (r *GormStorageBlobRepository) Release(ctx context.Context, projectID string, id uuid.UUID) (*models.StorageBlob, bool, error) {
+	var blob models.StorageBlob
+	res := r.db.WithContext(ctx).Model(&blob).Clauses(clause.Returning{}).
+		Where("id = ? AND project_id = ? AND ref_count > 0", id, projectID).
+		Update("ref_count", gorm.Expr("ref_count - 1"))
+	if res.Error != nil {
+		return nil, false, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, false, gorm.ErrRecordNotFound
+	}
+	if blob.RefCount > 0 {
+		return &blob, false, nil
+	}
+	if err := r.db.WithContext(ctx).Delete(&models.StorageBlob{}, "id = ? AND project_id = ? AND ref_count = 0", id, projectID).Error; err != nil {
+		return &blob, true, err
+	}
+	return &blob, true, nil
+}
@@ -0,0 +1,68 @@
+package repo
+
+import (
+	"context"
+	"superaib/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// StorageBucketRepository: Buckets-ka storage-ka project kasta
+type StorageBucketRepository interface {
+	Create(ctx context.Context, bucket *models.StorageBucket) error
+	GetByName(ctx context.Context, projectID, name string) (*models.StorageBucket, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*models.StorageBucket, error)
+	ListByProject(ctx context.Context, projectID string) ([]models.StorageBucket, error)
+	Update(ctx context.Context, bucket *models.StorageBucket) error
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+type GormStorageBucketRepository struct {
+	db *gorm.DB
+}
+
+func NewGormStorageBucketRepository(db *gorm.DB) StorageBucketRepository {
+	return &GormStorageBucketRepository{db: db}
+}
+
+func (r *GormStorageBucketRepository) Create(ctx context.Context, bucket *models.StorageBucket) error {
+	return r.db.WithContext(ctx).Create(bucket).Error
+}
+
+func (r *GormStorageBucketRepository) GetByName(ctx context.Context, projectID, name string) (*models.StorageBucket, error) {
+	var bucket models.StorageBucket
+	if err := r.db.WithContext(ctx).First(&bucket, "project_id = ? AND name = ?", projectID, name).Error; err != nil {
+		return nil, err
+	}
+	return &bucket, nil
+}
+
+func (r *GormStorageBucketRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.StorageBucket, error) {
+	var bucket models.StorageBucket
+	if err := r.db.WithContext(ctx).First(&bucket, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &bucket, nil
+}
+
+func (r *GormStorageBucketRepository) ListByProject(ctx context.Context, projectID string) ([]models.StorageBucket, error) {
+	var buckets []models.StorageBucket
+	err := r.db.WithContext(ctx).Where("project_id = ?", projectID).Order("name ASC").Find(&buckets).Error
+	return buckets, err
+}
+
+// Update: Settings-ka kaliya (magaca iyo project-ka lama beddelo)
+func (r *GormStorageBucketRepository) Update(ctx context.Context, bucket *models.StorageBucket) error {
+	return r.db.WithContext(ctx).Model(&models.StorageBucket{}).Where("id = ?", bucket.ID).
+		Updates(map[string]interface{}{
+			"visibility":         bucket.Visibility,
+			"allowed_mime_types": bucket.AllowedMimeTypes,
+			"max_file_size":      bucket.MaxFileSize,
+			"allowed_prefixes":   bucket.AllowedPrefixes,
+		}).Error
+}
+
+func (r *GormStorageBucketRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Delete(&models.StorageBucket{}, "id = ?", id).Error
+}
@@ -2,9 +2,12 @@ package repo
 
 import (
 	"context"
+	"strings"
 	"superaib/internal/models"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
+	"gorm.io/datatypes"
 	"gorm.io/gorm"
 )
 
@@ -13,6 +16,21 @@ type StorageRepository interface {
 	GetByProject(ctx context.Context, projectID string, page, pageSize int) ([]models.StorageFile, int64, error)
 	GetByID(ctx context.Context, id uuid.UUID) (*models.StorageFile, error)
 	Delete(ctx context.Context, id uuid.UUID) error
+	UpdateAccess(ctx context.Context, id uuid.UUID, accessControl datatypes.JSON, url string) error
+	AddVariant(ctx context.Context, id uuid.UUID, name, key string, size int64) (bool, error)
+
+	// 🗂️ Buckets (path-ka gudaha bucket-ka)
+	GetByPath(ctx context.Context, bucketID uuid.UUID, path string) (*models.StorageFile, error)
+	GetByPaths(ctx context.Context, bucketID uuid.UUID, paths []string) ([]models.StorageFile, error)
+	ListBucketEntries(ctx context.Context, bucketID uuid.UUID, prefix, delimiter, after string, limit int) ([]BucketEntry, error)
+	CountByBucket(ctx context.Context, bucketID uuid.UUID) (int64, error)
+	Move(ctx context.Context, id uuid.UUID, bucketID uuid.UUID, path, fileName string) error
+}
+
+// BucketEntry: Hal element oo ka mid ah liiska bucket-ka: file (Path) ama folder (prefix + delimiter)
+type BucketEntry struct {
+	Path     string
+	IsPrefix bool
 }
 
 type GormStorageRepository struct {
@@ -56,3 +74,94 @@ func (r *GormStorageRepository) Delete(ctx context.Context, id uuid.UUID) error
 	// GORM's soft delete will be used because of `gorm.DeletedAt` in the model
 	return r.db.WithContext(ctx).Delete(&models.StorageFile{}, "id = ?", id).Error
 }
+
+// UpdateAccess: Beddel visibility-ga (URL-ka joogtada ah waa la beddelaa marka file-ku noqdo private)
+func (r *GormStorageRepository) UpdateAccess(ctx context.Context, id uuid.UUID, accessControl datatypes.JSON, url string) error {
+	return r.db.WithContext(ctx).Model(&models.StorageFile{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"access_control": accessControl, "url": url}).Error
+}
+
+// AddVariant: metadata.variants[name] = key + metadata.variant_bytes += size (jsonb merge si aysan requests
+// isku mar ahi isugu tirtirin). added = false haddii variant-ka horay loo diiwaangeliyay (bytes-ka mar kaliya ayaa la xisaabiyaa).
+func (r *GormStorageRepository) AddVariant(ctx context.Context, id uuid.UUID, name, key string, size int64) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&models.StorageFile{}).
+		Where("id = ? AND (metadata->'variants'->>?) IS NULL", id, name).
+		Update("metadata", gorm.Expr(
+			`COALESCE(metadata, '{}'::jsonb) || jsonb_build_object(
+				'variants', COALESCE(metadata->'variants', '{}'::jsonb) || jsonb_build_object(?::text, ?::text),
+				'variant_bytes', COALESCE((metadata->>'variant_bytes')::bigint, 0) + ?::bigint)`,
+			name, key, size))
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *GormStorageRepository) GetByPath(ctx context.Context, bucketID uuid.UUID, path string) (*models.StorageFile, error) {
+	var file models.StorageFile
+	if err := r.db.WithContext(ctx).First(&file, "bucket_id = ? AND path = ?", bucketID, path).Error; err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+func (r *GormStorageRepository) GetByPaths(ctx context.Context, bucketID uuid.UUID, paths []string) ([]models.StorageFile, error) {
+	var files []models.StorageFile
+	if len(paths) == 0 {
+		return files, nil
+	}
+	err := r.db.WithContext(ctx).Where("bucket_id = ? AND path IN ?", bucketID, paths).Find(&files).Error
+	return files, err
+}
+
+// ListBucketEntries: Liiska S3-ka oo kale (prefix/delimiter). Files-ka hoos yaal folder (prefix + qayb + delimiter)
+// waxay isu uruuraan hal entry; bogagga waxaa lagu kala qaadaa "after" (entry-gii ugu dambeeyay, byte order).
+func (r *GormStorageRepository) ListBucketEntries(ctx context.Context, bucketID uuid.UUID, prefix, delimiter, after string, limit int) ([]BucketEntry, error) {
+	like := likeEscaper.Replace(prefix) + "%"
+	var entries []BucketEntry
+
+	if delimiter == "" {
+		err := r.db.WithContext(ctx).Model(&models.StorageFile{}).
+			Select("path").
+			Where(`bucket_id = ? AND path LIKE ? ESCAPE '\' AND path COLLATE "C" > ?`, bucketID, like, after).
+			Order(`path COLLATE "C"`).Limit(limit).
+			Scan(&entries).Error
+		return entries, err
+	}
+
+	// substr/strpos waxay tiriyaan characters (ma aha bytes)
+	args := map[string]interface{}{
+		"bucket": bucketID,
+		"like":   like,
+		"prefix": prefix,
+		"start":  utf8.RuneCountInString(prefix) + 1,
+		"delim":  delimiter,
+		"after":  after,
+		"limit":  limit,
+	}
+	err := r.db.WithContext(ctx).Raw(`
+		SELECT path, bool_or(is_prefix) AS is_prefix FROM (
+			SELECT CASE WHEN strpos(substr(path, @start), @delim) > 0
+					THEN @prefix || split_part(substr(path, @start), @delim, 1) || @delim
+					ELSE path END AS path,
+				strpos(substr(path, @start), @delim) > 0 AS is_prefix
+			FROM storage_files
+			WHERE bucket_id = @bucket AND deleted_at IS NULL AND path LIKE @like ESCAPE '\'
+		) entries
+		WHERE path COLLATE "C" > @after
+		GROUP BY path
+		ORDER BY path COLLATE "C"
+		LIMIT @limit`, args).Scan(&entries).Error
+	return entries, err
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (r *GormStorageRepository) CountByBucket(ctx context.Context, bucketID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.StorageFile{}).Where("bucket_id = ?", bucketID).Count(&count).Error
+	return count, err
+}
+
+// Move: Bucket/path/magaca kaliya (blob-ka driver-ka meeshiisa ayuu joogaa)
+func (r *GormStorageRepository) Move(ctx context.Context, id uuid.UUID, bucketID uuid.UUID, path, fileName string) error {
+	return r.db.WithContext(ctx).Model(&models.StorageFile{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"bucket_id": bucketID, "path": path, "file_name": fileName}).Error
+}
@@ -0,0 +1,62 @@
+package repo
+
+import (
+	"context"
+	"superaib/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// StorageUploadRepository: Sessions-ka tus uploads-ka
+type StorageUploadRepository interface {
+	Create(ctx context.Context, upload *models.StorageUpload) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.StorageUpload, error)
+	UpdateOffset(ctx context.Context, id uuid.UUID, offset int64, expiresAt time.Time) error
+	Complete(ctx context.Context, id uuid.UUID, fileID uuid.UUID) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	ListExpired(ctx context.Context, before time.Time, limit int) ([]models.StorageUpload, error)
+}
+
+type GormStorageUploadRepository struct {
+	db *gorm.DB
+}
+
+func NewGormStorageUploadRepository(db *gorm.DB) StorageUploadRepository {
+	return &GormStorageUploadRepository{db: db}
+}
+
+func (r *GormStorageUploadRepository) Create(ctx context.Context, upload *models.StorageUpload) error {
+	return r.db.WithContext(ctx).Create(upload).Error
+}
+
+func (r *GormStorageUploadRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.StorageUpload, error) {
+	var upload models.StorageUpload
+	if err := r.db.WithContext(ctx).First(&upload, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &upload, nil
+}
+
+func (r *GormStorageUploadRepository) UpdateOffset(ctx context.Context, id uuid.UUID, offset int64, expiresAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.StorageUpload{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"upload_offset": offset, "expires_at": expiresAt}).Error
+}
+
+func (r *GormStorageUploadRepository) Complete(ctx context.Context, id uuid.UUID, fileID uuid.UUID) error {
+	return r.db.WithContext(ctx).Model(&models.StorageUpload{}).Where("id = ?", id).
+		Update("file_id", fileID).Error
+}
+
+func (r *GormStorageUploadRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Delete(&models.StorageUpload{}, "id = ?", id).Error
+}
+
+// ListExpired: Uploads-ka waqtigoodu dhacay (kuwa dhammaaday iyo kuwa la dayacay)
+func (r *GormStorageUploadRepository) ListExpired(ctx context.Context, before time.Time, limit int) ([]models.StorageUpload, error) {
+	var uploads []models.StorageUpload
+	err := r.db.WithContext(ctx).Where("expires_at < ?", before).
+		Order("expires_at ASC").Limit(limit).Find(&uploads).Error
+	return uploads, err
+}
@@ -0,0 +1,276 @@
+package blobstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	cloudinaryAPIBase      = "https://api.cloudinary.com/v1_1/"
+	cloudinaryDeliveryBase = "https://res.cloudinary.com/"
+)
+
+// CloudinaryDriver uploads through the "auto" endpoint, so images, videos and
+// raw files (PDF, zip...) are all accepted. The stored key is the delivery path
+// "<resource_type>/upload/<public_id>[.<format>]", which is what Get/Stat fetch
+// and what Delete needs to find the resource type again.
+//
+// Uploads are signed when api_key/api_secret are configured, otherwise the
+// unsigned upload_preset is used. Delete always needs the API credentials.
+type CloudinaryDriver struct {
+	cloudName    string
+	uploadPreset string
+	apiKey       string
+	apiSecret    string
+	client       *http.Client
+}
+
+func NewCloudinaryDriver(cfg Config, client *http.Client) (*CloudinaryDriver, error) {
+	if cfg.CloudName == "" {
+		return nil, errors.New("cloudinary config (cloud_name) is missing")
+	}
+	if cfg.UploadPreset == "" && (cfg.APIKey == "" || cfg.APISecret == "") {
+		return nil, errors.New("cloudinary config requires upload_preset or api_key/api_secret")
+	}
+	return &CloudinaryDriver{
+		cloudName:    cfg.CloudName,
+		uploadPreset: cfg.UploadPreset,
+		apiKey:       cfg.APIKey,
+		apiSecret:    cfg.APISecret,
+		client:       client,
+	}, nil
+}
+
+func (d *CloudinaryDriver) Name() string { return DriverCloudinary }
+
+func (d *CloudinaryDriver) signed() bool { return d.apiKey != "" && d.apiSecret != "" }
+
+func (d *CloudinaryDriver) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) (*Object, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+	params := map[string]string{"public_id": strings.TrimSuffix(key, path.Ext(key))}
+	if d.uploadPreset != "" {
+		params["upload_preset"] = d.uploadPreset
+	}
+	if d.signed() {
+		d.sign(params)
+	}
+
+	// Stream the multipart body through a pipe so the file is never held in memory
+	pr, pw := io.Pipe()
+	writer := multipart.NewWriter(pw)
+	go func() {
+		for name, value := range params {
+			if err := writer.WriteField(name, value); err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+		}
+		part, err := writer.CreateFormFile("file", path.Base(key))
+		if err == nil {
+			_, err = io.Copy(part, r)
+		}
+		if err == nil {
+			err = writer.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cloudinaryAPIBase+d.cloudName+"/auto/upload", pr)
+	if err != nil {
+		pr.Close()
+		return nil, err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	var result struct {
+		SecureURL    string    `json:"secure_url"`
+		PublicID     string    `json:"public_id"`
+		ResourceType string    `json:"resource_type"`
+		Format       string    `json:"format"`
+		Bytes        int64     `json:"bytes"`
+		Etag         string    `json:"etag"`
+		CreatedAt    time.Time `json:"created_at"`
+	}
+	if err := d.call(req, &result); err != nil {
+		pr.Close()
+		return nil, err
+	}
+
+	storedKey := result.ResourceType + "/upload/" + result.PublicID
+	if result.Format != "" && result.ResourceType != "raw" {
+		storedKey += "." + result.Format
+	}
+	return &Object{
+		Key:         storedKey,
+		Size:        result.Bytes,
+		ContentType: contentType,
+		ETag:        result.Etag,
+		URL:         result.SecureURL,
+		ModifiedAt:  result.CreatedAt,
+	}, nil
+}
+
+func (d *CloudinaryDriver) deliveryURL(key string) (string, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return cloudinaryDeliveryBase + d.cloudName + "/" + (&url.URL{Path: key}).EscapedPath(), nil
+}
+
+func (d *CloudinaryDriver) fetch(ctx context.Context, method, key string) (*http.Response, error) {
+	u, err := d.deliveryURL(key)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cloudinary request failed: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("cloudinary delivery failed: %s", resp.Status)
+	}
+	return resp, nil
+}
+
+func (d *CloudinaryDriver) Get(ctx context.Context, key string) (io.ReadCloser, *Object, error) {
+	resp, err := d.fetch(ctx, http.MethodGet, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp.Body, d.objectFromHeaders(key, resp), nil
+}
+
+func (d *CloudinaryDriver) Stat(ctx context.Context, key string) (*Object, error) {
+	resp, err := d.fetch(ctx, http.MethodHead, key)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return d.objectFromHeaders(key, resp), nil
+}
+
+func (d *CloudinaryDriver) Delete(ctx context.Context, key string) error {
+	if !d.signed() {
+		return errors.New("cloudinary delete requires api_key and api_secret")
+	}
+	key, err := CleanKey(key)
+	if err != nil {
+		return err
+	}
+	resourceType, rest, ok := strings.Cut(key, "/upload/")
+	if !ok {
+		return ErrInvalidKey
+	}
+	publicID := rest
+	if resourceType != "raw" {
+		publicID = strings.TrimSuffix(rest, path.Ext(rest))
+	}
+
+	params := map[string]string{"public_id": publicID, "invalidate": "true"}
+	d.sign(params)
+	form := url.Values{}
+	for name, value := range params {
+		form.Set(name, value)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		cloudinaryAPIBase+d.cloudName+"/"+resourceType+"/destroy", strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var result struct {
+		Result string `json:"result"`
+	}
+	if err := d.call(req, &result); err != nil {
+		return err
+	}
+	if result.Result != "ok" && result.Result != "not found" {
+		return fmt.Errorf("cloudinary delete failed: %s", result.Result)
+	}
+	return nil
+}
+
+// sign adds api_key, timestamp and signature (SHA-1 of the sorted params + api_secret).
+func (d *CloudinaryDriver) sign(params map[string]string) {
+	params["timestamp"] = strconv.FormatInt(time.Now().Unix(), 10)
+
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte('&')
+		}
+		b.WriteString(name + "=" + params[name])
+	}
+	sum := sha1.Sum([]byte(b.String() + d.apiSecret))
+
+	params["signature"] = hex.EncodeToString(sum[:])
+	params["api_key"] = d.apiKey
+}
+
+func (d *CloudinaryDriver) call(req *http.Request, out interface{}) error {
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("cloudinary request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode != http.StatusOK {
+		var failure struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		_ = json.Unmarshal(body, &failure)
+		if failure.Error.Message == "" {
+			failure.Error.Message = resp.Status
+		}
+		return fmt.Errorf("cloudinary request failed: %s", failure.Error.Message)
+	}
+	return json.NewDecoder(bytes.NewReader(body)).Decode(out)
+}
+
+func (d *CloudinaryDriver) objectFromHeaders(key string, resp *http.Response) *Object {
+	modified, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+	u, _ := d.deliveryURL(key)
+	return &Object{
+		Key:         key,
+		Size:        resp.ContentLength,
+		ContentType: resp.Header.Get("Content-Type"),
+		ETag:        strings.Trim(resp.Header.Get("ETag"), `"`),
+		URL:         u,
+		ModifiedAt:  modified,
+	}
+}
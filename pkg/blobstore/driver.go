@@ -0,0 +1,142 @@
+package blobstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// Driver names accepted in the storage feature config ("driver").
+const (
+	DriverLocal      = "local"
+	DriverS3         = "s3"
+	DriverCloudinary = "cloudinary"
+)
+
+var (
+	ErrNotFound   = errors.New("blobstore: object not found")
+	ErrInvalidKey = errors.New("blobstore: invalid object key")
+)
+
+// Object describes a stored blob.
+type Object struct {
+	Key         string    // Driver key; Put may rewrite it (e.g. Cloudinary adds resource type and format)
+	Size        int64     // Bytes, -1 when unknown
+	ContentType string    // MIME type reported by the backend
+	ETag        string    // Backend version tag, when available
+	URL         string    // Public URL, empty when the object is only reachable through the API
+	ModifiedAt  time.Time // Zero when unknown
+}
+
+// Driver is a storage backend. Keys are slash-separated relative paths
+// ("<project>/<file>/<name>"); drivers never see absolute paths or "..".
+type Driver interface {
+	Name() string
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) (*Object, error)
+	Get(ctx context.Context, key string) (io.ReadCloser, *Object, error)
+	Stat(ctx context.Context, key string) (*Object, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Presigner is implemented by drivers that can hand out short-lived direct
+// URLs, so private downloads skip the API server.
+type Presigner interface {
+	PresignGet(ctx context.Context, key string, expires time.Duration) (string, error)
+}
+
+// Config is the storage ProjectFeature.Config. The driver is picked by "driver";
+// legacy configs that only carry cloud_name/upload_preset keep using Cloudinary,
+// and an empty config falls back to the local disk so uploads work offline.
+// secret_access_key and api_secret are server-only: feature responses omit them
+// (see models.RedactFeatureConfig).
+//
+//	{"driver": "local"}
+//	{"driver": "s3", "endpoint": "http://localhost:9000", "region": "us-east-1", "bucket": "files",
+//	 "access_key_id": "...", "secret_access_key": "...", "path_style": true, "public_url": ""}
+//	{"driver": "cloudinary", "cloud_name": "...", "upload_preset": "...", "api_key": "...", "api_secret": "..."}
+type Config struct {
+	Driver string `json:"driver"`
+
+	// S3-compatible (AWS, MinIO, R2, Spaces...)
+	Endpoint        string `json:"endpoint"`
+	Region          string `json:"region"`
+	Bucket          string `json:"bucket"`
+	AccessKeyID     string `json:"access_key_id"`
+	SecretAccessKey string `json:"secret_access_key"`
+	PathStyle       bool   `json:"path_style"`
+	PublicURL       string `json:"public_url"` // CDN / public bucket base; objects are private when empty
+
+	// Cloudinary
+	CloudName    string `json:"cloud_name"`
+	UploadPreset string `json:"upload_preset"`
+	APIKey       string `json:"api_key"`
+	APISecret    string `json:"api_secret"`
+}
+
+// ParseConfig decodes a feature config. Unknown fields (e.g. required_fields) are ignored.
+func ParseConfig(raw []byte) (Config, error) {
+	var cfg Config
+	if len(raw) == 0 {
+		return cfg, nil
+	}
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return cfg, fmt.Errorf("invalid storage configuration: %w", err)
+	}
+	cfg.Driver = strings.ToLower(strings.TrimSpace(cfg.Driver))
+	return cfg, nil
+}
+
+// DriverName resolves the effective driver for cfg.
+func (c Config) DriverName() string {
+	if c.Driver != "" {
+		return c.Driver
+	}
+	if c.CloudName != "" {
+		return DriverCloudinary
+	}
+	return DriverLocal
+}
+
+// Options are server-wide settings shared by every project.
+type Options struct {
+	LocalRoot  string       // Base directory of the local driver
+	HTTPClient *http.Client // Used by the S3 and Cloudinary drivers
+}
+
+// Open builds the driver described by cfg.
+func Open(cfg Config, opts Options) (Driver, error) {
+	client := opts.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Minute}
+	}
+
+	switch cfg.DriverName() {
+	case DriverLocal:
+		return NewLocalDriver(opts.LocalRoot)
+	case DriverS3:
+		return NewS3Driver(cfg, client)
+	case DriverCloudinary:
+		return NewCloudinaryDriver(cfg, client)
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", cfg.Driver)
+	}
+}
+
+// CleanKey validates a key and returns its canonical form.
+func CleanKey(key string) (string, error) {
+	if key == "" || strings.ContainsRune(key, 0) || strings.Contains(key, "\\") {
+		return "", ErrInvalidKey
+	}
+	for _, seg := range strings.Split(key, "/") {
+		if seg == "" || seg == "." || seg == ".." {
+			return "", ErrInvalidKey
+		}
+	}
+	return path.Clean(key), nil
+}
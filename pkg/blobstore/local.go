@@ -0,0 +1,151 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// LocalDriver stores objects as plain files under Root. Writes go to a temp
+// file in the same directory and are renamed into place, so readers never see
+// a partial object.
+type LocalDriver struct {
+	Root string
+}
+
+func NewLocalDriver(root string) (*LocalDriver, error) {
+	if root == "" {
+		root = "data/storage"
+	}
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(abs, 0o755); err != nil {
+		return nil, fmt.Errorf("local storage root: %w", err)
+	}
+	return &LocalDriver{Root: abs}, nil
+}
+
+func (d *LocalDriver) Name() string { return DriverLocal }
+
+func (d *LocalDriver) path(key string) (string, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(d.Root, filepath.FromSlash(key)), nil
+}
+
+func (d *LocalDriver) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) (*Object, error) {
+	p, err := d.path(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return nil, err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".upload-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name()) // no-op after a successful rename
+
+	n, err := io.Copy(tmp, readerWithContext(ctx, r))
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+	if size >= 0 && n != size {
+		return nil, fmt.Errorf("blobstore: short write (%d of %d bytes)", n, size)
+	}
+	if err := os.Rename(tmp.Name(), p); err != nil {
+		return nil, err
+	}
+	return d.Stat(ctx, key)
+}
+
+func (d *LocalDriver) Get(ctx context.Context, key string) (io.ReadCloser, *Object, error) {
+	p, err := d.path(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, nil, mapFSError(err)
+	}
+	obj, err := d.Stat(ctx, key)
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return f, obj, nil
+}
+
+func (d *LocalDriver) Stat(ctx context.Context, key string) (*Object, error) {
+	p, err := d.path(key)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(p)
+	if err != nil {
+		return nil, mapFSError(err)
+	}
+	if info.IsDir() {
+		return nil, ErrNotFound
+	}
+	return &Object{
+		Key:         key,
+		Size:        info.Size(),
+		ContentType: mime.TypeByExtension(path.Ext(key)),
+		ETag:        fmt.Sprintf("%x-%x", info.ModTime().UnixNano(), info.Size()),
+		ModifiedAt:  info.ModTime(),
+	}, nil
+}
+
+func (d *LocalDriver) Delete(ctx context.Context, key string) error {
+	p, err := d.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	// Remove now-empty parent directories up to Root (best effort)
+	for dir := filepath.Dir(p); dir != d.Root && len(dir) > len(d.Root); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+	return nil
+}
+
+func mapFSError(err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// readerWithContext stops a copy when ctx is cancelled (client went away mid-upload).
+func readerWithContext(ctx context.Context, r io.Reader) io.Reader {
+	return readerFunc(func(p []byte) (int, error) {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		return r.Read(p)
+	})
+}
+
+type readerFunc func(p []byte) (int, error)
+
+func (f readerFunc) Read(p []byte) (int, error) { return f(p) }
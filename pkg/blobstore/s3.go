@@ -0,0 +1,223 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// S3Driver talks to any S3-compatible API (AWS, MinIO, R2, Spaces) with
+// hand-rolled SigV4 signing. Uploads are streamed with UNSIGNED-PAYLOAD, so the
+// body is never buffered; TLS protects integrity on https endpoints.
+type S3Driver struct {
+	endpoint  *url.URL
+	bucket    string
+	pathStyle bool
+	publicURL string
+	signer    sigV4Signer
+	client    *http.Client
+}
+
+func NewS3Driver(cfg Config, client *http.Client) (*S3Driver, error) {
+	if cfg.Bucket == "" || cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, errors.New("s3 config requires bucket, access_key_id and secret_access_key")
+	}
+	region := cfg.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = "https://s3." + region + ".amazonaws.com"
+	}
+	u, err := url.Parse(strings.TrimRight(endpoint, "/"))
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("invalid s3 endpoint %q", cfg.Endpoint)
+	}
+
+	return &S3Driver{
+		endpoint:  u,
+		bucket:    cfg.Bucket,
+		pathStyle: cfg.PathStyle,
+		publicURL: strings.TrimRight(cfg.PublicURL, "/"),
+		signer: sigV4Signer{
+			accessKey: cfg.AccessKeyID,
+			secretKey: cfg.SecretAccessKey,
+			region:    region,
+			service:   "s3",
+		},
+		client: client,
+	}, nil
+}
+
+func (d *S3Driver) Name() string { return DriverS3 }
+
+// objectURL returns the path-style (endpoint/bucket/key, MinIO) or virtual-hosted (bucket.endpoint/key) URL.
+func (d *S3Driver) objectURL(key string) *url.URL {
+	u := *d.endpoint
+	if d.pathStyle {
+		u.Path = u.Path + "/" + d.bucket + "/" + key
+	} else {
+		u.Host = d.bucket + "." + u.Host
+		u.Path = u.Path + "/" + key
+	}
+	u.RawPath = ""
+	return &u
+}
+
+func (d *S3Driver) do(ctx context.Context, method, key string, body io.Reader, size int64, header http.Header) (*http.Response, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, d.objectURL(key).String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	payloadHash := emptyPayloadHash
+	if body != nil {
+		payloadHash = unsignedPayload
+		req.ContentLength = size
+	}
+	d.signer.sign(req, payloadHash, time.Now())
+	return d.client.Do(req)
+}
+
+func (d *S3Driver) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) (*Object, error) {
+	if size < 0 {
+		return nil, errors.New("s3 upload requires a known size")
+	}
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	body := io.NopCloser(r)
+	if size == 0 {
+		body = http.NoBody
+	}
+	resp, err := d.do(ctx, http.MethodPut, key, body, size, header)
+	if err != nil {
+		return nil, fmt.Errorf("s3 request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, s3Error(resp)
+	}
+	return &Object{
+		Key:         key,
+		Size:        size,
+		ContentType: contentType,
+		ETag:        strings.Trim(resp.Header.Get("ETag"), `"`),
+		URL:         d.publicObjectURL(key),
+		ModifiedAt:  time.Now(),
+	}, nil
+}
+
+func (d *S3Driver) Get(ctx context.Context, key string) (io.ReadCloser, *Object, error) {
+	resp, err := d.do(ctx, http.MethodGet, key, nil, 0, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("s3 request failed: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, nil, s3Error(resp)
+	}
+	return resp.Body, d.objectFromHeaders(key, resp), nil
+}
+
+func (d *S3Driver) Stat(ctx context.Context, key string) (*Object, error) {
+	resp, err := d.do(ctx, http.MethodHead, key, nil, 0, nil)
+	if err != nil {
+		return nil, fmt.Errorf("s3 request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, s3Error(resp)
+	}
+	return d.objectFromHeaders(key, resp), nil
+}
+
+func (d *S3Driver) Delete(ctx context.Context, key string) error {
+	resp, err := d.do(ctx, http.MethodDelete, key, nil, 0, nil)
+	if err != nil {
+		return fmt.Errorf("s3 request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	// S3 returns 204 even when the key never existed
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return s3Error(resp)
+	}
+	return nil
+}
+
+// maxPresignExpiry: SigV4 query authentication is limited to 7 days
+const maxPresignExpiry = 7 * 24 * time.Hour
+
+func (d *S3Driver) PresignGet(ctx context.Context, key string, expires time.Duration) (string, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	if expires <= 0 || expires > maxPresignExpiry {
+		return "", fmt.Errorf("presign expiry must be between 1s and %s", maxPresignExpiry)
+	}
+	return d.signer.presign(http.MethodGet, d.objectURL(key), expires, time.Now()), nil
+}
+
+func (d *S3Driver) objectFromHeaders(key string, resp *http.Response) *Object {
+	size, err := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64)
+	if err != nil {
+		size = -1
+	}
+	modified, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+	return &Object{
+		Key:         key,
+		Size:        size,
+		ContentType: resp.Header.Get("Content-Type"),
+		ETag:        strings.Trim(resp.Header.Get("ETag"), `"`),
+		URL:         d.publicObjectURL(key),
+		ModifiedAt:  modified,
+	}
+}
+
+func (d *S3Driver) publicObjectURL(key string) string {
+	if d.publicURL == "" {
+		return ""
+	}
+	return d.publicURL + "/" + (&url.URL{Path: key}).EscapedPath()
+}
+
+// s3Error: <Error><Code>..</Code><Message>..</Message></Error> (HEAD has no body)
+func s3Error(resp *http.Response) error {
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	code := between(string(body), "<Code>", "</Code>")
+	msg := between(string(body), "<Message>", "</Message>")
+	if code == "" {
+		code = resp.Status
+	}
+	return fmt.Errorf("s3 %s: %s", code, msg)
+}
+
+func between(s, start, end string) string {
+	i := strings.Index(s, start)
+	if i < 0 {
+		return ""
+	}
+	s = s[i+len(start):]
+	if j := strings.Index(s, end); j >= 0 {
+		return s[:j]
+	}
+	return ""
+}
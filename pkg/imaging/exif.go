@@ -0,0 +1,167 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strings"
+)
+
+// EXIF holds the subset of EXIF tags the storage service records. GPS and
+// maker notes are deliberately skipped so uploads do not leak locations.
+type EXIF struct {
+	Orientation      int    `json:"orientation,omitempty"`  // 1..8, 0 when absent
+	Make             string `json:"make,omitempty"`         // Camera manufacturer
+	Model            string `json:"model,omitempty"`        // Camera model
+	Software         string `json:"software,omitempty"`     // Editing software
+	DateTimeOriginal string `json:"taken_at,omitempty"`     // "YYYY:MM:DD HH:MM:SS" as written by the camera
+	PixelWidth       int    `json:"pixel_width,omitempty"`  // PixelXDimension
+	PixelHeight      int    `json:"pixel_height,omitempty"` // PixelYDimension
+}
+
+var errInvalidEXIF = errors.New("imaging: invalid EXIF data")
+
+const (
+	tagOrientation      = 0x0112
+	tagMake             = 0x010F
+	tagModel            = 0x0110
+	tagSoftware         = 0x0131
+	tagExifIFD          = 0x8769
+	tagDateTimeOriginal = 0x9003
+	tagPixelXDimension  = 0xA002
+	tagPixelYDimension  = 0xA003
+
+	tiffShort = 3
+	tiffLong  = 4
+	tiffASCII = 2
+)
+
+// ParseEXIF decodes a TIFF-structured EXIF block (the payload of a JPEG APP1
+// segment after "Exif\x00\x00", a PNG eXIf chunk or a WebP EXIF chunk).
+func ParseEXIF(b []byte) (*EXIF, error) {
+	b = bytes.TrimPrefix(b, []byte("Exif\x00\x00"))
+	if len(b) < 8 {
+		return nil, errInvalidEXIF
+	}
+	var order binary.ByteOrder
+	switch string(b[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil, errInvalidEXIF
+	}
+	if order.Uint16(b[2:4]) != 42 {
+		return nil, errInvalidEXIF
+	}
+
+	t := tiffReader{b: b, order: order}
+	exif := &EXIF{}
+	exifIFD := uint32(0)
+	err := t.walk(order.Uint32(b[4:8]), func(tag, typ uint16, count uint32, value []byte) {
+		switch tag {
+		case tagOrientation:
+			exif.Orientation = int(t.uint(typ, value))
+		case tagMake:
+			exif.Make = t.ascii(typ, value)
+		case tagModel:
+			exif.Model = t.ascii(typ, value)
+		case tagSoftware:
+			exif.Software = t.ascii(typ, value)
+		case tagExifIFD:
+			exifIFD = t.uint(typ, value)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if exifIFD != 0 {
+		// A broken sub-IFD should not discard the orientation found in IFD0
+		_ = t.walk(exifIFD, func(tag, typ uint16, count uint32, value []byte) {
+			switch tag {
+			case tagDateTimeOriginal:
+				exif.DateTimeOriginal = t.ascii(typ, value)
+			case tagPixelXDimension:
+				exif.PixelWidth = int(t.uint(typ, value))
+			case tagPixelYDimension:
+				exif.PixelHeight = int(t.uint(typ, value))
+			}
+		})
+	}
+	if exif.Orientation < 1 || exif.Orientation > 8 {
+		exif.Orientation = 0
+	}
+	return exif, nil
+}
+
+type tiffReader struct {
+	b     []byte
+	order binary.ByteOrder
+}
+
+// walk calls fn for every entry of the IFD at offset; value holds the
+// entry's data, whether inline or out of line.
+func (t tiffReader) walk(offset uint32, fn func(tag, typ uint16, count uint32, value []byte)) error {
+	if uint64(offset)+2 > uint64(len(t.b)) {
+		return errInvalidEXIF
+	}
+	n := int(t.order.Uint16(t.b[offset:]))
+	entries := int(offset) + 2
+	if entries+n*12 > len(t.b) {
+		return errInvalidEXIF
+	}
+	for i := 0; i < n; i++ {
+		e := t.b[entries+i*12 : entries+(i+1)*12]
+		tag, typ, count := t.order.Uint16(e[0:]), t.order.Uint16(e[2:]), t.order.Uint32(e[4:])
+
+		size := uint64(count) * uint64(typeSize(typ))
+		if size == 0 {
+			continue
+		}
+		value := e[8:12]
+		if size > 4 {
+			start := uint64(t.order.Uint32(e[8:]))
+			if start+size > uint64(len(t.b)) {
+				continue
+			}
+			value = t.b[start : start+size]
+		}
+		fn(tag, typ, count, value[:min(size, uint64(len(value)))])
+	}
+	return nil
+}
+
+func (t tiffReader) uint(typ uint16, value []byte) uint32 {
+	switch {
+	case typ == tiffShort && len(value) >= 2:
+		return uint32(t.order.Uint16(value))
+	case typ == tiffLong && len(value) >= 4:
+		return t.order.Uint32(value)
+	}
+	return 0
+}
+
+func (t tiffReader) ascii(typ uint16, value []byte) string {
+	if typ != tiffASCII {
+		return ""
+	}
+	if i := bytes.IndexByte(value, 0); i >= 0 {
+		value = value[:i]
+	}
+	return strings.TrimSpace(strings.ToValidUTF8(string(value), ""))
+}
+
+func typeSize(typ uint16) int {
+	switch typ {
+	case 1, 2, 6, 7: // BYTE, ASCII, SBYTE, UNDEFINED
+		return 1
+	case 3, 8: // SHORT, SSHORT
+		return 2
+	case 4, 9, 11: // LONG, SLONG, FLOAT
+		return 4
+	case 5, 10, 12: // RATIONAL, SRATIONAL, DOUBLE
+		return 8
+	}
+	return 0
+}
@@ -0,0 +1,234 @@
+// Package imaging resizes, crops, orients and re-encodes images using the
+// standard library (image/jpeg, image/png, image/gif) plus the WebP decoder
+// from golang.org/x/image.
+//
+// WebP output is encoded losslessly by this package (see webp.go); there is
+// no pure-Go lossy VP8 encoder, so the quality setting does not apply to it.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif" // register the GIF decoder
+	"image/jpeg"
+	"image/png"
+	"io"
+	"math"
+
+	_ "golang.org/x/image/webp" // register the WebP decoder
+)
+
+// Output formats.
+const (
+	FormatJPEG = "jpeg"
+	FormatPNG  = "png"
+	FormatWebP = "webp"
+)
+
+// Fit modes used when both Width and Height are set.
+const (
+	FitCover   = "cover"   // Fill the box, cropping the overflow (default)
+	FitContain = "contain" // Fit inside the box, keeping the aspect ratio; never enlarges
+	FitFill    = "fill"    // Stretch to the exact box
+)
+
+// Gravity picks the kept region when FitCover crops.
+const (
+	GravityCenter = "center"
+	GravityTop    = "top"
+	GravityBottom = "bottom"
+	GravityLeft   = "left"
+	GravityRight  = "right"
+)
+
+const DefaultQuality = 80
+
+var (
+	ErrUnsupportedFormat = errors.New("imaging: unsupported image format")
+	ErrTooLarge          = errors.New("imaging: image dimensions exceed the limit")
+)
+
+// Options describe a transformation. A zero Width or Height is derived from
+// the other one; both zero keeps the original size.
+type Options struct {
+	Width, Height int
+	Fit           string
+	Gravity       string
+}
+
+// Decode decodes a JPEG, PNG, GIF or WebP after checking that it has at most
+// maxPixels pixels (0 disables the check), so a small file cannot expand
+// into gigabytes of memory.
+func Decode(data []byte, maxPixels int) (image.Image, string, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", ErrUnsupportedFormat
+	}
+	if maxPixels > 0 && cfg.Width*cfg.Height > maxPixels {
+		return nil, format, ErrTooLarge
+	}
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, format, err
+	}
+	return img, format, nil
+}
+
+// OutputFormat resolves the encoder for a requested format: "" keeps the
+// source format (GIF becomes PNG). Anything but JPEG, PNG or WebP returns
+// ErrUnsupportedFormat.
+func OutputFormat(requested, source string) (string, error) {
+	switch requested {
+	case FormatJPEG, "jpg":
+		return FormatJPEG, nil
+	case FormatPNG:
+		return FormatPNG, nil
+	case FormatWebP:
+		return FormatWebP, nil
+	case "":
+		if source == FormatJPEG || source == FormatWebP {
+			return source, nil
+		}
+		return FormatPNG, nil
+	}
+	return "", ErrUnsupportedFormat
+}
+
+// Encode writes img as JPEG (quality 1-100, 0 = DefaultQuality), PNG or
+// lossless WebP. Transparent pixels are flattened onto white for JPEG.
+func Encode(w io.Writer, img image.Image, format string, quality int) error {
+	switch format {
+	case FormatJPEG:
+		if quality <= 0 || quality > 100 {
+			quality = DefaultQuality
+		}
+		if !opaque(img) {
+			flat := image.NewRGBA(img.Bounds())
+			draw.Draw(flat, flat.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+			draw.Draw(flat, flat.Bounds(), img, img.Bounds().Min, draw.Over)
+			img = flat
+		}
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+	case FormatPNG:
+		return (&png.Encoder{CompressionLevel: png.BestSpeed}).Encode(w, img)
+	case FormatWebP:
+		return encodeWebP(w, img)
+	}
+	return ErrUnsupportedFormat
+}
+
+func opaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	return false
+}
+
+// Transform orients src according to its EXIF orientation and applies opts.
+func Transform(src image.Image, orientation int, opts Options) *image.RGBA {
+	img := Orient(toRGBA(src), orientation)
+	sw, sh := img.Bounds().Dx(), img.Bounds().Dy()
+	w, h := opts.Width, opts.Height
+
+	switch {
+	case w == 0 && h == 0:
+		return img
+	case h == 0 || w == 0:
+		// One side given: keep the aspect ratio, never enlarge
+		if w == 0 {
+			w = int(math.Round(float64(sw) * float64(h) / float64(sh)))
+		} else {
+			h = int(math.Round(float64(sh) * float64(w) / float64(sw)))
+		}
+		if w >= sw || h >= sh {
+			return img
+		}
+		return Resize(img, max(w, 1), max(h, 1))
+	}
+
+	switch opts.Fit {
+	case FitFill:
+		return Resize(img, w, h)
+	case FitContain:
+		scale := math.Min(1, math.Min(float64(w)/float64(sw), float64(h)/float64(sh)))
+		if scale == 1 {
+			return img
+		}
+		return Resize(img, max(1, int(math.Round(float64(sw)*scale))), max(1, int(math.Round(float64(sh)*scale))))
+	default:
+		scale := math.Max(float64(w)/float64(sw), float64(h)/float64(sh))
+		cw := min(sw, max(1, int(math.Round(float64(w)/scale))))
+		ch := min(sh, max(1, int(math.Round(float64(h)/scale))))
+		x, y := (sw-cw)/2, (sh-ch)/2
+		switch opts.Gravity {
+		case GravityTop:
+			y = 0
+		case GravityBottom:
+			y = sh - ch
+		case GravityLeft:
+			x = 0
+		case GravityRight:
+			x = sw - cw
+		}
+		o := img.Bounds().Min
+		crop := img.SubImage(image.Rect(o.X+x, o.Y+y, o.X+x+cw, o.Y+y+ch)).(*image.RGBA)
+		return Resize(crop, w, h)
+	}
+}
+
+func toRGBA(src image.Image) *image.RGBA {
+	if rgba, ok := src.(*image.RGBA); ok {
+		return rgba
+	}
+	b := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), src, b.Min, draw.Src)
+	return dst
+}
+
+// Orient rotates and flips img so an EXIF orientation of 2..8 displays
+// upright; other values return img unchanged.
+func Orient(img *image.RGBA, orientation int) *image.RGBA {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+	b := img.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	dw, dh := sw, sh
+	if orientation >= 5 {
+		dw, dh = sh, sw
+	}
+
+	// source maps a destination pixel to the source pixel shown there
+	var source func(x, y int) (int, int)
+	switch orientation {
+	case 2: // mirrored horizontally
+		source = func(x, y int) (int, int) { return sw - 1 - x, y }
+	case 3: // rotated 180°
+		source = func(x, y int) (int, int) { return sw - 1 - x, sh - 1 - y }
+	case 4: // mirrored vertically
+		source = func(x, y int) (int, int) { return x, sh - 1 - y }
+	case 5: // transposed
+		source = func(x, y int) (int, int) { return y, x }
+	case 6: // needs 90° clockwise
+		source = func(x, y int) (int, int) { return y, sh - 1 - x }
+	case 7: // transversed
+		source = func(x, y int) (int, int) { return sw - 1 - y, sh - 1 - x }
+	case 8: // needs 90° counter-clockwise
+		source = func(x, y int) (int, int) { return sw - 1 - y, x }
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			sx, sy := source(x, y)
+			si := img.PixOffset(b.Min.X+sx, b.Min.Y+sy)
+			di := dst.PixOffset(x, y)
+			copy(dst.Pix[di:di+4], img.Pix[si:si+4])
+		}
+	}
+	return dst
+}
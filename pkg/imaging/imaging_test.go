@@ -0,0 +1,61 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestTransformSize(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 400, 200))
+
+	tests := []struct {
+		name        string
+		orientation int
+		opts        Options
+		w, h        int
+	}{
+		{"original", 1, Options{}, 400, 200},
+		{"width only", 1, Options{Width: 100}, 100, 50},
+		{"height only", 1, Options{Height: 50}, 100, 50},
+		{"never enlarges", 1, Options{Width: 800}, 400, 200},
+		{"cover", 1, Options{Width: 100, Height: 100}, 100, 100},
+		{"fill", 1, Options{Width: 100, Height: 30, Fit: FitFill}, 100, 30},
+		{"contain", 1, Options{Width: 100, Height: 100, Fit: FitContain}, 100, 50},
+		{"contain never enlarges", 1, Options{Width: 800, Height: 800, Fit: FitContain}, 400, 200},
+		{"rotated before resize", 6, Options{Width: 100}, 100, 200},
+		{"mirrored keeps size", 2, Options{}, 400, 200},
+	}
+	for _, tt := range tests {
+		b := Transform(src, tt.orientation, tt.opts).Bounds()
+		if b.Dx() != tt.w || b.Dy() != tt.h {
+			t.Errorf("%s: got %dx%d, want %dx%d", tt.name, b.Dx(), b.Dy(), tt.w, tt.h)
+		}
+	}
+}
+
+func TestOrient(t *testing.T) {
+	// 2x1: red on the left, blue on the right
+	red, blue := color.RGBA{255, 0, 0, 255}, color.RGBA{0, 0, 255, 255}
+	src := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	src.SetRGBA(0, 0, red)
+	src.SetRGBA(1, 0, blue)
+
+	tests := []struct {
+		orientation int
+		topLeft     color.RGBA
+		w, h        int
+	}{
+		{1, red, 2, 1},
+		{2, blue, 2, 1},
+		{3, blue, 2, 1},
+		{6, red, 1, 2},
+		{8, blue, 1, 2},
+	}
+	for _, tt := range tests {
+		got := Orient(src, tt.orientation)
+		if got.Bounds().Dx() != tt.w || got.Bounds().Dy() != tt.h || got.RGBAAt(0, 0) != tt.topLeft {
+			t.Errorf("orientation %d: %v top-left %v, want %dx%d top-left %v", tt.orientation, got.Bounds(), got.RGBAAt(0, 0), tt.w, tt.h, tt.topLeft)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+)
+
+// Info describes an encoded image without decoding its pixels.
+type Info struct {
+	Format      string `json:"format"`      // jpeg, png, gif or webp
+	Width       int    `json:"width"`       // Display width (EXIF orientation applied)
+	Height      int    `json:"height"`      // Display height (EXIF orientation applied)
+	Orientation int    `json:"orientation"` // EXIF orientation, 1 when absent
+	EXIF        *EXIF  `json:"exif,omitempty"`
+}
+
+// ProbeBytes is how much of a file Probe needs: EXIF segments are capped at
+// 64 KiB and every supported format stores its dimensions before the pixels.
+const ProbeBytes = 256 << 10
+
+// Probe reads the format, dimensions and EXIF block from the head of an
+// image file (at most ProbeBytes are needed).
+func Probe(head []byte) (*Info, error) {
+	info := &Info{Orientation: 1}
+	var exifBlock []byte
+
+	switch {
+	case bytes.HasPrefix(head, []byte("RIFF")) && len(head) >= 12 && string(head[8:12]) == "WEBP":
+		w, h, block, err := probeWebP(head)
+		if err != nil {
+			return nil, err
+		}
+		info.Format, info.Width, info.Height, exifBlock = "webp", w, h, block
+	default:
+		cfg, format, err := image.DecodeConfig(bytes.NewReader(head))
+		if err != nil {
+			return nil, ErrUnsupportedFormat
+		}
+		info.Format, info.Width, info.Height = format, cfg.Width, cfg.Height
+		switch format {
+		case "jpeg":
+			exifBlock = jpegEXIF(head)
+		case "png":
+			exifBlock = pngEXIF(head)
+		}
+	}
+
+	if exifBlock != nil {
+		if exif, err := ParseEXIF(exifBlock); err == nil {
+			info.EXIF = exif
+			if exif.Orientation != 0 {
+				info.Orientation = exif.Orientation
+			}
+		}
+	}
+	if info.Orientation >= 5 {
+		info.Width, info.Height = info.Height, info.Width
+	}
+	return info, nil
+}
+
+// jpegEXIF returns the TIFF payload of the first APP1 "Exif" segment.
+func jpegEXIF(b []byte) []byte {
+	if len(b) < 4 || b[0] != 0xFF || b[1] != 0xD8 {
+		return nil
+	}
+	for i := 2; i+4 <= len(b); {
+		if b[i] != 0xFF {
+			return nil
+		}
+		marker := b[i+1]
+		if marker == 0xFF { // fill byte
+			i++
+			continue
+		}
+		if marker == 0xDA || marker == 0xD9 { // start of scan / end of image
+			return nil
+		}
+		length := int(binary.BigEndian.Uint16(b[i+2:]))
+		end := i + 2 + length
+		if length < 2 || end > len(b) {
+			return nil
+		}
+		if marker == 0xE1 && bytes.HasPrefix(b[i+4:end], []byte("Exif\x00\x00")) {
+			return b[i+10 : end]
+		}
+		i = end
+	}
+	return nil
+}
+
+// pngEXIF returns the eXIf chunk, which writers place before the image data.
+func pngEXIF(b []byte) []byte {
+	for i := 8; i+8 <= len(b); {
+		length := int(binary.BigEndian.Uint32(b[i:]))
+		typ := string(b[i+4 : i+8])
+		end := i + 8 + length
+		if end > len(b) {
+			return nil
+		}
+		switch typ {
+		case "eXIf":
+			return b[i+8 : end]
+		case "IDAT", "IEND":
+			return nil
+		}
+		i = end + 4 // CRC
+	}
+	return nil
+}
+
+// probeWebP reads the canvas size from the VP8X, VP8L or VP8 chunk and the
+// optional EXIF chunk. Pixels cannot be decoded with the standard library.
+func probeWebP(b []byte) (width, height int, exifBlock []byte, err error) {
+	for i := 12; i+8 <= len(b); {
+		typ := string(b[i : i+4])
+		length := int(binary.LittleEndian.Uint32(b[i+4:]))
+		data := b[i+8:]
+		if length < len(data) {
+			data = data[:length]
+		}
+		switch typ {
+		case "VP8X":
+			if len(data) >= 10 && width == 0 {
+				width = 1 + int(uint32(data[4])|uint32(data[5])<<8|uint32(data[6])<<16)
+				height = 1 + int(uint32(data[7])|uint32(data[8])<<8|uint32(data[9])<<16)
+			}
+		case "VP8L":
+			if len(data) >= 5 && data[0] == 0x2F && width == 0 {
+				bits := binary.LittleEndian.Uint32(data[1:])
+				width = 1 + int(bits&0x3FFF)
+				height = 1 + int((bits>>14)&0x3FFF)
+			}
+		case "VP8 ":
+			if len(data) >= 10 && data[3] == 0x9D && data[4] == 0x01 && data[5] == 0x2A && width == 0 {
+				width = int(binary.LittleEndian.Uint16(data[6:]) & 0x3FFF)
+				height = int(binary.LittleEndian.Uint16(data[8:]) & 0x3FFF)
+			}
+		case "EXIF":
+			exifBlock = data
+		}
+		i += 8 + length + length&1 // chunks are padded to an even size
+	}
+	if width == 0 || height == 0 {
+		return 0, 0, nil, ErrUnsupportedFormat
+	}
+	return width, height, exifBlock, nil
+}
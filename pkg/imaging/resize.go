@@ -0,0 +1,98 @@
+package imaging
+
+import (
+	"image"
+	"math"
+)
+
+// Resize scales src to w×h with a separable triangle (bilinear) filter whose
+// support widens when shrinking, so every source pixel contributes and
+// thumbnails do not alias. Pixels are premultiplied, which keeps transparent
+// edges free of dark halos.
+func Resize(src *image.RGBA, w, h int) *image.RGBA {
+	b := src.Bounds()
+	if w == b.Dx() && h == b.Dy() {
+		return src
+	}
+	tmp := image.NewRGBA(image.Rect(0, 0, w, b.Dy()))
+	cols := weights(b.Dx(), w)
+	for y := 0; y < b.Dy(); y++ {
+		row := src.Pix[src.PixOffset(b.Min.X, b.Min.Y+y):]
+		out := tmp.Pix[tmp.PixOffset(0, y):]
+		for x, c := range cols {
+			accumulate(out[x*4:x*4+4], row, c, 4)
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	rows := weights(b.Dy(), h)
+	for x := 0; x < w; x++ {
+		col := tmp.Pix[x*4:]
+		for y, c := range rows {
+			i := dst.PixOffset(x, y)
+			accumulate(dst.Pix[i:i+4], col, c, tmp.Stride)
+		}
+	}
+	return dst
+}
+
+// contribution lists the source samples (from start) blended into one output sample.
+type contribution struct {
+	start   int
+	weights []float32
+}
+
+func weights(srcLen, dstLen int) []contribution {
+	scale := float64(srcLen) / float64(dstLen)
+	support := math.Max(scale, 1) // triangle radius in source pixels
+	out := make([]contribution, dstLen)
+	for i := range out {
+		center := (float64(i) + 0.5) * scale
+		start := max(0, int(math.Floor(center-support)))
+		end := min(srcLen, int(math.Ceil(center+support)))
+
+		ws := make([]float32, end-start)
+		var sum float64
+		for j := start; j < end; j++ {
+			wt := 1 - math.Abs((float64(j)+0.5-center)/support)
+			if wt > 0 {
+				ws[j-start] = float32(wt)
+				sum += wt
+			}
+		}
+		if sum == 0 {
+			// Degenerate window: nearest neighbour
+			n := min(srcLen-1, int(center))
+			out[i] = contribution{start: n, weights: []float32{1}}
+			continue
+		}
+		for k := range ws {
+			ws[k] /= float32(sum)
+		}
+		out[i] = contribution{start: start, weights: ws}
+	}
+	return out
+}
+
+// accumulate blends the weighted RGBA samples of line (stride bytes apart) into px.
+func accumulate(px, line []byte, c contribution, stride int) {
+	var r, g, b, a float32
+	for k, wt := range c.weights {
+		i := (c.start + k) * stride
+		r += float32(line[i]) * wt
+		g += float32(line[i+1]) * wt
+		b += float32(line[i+2]) * wt
+		a += float32(line[i+3]) * wt
+	}
+	px[0], px[1], px[2], px[3] = clamp8(r), clamp8(g), clamp8(b), clamp8(a)
+}
+
+func clamp8(v float32) uint8 {
+	switch {
+	case v <= 0:
+		return 0
+	case v >= 255:
+		return 255
+	}
+	return uint8(v + 0.5)
+}
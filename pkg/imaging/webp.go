@@ -0,0 +1,310 @@
+package imaging
+
+import (
+	"encoding/binary"
+	"image"
+	"image/draw"
+	"io"
+	"sort"
+)
+
+// WebP output is lossless (VP8L): every pixel is a Huffman-coded literal
+// after the subtract-green transform. There is no LZ77 or color cache, so
+// files are larger than libwebp's, but the encoder needs no cgo and the
+// result decodes anywhere WebP is supported.
+
+const (
+	webpMaxDimension     = 1 << 14
+	vp8lSignature        = 0x2f
+	transformSubGreen    = 2
+	maxCodeLength        = 15
+	maxCodeLengthCode    = 7
+	greenAlphabetSize    = 256 + 24 // literals + LZ77 length prefixes
+	distanceAlphabetSize = 40
+)
+
+// codeLengthCodeOrder is the order in which code-length code lengths are
+// stored (VP8L spec, section 3.7.2.1.2).
+var codeLengthCodeOrder = [19]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+// encodeWebP writes img as a lossless WebP file.
+func encodeWebP(w io.Writer, img image.Image) error {
+	b := img.Bounds()
+	width, height := b.Dx(), b.Dy()
+	if width < 1 || height < 1 || width > webpMaxDimension || height > webpMaxDimension {
+		return ErrTooLarge
+	}
+	src, ok := img.(*image.NRGBA)
+	if !ok || src.Rect.Min != (image.Point{}) {
+		src = image.NewNRGBA(image.Rect(0, 0, width, height))
+		draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+	}
+
+	// Subtract green: red and blue become differences, which cluster
+	// around zero for natural images and so get shorter codes.
+	pix := make([]byte, 4*width*height)
+	var counts [4][]int
+	counts[0] = make([]int, greenAlphabetSize)
+	for i := 1; i < 4; i++ {
+		counts[i] = make([]int, 256)
+	}
+	for y := 0; y < height; y++ {
+		row := src.Pix[y*src.Stride : y*src.Stride+4*width]
+		for x := 0; x < width; x++ {
+			r, g, bl, a := row[4*x], row[4*x+1], row[4*x+2], row[4*x+3]
+			p := pix[4*(y*width+x):]
+			p[0], p[1], p[2], p[3] = g, r-g, bl-g, a
+			counts[0][g]++
+			counts[1][r-g]++
+			counts[2][bl-g]++
+			counts[3][a]++
+		}
+	}
+
+	bw := &bitWriter{}
+	bw.write(vp8lSignature, 8)
+	bw.write(uint32(width-1), 14)
+	bw.write(uint32(height-1), 14)
+	alpha := uint32(0)
+	if !opaque(src) {
+		alpha = 1
+	}
+	bw.write(alpha, 1)
+	bw.write(0, 3) // version
+
+	bw.write(1, 1) // transform present
+	bw.write(transformSubGreen, 2)
+	bw.write(0, 1) // no more transforms
+	bw.write(0, 1) // no color cache
+	bw.write(0, 1) // a single prefix code group
+
+	var codes [4]prefixCode
+	for i := range codes {
+		codes[i] = writePrefixCode(bw, counts[i])
+	}
+	writePrefixCode(bw, make([]int, distanceAlphabetSize)) // unused: no backward references
+
+	for i := 0; i < len(pix); i += 4 {
+		for c := 0; c < 4; c++ {
+			codes[c].write(bw, int(pix[i+c]))
+		}
+	}
+	data := bw.flush()
+
+	pad := len(data) & 1
+	header := make([]byte, 20)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(12+len(data)+pad))
+	copy(header[8:], "WEBPVP8L")
+	binary.LittleEndian.PutUint32(header[16:], uint32(len(data)))
+	if pad == 1 {
+		data = append(data, 0)
+	}
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+// bitWriter packs values least-significant bit first, as VP8L expects.
+type bitWriter struct {
+	buf  []byte
+	acc  uint64
+	nacc uint
+}
+
+func (bw *bitWriter) write(v uint32, n uint) {
+	bw.acc |= uint64(v) << bw.nacc
+	bw.nacc += n
+	for bw.nacc >= 8 {
+		bw.buf = append(bw.buf, byte(bw.acc))
+		bw.acc >>= 8
+		bw.nacc -= 8
+	}
+}
+
+func (bw *bitWriter) flush() []byte {
+	if bw.nacc > 0 {
+		bw.buf = append(bw.buf, byte(bw.acc))
+		bw.acc, bw.nacc = 0, 0
+	}
+	return bw.buf
+}
+
+// prefixCode holds the bit-reversed canonical code of every symbol, ready
+// for an LSB-first writer.
+type prefixCode struct {
+	codes   []uint32
+	lengths []uint8
+}
+
+func (c prefixCode) write(bw *bitWriter, symbol int) {
+	bw.write(c.codes[symbol], uint(c.lengths[symbol]))
+}
+
+// writePrefixCode stores the code for a symbol histogram and returns it.
+// One or two symbols below 256 use the compact "simple" form.
+func writePrefixCode(bw *bitWriter, counts []int) prefixCode {
+	var used []int
+	for s, n := range counts {
+		if n > 0 {
+			used = append(used, s)
+		}
+	}
+	if len(used) <= 2 && (len(used) == 0 || used[len(used)-1] < 256) {
+		if len(used) == 0 {
+			used = []int{0}
+		}
+		code := prefixCode{codes: make([]uint32, len(counts)), lengths: make([]uint8, len(counts))}
+		bw.write(1, 1) // simple code
+		bw.write(uint32(len(used)-1), 1)
+		if used[0] < 2 {
+			bw.write(0, 1)
+			bw.write(uint32(used[0]), 1)
+		} else {
+			bw.write(1, 1)
+			bw.write(uint32(used[0]), 8)
+		}
+		if len(used) == 2 {
+			bw.write(uint32(used[1]), 8)
+			code.codes[used[1]] = 1
+			code.lengths[used[0]], code.lengths[used[1]] = 1, 1
+		}
+		return code
+	}
+
+	lengths := huffmanLengths(counts, maxCodeLength)
+	writeCodeLengths(bw, lengths)
+	return canonicalCode(lengths)
+}
+
+// writeCodeLengths stores lengths with a second Huffman code over the
+// length values 0-15 (no run-length codes).
+func writeCodeLengths(bw *bitWriter, lengths []uint8) {
+	clCounts := make([]int, len(codeLengthCodeOrder))
+	for _, l := range lengths {
+		clCounts[l]++
+	}
+	clLengths := huffmanLengths(clCounts, maxCodeLengthCode)
+
+	n := 4
+	for i, s := range codeLengthCodeOrder {
+		if clLengths[s] > 0 {
+			n = max(n, i+1)
+		}
+	}
+	bw.write(0, 1) // normal code
+	bw.write(uint32(n-4), 4)
+	for _, s := range codeLengthCodeOrder[:n] {
+		bw.write(uint32(clLengths[s]), 3)
+	}
+	bw.write(0, 1) // lengths for the whole alphabet follow
+
+	clCode := canonicalCode(clLengths)
+	for _, l := range lengths {
+		clCode.write(bw, int(l))
+	}
+}
+
+// canonicalCode assigns canonical codes (shorter first, then by symbol).
+// A lone symbol is decoded without reading any bits.
+func canonicalCode(lengths []uint8) prefixCode {
+	code := prefixCode{codes: make([]uint32, len(lengths)), lengths: make([]uint8, len(lengths))}
+	var hist [maxCodeLength + 1]uint32
+	used := 0
+	for _, l := range lengths {
+		if l > 0 {
+			hist[l]++
+			used++
+		}
+	}
+	if used == 1 {
+		return code
+	}
+	var next [maxCodeLength + 1]uint32
+	for l, c := 1, uint32(0); l <= maxCodeLength; l++ {
+		c = (c + hist[l-1]) << 1
+		next[l] = c
+	}
+	for s, l := range lengths {
+		if l == 0 {
+			continue
+		}
+		c := next[l]
+		next[l]++
+		var rev uint32
+		for i := uint8(0); i < l; i++ {
+			rev = rev<<1 | (c>>i)&1
+		}
+		code.codes[s], code.lengths[s] = rev, l
+	}
+	return code
+}
+
+// huffmanLengths returns Huffman code lengths for counts, no longer than
+// limit. When the tree is too deep the counts are flattened and it is
+// rebuilt. A single used symbol gets length 1.
+func huffmanLengths(counts []int, limit int) []uint8 {
+	counts = append([]int(nil), counts...)
+	lengths := make([]uint8, len(counts))
+	for {
+		type node struct {
+			count       int
+			left, right int // -1 for leaves
+			symbol      int
+		}
+		var nodes []node
+		for s, n := range counts {
+			if n > 0 {
+				nodes = append(nodes, node{count: n, left: -1, right: -1, symbol: s})
+			}
+		}
+		switch len(nodes) {
+		case 0:
+			return lengths
+		case 1:
+			lengths[nodes[0].symbol] = 1
+			return lengths
+		}
+		sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].count < nodes[j].count })
+
+		// Two-queue construction: leaves are sorted and merged nodes are
+		// created in non-decreasing order, so the smallest is at a front.
+		leaves := len(nodes)
+		li, mi := 0, leaves
+		pop := func() int {
+			if li < leaves && (mi >= len(nodes) || nodes[li].count <= nodes[mi].count) {
+				li++
+				return li - 1
+			}
+			mi++
+			return mi - 1
+		}
+		for len(nodes) < 2*leaves-1 {
+			a, b := pop(), pop()
+			nodes = append(nodes, node{count: nodes[a].count + nodes[b].count, left: a, right: b})
+		}
+
+		depth := make([]int, len(nodes))
+		deepest := 0
+		for i := len(nodes) - 1; i >= leaves; i-- {
+			depth[nodes[i].left] = depth[i] + 1
+			depth[nodes[i].right] = depth[i] + 1
+		}
+		for i := 0; i < leaves; i++ {
+			deepest = max(deepest, depth[i])
+		}
+		if deepest <= limit {
+			for i := 0; i < leaves; i++ {
+				lengths[nodes[i].symbol] = uint8(depth[i])
+			}
+			return lengths
+		}
+		for s, n := range counts {
+			if n > 0 {
+				counts[s] = n>>1 | 1
+			}
+		}
+	}
+}
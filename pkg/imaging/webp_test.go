@@ -0,0 +1,124 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+
+	"golang.org/x/image/webp"
+)
+
+func TestEncodeWebPRoundTrip(t *testing.T) {
+	fill := func(w, h int, px func(x, y int) color.NRGBA) *image.NRGBA {
+		img := image.NewNRGBA(image.Rect(0, 0, w, h))
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				img.SetNRGBA(x, y, px(x, y))
+			}
+		}
+		return img
+	}
+	rng := rand.New(rand.NewSource(1))
+
+	tests := []struct {
+		name string
+		img  *image.NRGBA
+	}{
+		{"1x1", fill(1, 1, func(x, y int) color.NRGBA { return color.NRGBA{10, 20, 30, 255} })},
+		{"single colour", fill(7, 5, func(x, y int) color.NRGBA { return color.NRGBA{200, 100, 50, 255} })},
+		{"two colours", fill(8, 8, func(x, y int) color.NRGBA {
+			if (x+y)%2 == 0 {
+				return color.NRGBA{0, 0, 0, 255}
+			}
+			return color.NRGBA{255, 255, 255, 255}
+		})},
+		{"gradient", fill(64, 33, func(x, y int) color.NRGBA { return color.NRGBA{uint8(x * 4), uint8(y * 7), uint8(x ^ y), 255} })},
+		{"alpha", fill(20, 20, func(x, y int) color.NRGBA { return color.NRGBA{uint8(x * 12), 80, uint8(y * 12), uint8(x * y)} })},
+		{"noise", fill(50, 40, func(x, y int) color.NRGBA {
+			return color.NRGBA{uint8(rng.Intn(256)), uint8(rng.Intn(256)), uint8(rng.Intn(256)), uint8(rng.Intn(256))}
+		})},
+		{"skewed histogram", fill(300, 2, func(x, y int) color.NRGBA {
+			// Counts from 1 to ~2^16 force the code-length limit
+			v := uint8(0)
+			for n := x * (y + 1); n > 0 && v < 40; n >>= 1 {
+				v++
+			}
+			return color.NRGBA{v, v * 3, 255 - v, 255}
+		})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Encode(&buf, tt.img, FormatWebP, 0); err != nil {
+				t.Fatal(err)
+			}
+			got, err := webp.Decode(&buf)
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if got.Bounds() != tt.img.Bounds() {
+				t.Fatalf("bounds = %v, want %v", got.Bounds(), tt.img.Bounds())
+			}
+			b := tt.img.Bounds()
+			for y := b.Min.Y; y < b.Max.Y; y++ {
+				for x := b.Min.X; x < b.Max.X; x++ {
+					want := tt.img.NRGBAAt(x, y)
+					if c := color.NRGBAModel.Convert(got.At(x, y)).(color.NRGBA); c != want {
+						t.Fatalf("pixel (%d,%d) = %v, want %v", x, y, c, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestEncodeWebPTooLarge(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, webpMaxDimension+1, 1))
+	if err := Encode(&bytes.Buffer{}, img, FormatWebP, 0); err != ErrTooLarge {
+		t.Fatalf("err = %v, want ErrTooLarge", err)
+	}
+}
+
+func TestHuffmanLengthsRespectLimit(t *testing.T) {
+	counts := make([]int, 30)
+	for i := range counts {
+		counts[i] = 1 << i // unlimited, this skew gives a 29-deep tree
+	}
+	for _, limit := range []int{maxCodeLengthCode, maxCodeLength} {
+		lengths := huffmanLengths(counts, limit)
+		kraft := 0.0
+		for _, l := range lengths {
+			if l == 0 || int(l) > limit {
+				t.Fatalf("limit %d: length %d", limit, l)
+			}
+			kraft += 1 / float64(uint(1)<<l)
+		}
+		if kraft != 1 {
+			t.Fatalf("limit %d: Kraft sum = %v, want a complete code", limit, kraft)
+		}
+	}
+}
+
+func TestOutputFormat(t *testing.T) {
+	tests := []struct {
+		requested, source, want string
+		err                     error
+	}{
+		{"", FormatJPEG, FormatJPEG, nil},
+		{"", FormatPNG, FormatPNG, nil},
+		{"", "gif", FormatPNG, nil},
+		{"", FormatWebP, FormatWebP, nil},
+		{"jpg", FormatPNG, FormatJPEG, nil},
+		{"png", FormatWebP, FormatPNG, nil},
+		{"webp", FormatJPEG, FormatWebP, nil},
+		{"avif", FormatJPEG, "", ErrUnsupportedFormat},
+	}
+	for _, tt := range tests {
+		got, err := OutputFormat(tt.requested, tt.source)
+		if got != tt.want || err != tt.err {
+			t.Errorf("OutputFormat(%q, %q) = %q, %v; want %q, %v", tt.requested, tt.source, got, err, tt.want, tt.err)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// MergePatch applies an RFC 7396 JSON merge patch to target and returns the result.
+// A nil value in the patch removes the key; nested objects are merged recursively.
+func MergePatch(target interface{}, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObj, ok := target.(map[string]interface{})
+	if !ok {
+		targetObj = map[string]interface{}{}
+	}
+
+	result := make(map[string]interface{}, len(targetObj))
+	for k, v := range targetObj {
+		result[k] = v
+	}
+	for k, v := range patchObj {
+		if v == nil {
+			delete(result, k)
+			continue
+		}
+		result[k] = MergePatch(result[k], v)
+	}
+	return result
+}
+
+// PatchOperation is a single RFC 6902 JSON Patch operation.
+type PatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	From  string      `json:"from,omitempty"`
+	Value interface{} `json:"value"`
+}
+
+var (
+	ErrPatchInvalidPointer = errors.New("jsonpatch: invalid JSON pointer")
+	ErrPatchPathNotFound   = errors.New("jsonpatch: path not found")
+	ErrPatchTestFailed     = errors.New("jsonpatch: test operation failed")
+)
+
+// ApplyJSONPatch applies RFC 6902 operations (add, remove, replace, move, copy, test)
+// to a copy of doc. The patch is atomic: on error the original document is untouched.
+func ApplyJSONPatch(doc interface{}, ops []PatchOperation) (interface{}, error) {
+	out := deepCopyJSON(doc)
+	for i, op := range ops {
+		var err error
+		switch op.Op {
+		case "add":
+			out, err = patchAdd(out, op.Path, deepCopyJSON(op.Value))
+		case "remove":
+			out, _, err = patchRemove(out, op.Path)
+		case "replace":
+			if out, _, err = patchRemove(out, op.Path); err == nil {
+				out, err = patchAdd(out, op.Path, deepCopyJSON(op.Value))
+			}
+		case "move":
+			if op.From != op.Path && strings.HasPrefix(op.Path, op.From+"/") {
+				err = fmt.Errorf("jsonpatch: cannot move %q into its own child", op.From)
+				break
+			}
+			var v interface{}
+			if out, v, err = patchRemove(out, op.From); err == nil {
+				out, err = patchAdd(out, op.Path, v)
+			}
+		case "copy":
+			var v interface{}
+			if v, err = patchGet(out, op.From); err == nil {
+				out, err = patchAdd(out, op.Path, deepCopyJSON(v))
+			}
+		case "test":
+			var v interface{}
+			if v, err = patchGet(out, op.Path); err == nil && !reflect.DeepEqual(normalizeJSON(v), normalizeJSON(op.Value)) {
+				err = ErrPatchTestFailed
+			}
+		default:
+			err = fmt.Errorf("jsonpatch: unknown op %q", op.Op)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+	return out, nil
+}
+
+// parsePointer splits an RFC 6901 pointer ("/a/b~1c" -> ["a", "b/c"]).
+func parsePointer(path string) ([]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	if path[0] != '/' {
+		return nil, ErrPatchInvalidPointer
+	}
+	parts := strings.Split(path[1:], "/")
+	for i, p := range parts {
+		parts[i] = strings.ReplaceAll(strings.ReplaceAll(p, "~1", "/"), "~0", "~")
+	}
+	return parts, nil
+}
+
+// arrayIndex parses an array token; "-" (append) is only valid when allowEnd is set.
+func arrayIndex(token string, n int, allowEnd bool) (int, error) {
+	if token == "-" && allowEnd {
+		return n, nil
+	}
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, ErrPatchInvalidPointer
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 {
+		return 0, ErrPatchInvalidPointer
+	}
+	max := n - 1
+	if allowEnd {
+		max = n
+	}
+	if i > max {
+		return 0, ErrPatchPathNotFound
+	}
+	return i, nil
+}
+
+func patchGet(doc interface{}, path string) (interface{}, error) {
+	parts, err := parsePointer(path)
+	if err != nil {
+		return nil, err
+	}
+	cur := doc
+	for _, p := range parts {
+		switch node := cur.(type) {
+		case map[string]interface{}:
+			v, ok := node[p]
+			if !ok {
+				return nil, ErrPatchPathNotFound
+			}
+			cur = v
+		case []interface{}:
+			i, err := arrayIndex(p, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+			cur = node[i]
+		default:
+			return nil, ErrPatchPathNotFound
+		}
+	}
+	return cur, nil
+}
+
+// patchAdd returns the (possibly new) root; arrays are re-sliced so the parent is updated.
+func patchAdd(doc interface{}, path string, value interface{}) (interface{}, error) {
+	parts, err := parsePointer(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(parts) == 0 {
+		return value, nil
+	}
+	return setIn(doc, parts, value, true)
+}
+
+func patchRemove(doc interface{}, path string) (interface{}, interface{}, error) {
+	parts, err := parsePointer(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(parts) == 0 {
+		return nil, doc, nil
+	}
+	removed, err := patchGet(doc, path)
+	if err != nil {
+		return nil, nil, err
+	}
+	out, err := setIn(doc, parts, nil, false)
+	return out, removed, err
+}
+
+// setIn walks to the parent of the last token and inserts (add) or deletes (remove) there.
+func setIn(node interface{}, parts []string, value interface{}, add bool) (interface{}, error) {
+	last := len(parts) == 1
+	switch n := node.(type) {
+	case map[string]interface{}:
+		if last {
+			if add {
+				n[parts[0]] = value
+			} else {
+				delete(n, parts[0])
+			}
+			return n, nil
+		}
+		child, ok := n[parts[0]]
+		if !ok {
+			return nil, ErrPatchPathNotFound
+		}
+		updated, err := setIn(child, parts[1:], value, add)
+		if err != nil {
+			return nil, err
+		}
+		n[parts[0]] = updated
+		return n, nil
+
+	case []interface{}:
+		if last {
+			i, err := arrayIndex(parts[0], len(n), add)
+			if err != nil {
+				return nil, err
+			}
+			if add {
+				n = append(n, nil)
+				copy(n[i+1:], n[i:])
+				n[i] = value
+				return n, nil
+			}
+			return append(n[:i], n[i+1:]...), nil
+		}
+		i, err := arrayIndex(parts[0], len(n), false)
+		if err != nil {
+			return nil, err
+		}
+		updated, err := setIn(n[i], parts[1:], value, add)
+		if err != nil {
+			return nil, err
+		}
+		n[i] = updated
+		return n, nil
+	}
+	return nil, ErrPatchPathNotFound
+}
+
+func deepCopyJSON(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(t))
+		for k, x := range t {
+			out[k] = deepCopyJSON(x)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		for i, x := range t {
+			out[i] = deepCopyJSON(x)
+		}
+		return out
+	}
+	return v
+}
+
+// normalizeJSON makes numbers comparable regardless of how they were decoded (int vs float64).
+func normalizeJSON(v interface{}) interface{} {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var out interface{}
+	_ = json.Unmarshal(b, &out)
+	return out
+}
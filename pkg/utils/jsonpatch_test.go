@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestApplyJSONPatch(t *testing.T) {
+	const doc = `{"a":1,"b":{"c":[1,2,3]},"d~e/f":"x"}`
+
+	tests := []struct {
+		name string
+		ops  string
+		want string
+		err  error
+	}{
+		{"add key", `[{"op":"add","path":"/z","value":true}]`, `{"a":1,"b":{"c":[1,2,3]},"d~e/f":"x","z":true}`, nil},
+		{"add replaces existing key", `[{"op":"add","path":"/a","value":2}]`, `{"a":2,"b":{"c":[1,2,3]},"d~e/f":"x"}`, nil},
+		{"insert into array", `[{"op":"add","path":"/b/c/1","value":9}]`, `{"a":1,"b":{"c":[1,9,2,3]},"d~e/f":"x"}`, nil},
+		{"append with -", `[{"op":"add","path":"/b/c/-","value":4}]`, `{"a":1,"b":{"c":[1,2,3,4]},"d~e/f":"x"}`, nil},
+		{"add at array end index", `[{"op":"add","path":"/b/c/3","value":4}]`, `{"a":1,"b":{"c":[1,2,3,4]},"d~e/f":"x"}`, nil},
+		{"add past array end", `[{"op":"add","path":"/b/c/5","value":4}]`, "", ErrPatchPathNotFound},
+		{"add to missing parent", `[{"op":"add","path":"/x/y","value":1}]`, "", ErrPatchPathNotFound},
+		{"replace whole document", `[{"op":"add","path":"","value":[1]}]`, `[1]`, nil},
+		{"remove key", `[{"op":"remove","path":"/a"}]`, `{"b":{"c":[1,2,3]},"d~e/f":"x"}`, nil},
+		{"remove array element", `[{"op":"remove","path":"/b/c/0"}]`, `{"a":1,"b":{"c":[2,3]},"d~e/f":"x"}`, nil},
+		{"remove missing", `[{"op":"remove","path":"/nope"}]`, "", ErrPatchPathNotFound},
+		{"remove with -", `[{"op":"remove","path":"/b/c/-"}]`, "", ErrPatchInvalidPointer},
+		{"escaped pointer", `[{"op":"replace","path":"/d~0e~1f","value":"y"}]`, `{"a":1,"b":{"c":[1,2,3]},"d~e/f":"y"}`, nil},
+		{"replace missing", `[{"op":"replace","path":"/nope","value":1}]`, "", ErrPatchPathNotFound},
+		{"move", `[{"op":"move","from":"/a","path":"/b/a"}]`, `{"b":{"a":1,"c":[1,2,3]},"d~e/f":"x"}`, nil},
+		{"move into own child", `[{"op":"move","from":"/b","path":"/b/c/0"}]`, "", nil},
+		{"copy", `[{"op":"copy","from":"/b/c","path":"/e"}]`, `{"a":1,"b":{"c":[1,2,3]},"d~e/f":"x","e":[1,2,3]}`, nil},
+		{"test passes", `[{"op":"test","path":"/b/c","value":[1,2,3]},{"op":"remove","path":"/a"}]`, `{"b":{"c":[1,2,3]},"d~e/f":"x"}`, nil},
+		{"test fails", `[{"op":"test","path":"/a","value":2}]`, "", ErrPatchTestFailed},
+		{"leading zero index", `[{"op":"remove","path":"/b/c/01"}]`, "", ErrPatchInvalidPointer},
+		{"pointer without slash", `[{"op":"remove","path":"a"}]`, "", ErrPatchInvalidPointer},
+		{"unknown op", `[{"op":"frobnicate","path":"/a"}]`, "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var in interface{}
+			if err := json.Unmarshal([]byte(doc), &in); err != nil {
+				t.Fatal(err)
+			}
+			var ops []PatchOperation
+			if err := json.Unmarshal([]byte(tt.ops), &ops); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := ApplyJSONPatch(in, ops)
+			if tt.want == "" {
+				if err == nil {
+					t.Fatalf("got %v, want an error", got)
+				}
+				if tt.err != nil && !errors.Is(err, tt.err) {
+					t.Fatalf("err = %v, want %v", err, tt.err)
+				}
+			} else {
+				if err != nil {
+					t.Fatal(err)
+				}
+				var want interface{}
+				_ = json.Unmarshal([]byte(tt.want), &want)
+				if !reflect.DeepEqual(normalizeJSON(got), normalizeJSON(want)) {
+					t.Fatalf("got %v, want %v", got, want)
+				}
+			}
+
+			// The input is never modified, even when a later operation fails
+			var orig interface{}
+			_ = json.Unmarshal([]byte(doc), &orig)
+			if !reflect.DeepEqual(in, orig) {
+				t.Fatalf("input document was modified: %v", in)
+			}
+		})
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		target, patch, want string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a"]`, `{"a":"b"}`, `{"a":"b"}`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+	}
+	for _, tt := range tests {
+		var target, patch, want interface{}
+		_ = json.Unmarshal([]byte(tt.target), &target)
+		_ = json.Unmarshal([]byte(tt.patch), &patch)
+		_ = json.Unmarshal([]byte(tt.want), &want)
+		if got := MergePatch(target, patch); !reflect.DeepEqual(got, want) {
+			t.Errorf("MergePatch(%s, %s) = %v, want %s", tt.target, tt.patch, got, tt.want)
+		}
+	}
+}
@@ -1 +1,55 @@
 package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/google/uuid"
+)
+
+// Message kinds carried over the backplane.
+const (
+	KindChannel    = "channel"    // Fan out to subscribers of Channel
+	KindProject    = "project"    // Fan out to every connection of ProjectID
+	KindUser       = "user"       // Fan out to every connection of UserIDs within ProjectID
+	KindModeration = "moderation" // Apply a kick/ban/mute to live connections of UserIDs
+)
+
+// Message is a realtime broadcast that has to reach every node in the cluster.
+// Data holds the already-encoded frame so receiving nodes forward it as-is.
+type Message struct {
+	NodeID    string          `json:"node_id"`
+	Kind      string          `json:"kind"`
+	ProjectID string          `json:"project_id"`
+	Channel   string          `json:"channel,omitempty"`
+	UserIDs   []string        `json:"user_ids,omitempty"`
+	Data      json.RawMessage `json:"data"`
+
+	// Guaranteed delivery: receiving nodes track an ACK per subscriber when set
+	EventID     string `json:"event_id,omitempty"`
+	AckRequired bool   `json:"ack_required,omitempty"`
+}
+
+// Handler receives messages published by any node (including this one).
+type Handler func(Message)
+
+// Backplane is the pub/sub layer that connects RealtimeHandler instances
+// running on different replicas.
+type Backplane interface {
+	NodeID() string
+	Publish(ctx context.Context, msg Message) error
+	Subscribe(h Handler)
+	Start(ctx context.Context) error
+	Close() error
+}
+
+// NewNodeID returns a readable id for this process (hostname + short random suffix).
+func NewNodeID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "node"
+	}
+	return fmt.Sprintf("%s-%s", host, uuid.New().String()[:8])
+}
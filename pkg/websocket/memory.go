@@ -0,0 +1,82 @@
+package websocket
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryBus is an in-process message bus. Several MemoryBackplane nodes can share
+// one bus, which is how a single binary (or a test) simulates a cluster.
+type MemoryBus struct {
+	mu    sync.RWMutex
+	nodes []*MemoryBackplane
+}
+
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{}
+}
+
+// MemoryBackplane is the default single-instance backplane.
+type MemoryBackplane struct {
+	bus      *MemoryBus
+	nodeID   string
+	mu       sync.RWMutex
+	handlers []Handler
+}
+
+// NewMemoryBackplane joins bus as a new node. A nil bus creates a private one.
+func NewMemoryBackplane(bus *MemoryBus) *MemoryBackplane {
+	if bus == nil {
+		bus = NewMemoryBus()
+	}
+	b := &MemoryBackplane{bus: bus, nodeID: NewNodeID()}
+	bus.mu.Lock()
+	bus.nodes = append(bus.nodes, b)
+	bus.mu.Unlock()
+	return b
+}
+
+func (b *MemoryBackplane) NodeID() string { return b.nodeID }
+
+func (b *MemoryBackplane) Subscribe(h Handler) {
+	b.mu.Lock()
+	b.handlers = append(b.handlers, h)
+	b.mu.Unlock()
+}
+
+func (b *MemoryBackplane) Publish(ctx context.Context, msg Message) error {
+	if msg.NodeID == "" {
+		msg.NodeID = b.nodeID
+	}
+	b.bus.mu.RLock()
+	nodes := append([]*MemoryBackplane(nil), b.bus.nodes...)
+	b.bus.mu.RUnlock()
+
+	for _, n := range nodes {
+		n.dispatch(msg)
+	}
+	return nil
+}
+
+func (b *MemoryBackplane) dispatch(msg Message) {
+	b.mu.RLock()
+	handlers := append([]Handler(nil), b.handlers...)
+	b.mu.RUnlock()
+	for _, h := range handlers {
+		h(msg)
+	}
+}
+
+func (b *MemoryBackplane) Start(ctx context.Context) error { return nil }
+
+func (b *MemoryBackplane) Close() error {
+	b.bus.mu.Lock()
+	defer b.bus.mu.Unlock()
+	for i, n := range b.bus.nodes {
+		if n == b {
+			b.bus.nodes = append(b.bus.nodes[:i], b.bus.nodes[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
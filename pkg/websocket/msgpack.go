@@ -0,0 +1,365 @@
+package websocket
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Minimal MessagePack codec for the realtime wire protocol.
+// It covers the JSON data model (nil, bool, numbers, strings, arrays, maps),
+// which is all a realtime frame can carry. bin is decoded as a string and
+// ext types are rejected.
+
+const maxMsgpackDepth = 64
+
+var (
+	ErrMsgpackTruncated   = errors.New("msgpack: unexpected end of data")
+	ErrMsgpackUnsupported = errors.New("msgpack: unsupported type")
+	ErrMsgpackTooDeep     = errors.New("msgpack: nesting too deep")
+)
+
+// JSONToMsgpack re-encodes a JSON document as MessagePack.
+func JSONToMsgpack(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return EncodeMsgpack(v)
+}
+
+// MsgpackToJSON decodes a MessagePack document and returns it as JSON.
+func MsgpackToJSON(data []byte) ([]byte, error) {
+	v, err := DecodeMsgpack(data)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// EncodeMsgpack encodes values from the JSON data model.
+func EncodeMsgpack(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := encodeValue(&buf, v, 0); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func encodeValue(buf *bytes.Buffer, v interface{}, depth int) error {
+	if depth > maxMsgpackDepth {
+		return ErrMsgpackTooDeep
+	}
+	switch t := v.(type) {
+	case nil:
+		buf.WriteByte(0xc0)
+	case bool:
+		if t {
+			buf.WriteByte(0xc3)
+		} else {
+			buf.WriteByte(0xc2)
+		}
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			encodeInt(buf, i)
+			return nil
+		}
+		f, err := t.Float64()
+		if err != nil {
+			return err
+		}
+		encodeFloat(buf, f)
+	case float64:
+		if t == math.Trunc(t) && t >= math.MinInt64 && t <= math.MaxInt64 {
+			encodeInt(buf, int64(t))
+		} else {
+			encodeFloat(buf, t)
+		}
+	case int:
+		encodeInt(buf, int64(t))
+	case int64:
+		encodeInt(buf, t)
+	case string:
+		encodeString(buf, t)
+	case []interface{}:
+		encodeHeader(buf, len(t), 0x90, 15, 0xdc, 0xdd)
+		for _, item := range t {
+			if err := encodeValue(buf, item, depth+1); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		encodeHeader(buf, len(t), 0x80, 15, 0xde, 0xdf)
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys) // Deterministic output
+		for _, k := range keys {
+			encodeString(buf, k)
+			if err := encodeValue(buf, t[k], depth+1); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("%w: %T", ErrMsgpackUnsupported, v)
+	}
+	return nil
+}
+
+func encodeInt(buf *bytes.Buffer, i int64) {
+	switch {
+	case i >= 0 && i <= 127:
+		buf.WriteByte(byte(i))
+	case i < 0 && i >= -32:
+		buf.WriteByte(byte(int8(i)))
+	case i >= math.MinInt8 && i <= math.MaxInt8:
+		buf.WriteByte(0xd0)
+		buf.WriteByte(byte(int8(i)))
+	case i >= math.MinInt16 && i <= math.MaxInt16:
+		buf.WriteByte(0xd1)
+		binary.Write(buf, binary.BigEndian, int16(i))
+	case i >= math.MinInt32 && i <= math.MaxInt32:
+		buf.WriteByte(0xd2)
+		binary.Write(buf, binary.BigEndian, int32(i))
+	default:
+		buf.WriteByte(0xd3)
+		binary.Write(buf, binary.BigEndian, i)
+	}
+}
+
+func encodeFloat(buf *bytes.Buffer, f float64) {
+	buf.WriteByte(0xcb)
+	binary.Write(buf, binary.BigEndian, math.Float64bits(f))
+}
+
+func encodeString(buf *bytes.Buffer, s string) {
+	n := len(s)
+	switch {
+	case n <= 31:
+		buf.WriteByte(0xa0 | byte(n))
+	case n <= math.MaxUint8:
+		buf.WriteByte(0xd9)
+		buf.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(0xda)
+		binary.Write(buf, binary.BigEndian, uint16(n))
+	default:
+		buf.WriteByte(0xdb)
+		binary.Write(buf, binary.BigEndian, uint32(n))
+	}
+	buf.WriteString(s)
+}
+
+// encodeHeader writes an array or map length using the fix/16/32 forms.
+func encodeHeader(buf *bytes.Buffer, n int, fix byte, fixMax int, code16, code32 byte) {
+	switch {
+	case n <= fixMax:
+		buf.WriteByte(fix | byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(code16)
+		binary.Write(buf, binary.BigEndian, uint16(n))
+	default:
+		buf.WriteByte(code32)
+		binary.Write(buf, binary.BigEndian, uint32(n))
+	}
+}
+
+// DecodeMsgpack decodes a single MessagePack value into the JSON data model
+// (maps become map[string]interface{}, numbers become int64/uint64/float64).
+func DecodeMsgpack(data []byte) (interface{}, error) {
+	d := &msgpackDecoder{data: data}
+	v, err := d.value(0)
+	if err != nil {
+		return nil, err
+	}
+	if d.pos != len(d.data) {
+		return nil, errors.New("msgpack: trailing data")
+	}
+	return v, nil
+}
+
+type msgpackDecoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *msgpackDecoder) take(n int) ([]byte, error) {
+	if n < 0 || d.pos+n > len(d.data) {
+		return nil, ErrMsgpackTruncated
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+func (d *msgpackDecoder) uint(n int) (uint64, error) {
+	b, err := d.take(n)
+	if err != nil {
+		return 0, err
+	}
+	var v uint64
+	for _, x := range b {
+		v = v<<8 | uint64(x)
+	}
+	return v, nil
+}
+
+func (d *msgpackDecoder) value(depth int) (interface{}, error) {
+	if depth > maxMsgpackDepth {
+		return nil, ErrMsgpackTooDeep
+	}
+	b, err := d.take(1)
+	if err != nil {
+		return nil, err
+	}
+	c := b[0]
+
+	switch {
+	case c <= 0x7f:
+		return int64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c&0xf0 == 0x80:
+		return d.mapN(int(c&0x0f), depth)
+	case c&0xf0 == 0x90:
+		return d.arrayN(int(c&0x0f), depth)
+	case c&0xe0 == 0xa0:
+		return d.str(int(c & 0x1f))
+	}
+
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xd9: // bin8 / str8
+		n, err := d.uint(1)
+		if err != nil {
+			return nil, err
+		}
+		return d.str(int(n))
+	case 0xc5, 0xda: // bin16 / str16
+		n, err := d.uint(2)
+		if err != nil {
+			return nil, err
+		}
+		return d.str(int(n))
+	case 0xc6, 0xdb: // bin32 / str32
+		n, err := d.uint(4)
+		if err != nil {
+			return nil, err
+		}
+		return d.str(int(n))
+	case 0xca:
+		n, err := d.uint(4)
+		if err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(uint32(n))), nil
+	case 0xcb:
+		n, err := d.uint(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(n), nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		n, err := d.uint(1 << (c - 0xcc))
+		if err != nil {
+			return nil, err
+		}
+		if n <= math.MaxInt64 {
+			return int64(n), nil
+		}
+		return n, nil
+	case 0xd0:
+		n, err := d.uint(1)
+		return int64(int8(n)), err
+	case 0xd1:
+		n, err := d.uint(2)
+		return int64(int16(n)), err
+	case 0xd2:
+		n, err := d.uint(4)
+		return int64(int32(n)), err
+	case 0xd3:
+		n, err := d.uint(8)
+		return int64(n), err
+	case 0xdc:
+		n, err := d.uint(2)
+		if err != nil {
+			return nil, err
+		}
+		return d.arrayN(int(n), depth)
+	case 0xdd:
+		n, err := d.uint(4)
+		if err != nil {
+			return nil, err
+		}
+		return d.arrayN(int(n), depth)
+	case 0xde:
+		n, err := d.uint(2)
+		if err != nil {
+			return nil, err
+		}
+		return d.mapN(int(n), depth)
+	case 0xdf:
+		n, err := d.uint(4)
+		if err != nil {
+			return nil, err
+		}
+		return d.mapN(int(n), depth)
+	}
+	return nil, fmt.Errorf("%w: 0x%02x", ErrMsgpackUnsupported, c)
+}
+
+func (d *msgpackDecoder) str(n int) (string, error) {
+	b, err := d.take(n)
+	return string(b), err
+}
+
+func (d *msgpackDecoder) arrayN(n int, depth int) (interface{}, error) {
+	// Each element needs at least one byte, so a bogus length fails fast
+	if n > len(d.data)-d.pos {
+		return nil, ErrMsgpackTruncated
+	}
+	out := make([]interface{}, 0, n)
+	for i := 0; i < n; i++ {
+		v, err := d.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+func (d *msgpackDecoder) mapN(n int, depth int) (interface{}, error) {
+	if n > (len(d.data)-d.pos)/2 {
+		return nil, ErrMsgpackTruncated
+	}
+	out := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		k, err := d.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		v, err := d.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		key, ok := k.(string)
+		if !ok {
+			key = fmt.Sprint(k)
+		}
+		out[key] = v
+	}
+	return out, nil
+}
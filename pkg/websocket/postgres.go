@@ -0,0 +1,189 @@
+package websocket
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultPGChannel is the LISTEN/NOTIFY channel shared by all nodes.
+	DefaultPGChannel = "superaib_realtime"
+
+	// NOTIFY payloads are capped at 8000 bytes; larger messages go through the spill table.
+	maxNotifyPayload = 7900
+	spillTTL         = 5 * time.Minute
+)
+
+// spillRef is what gets NOTIFYed when the message itself lives in the spill table.
+type spillRef struct {
+	Spill int64 `json:"spill"`
+}
+
+// PostgresBackplane fans messages out across replicas with Postgres LISTEN/NOTIFY.
+// Publishing goes through the shared pool; listening holds one dedicated connection
+// which is re-established with backoff if it drops.
+type PostgresBackplane struct {
+	dsn     string
+	db      *sql.DB
+	channel string
+	nodeID  string
+	log     *logrus.Logger
+
+	mu       sync.RWMutex
+	handlers []Handler
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+func NewPostgresBackplane(dsn string, db *sql.DB, log *logrus.Logger) *PostgresBackplane {
+	return &PostgresBackplane{
+		dsn:     dsn,
+		db:      db,
+		channel: DefaultPGChannel,
+		nodeID:  NewNodeID(),
+		log:     log,
+	}
+}
+
+func (b *PostgresBackplane) NodeID() string { return b.nodeID }
+
+func (b *PostgresBackplane) Subscribe(h Handler) {
+	b.mu.Lock()
+	b.handlers = append(b.handlers, h)
+	b.mu.Unlock()
+}
+
+func (b *PostgresBackplane) Publish(ctx context.Context, msg Message) error {
+	if msg.NodeID == "" {
+		msg.NodeID = b.nodeID
+	}
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	if len(payload) > maxNotifyPayload {
+		var id int64
+		err := b.db.QueryRowContext(ctx,
+			"INSERT INTO realtime_backplane_spill (payload) VALUES ($1) RETURNING id", string(payload)).Scan(&id)
+		if err != nil {
+			return fmt.Errorf("backplane spill failed: %w", err)
+		}
+		payload, _ = json.Marshal(spillRef{Spill: id})
+	}
+
+	_, err = b.db.ExecContext(ctx, "SELECT pg_notify($1, $2)", b.channel, string(payload))
+	return err
+}
+
+// Start creates the spill table and begins listening in the background.
+func (b *PostgresBackplane) Start(ctx context.Context) error {
+	_, err := b.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS realtime_backplane_spill (
+		id BIGSERIAL PRIMARY KEY,
+		payload TEXT NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`)
+	if err != nil {
+		return fmt.Errorf("backplane init failed: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	b.cancel = cancel
+	b.done = make(chan struct{})
+	go b.listenLoop(ctx)
+	go b.cleanupLoop(ctx)
+	return nil
+}
+
+func (b *PostgresBackplane) Close() error {
+	if b.cancel != nil {
+		b.cancel()
+		<-b.done
+	}
+	return nil
+}
+
+func (b *PostgresBackplane) listenLoop(ctx context.Context) {
+	defer close(b.done)
+	backoff := time.Second
+
+	for ctx.Err() == nil {
+		err := b.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		b.log.Warnf("⚠️ [Backplane] LISTEN connection lost: %v (retry in %s)", err, backoff)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return
+		}
+		if backoff < 30*time.Second {
+			backoff *= 2
+		}
+	}
+}
+
+func (b *PostgresBackplane) listen(ctx context.Context) error {
+	conn, err := pgx.Connect(ctx, b.dsn)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{b.channel}.Sanitize()); err != nil {
+		return err
+	}
+	b.log.Infof("📡 [Backplane] Node %s listening on '%s'", b.nodeID, b.channel)
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		b.handleNotification(ctx, n.Payload)
+	}
+}
+
+func (b *PostgresBackplane) handleNotification(ctx context.Context, payload string) {
+	var ref spillRef
+	if err := json.Unmarshal([]byte(payload), &ref); err == nil && ref.Spill > 0 {
+		if err := b.db.QueryRowContext(ctx, "SELECT payload FROM realtime_backplane_spill WHERE id = $1", ref.Spill).Scan(&payload); err != nil {
+			b.log.Errorf("❌ [Backplane] Spill %d not found: %v", ref.Spill, err)
+			return
+		}
+	}
+
+	var msg Message
+	if err := json.Unmarshal([]byte(payload), &msg); err != nil {
+		b.log.Errorf("❌ [Backplane] Invalid payload: %v", err)
+		return
+	}
+
+	b.mu.RLock()
+	handlers := append([]Handler(nil), b.handlers...)
+	b.mu.RUnlock()
+	for _, h := range handlers {
+		h(msg)
+	}
+}
+
+func (b *PostgresBackplane) cleanupLoop(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, _ = b.db.ExecContext(ctx, "DELETE FROM realtime_backplane_spill WHERE created_at < $1", time.Now().Add(-spillTTL))
+		}
+	}
+}
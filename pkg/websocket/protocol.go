@@ -0,0 +1,39 @@
+package websocket
+
+// Realtime wire protocol.
+//
+// Clients pick an encoding through the Sec-WebSocket-Protocol header:
+//
+//	superaib.v1.json     text frames, JSON
+//	superaib.v1.msgpack  binary frames, MessagePack (same document shape as JSON)
+//
+// Without a subprotocol the connection speaks the legacy (v0) JSON dialect.
+//
+// A v1 client frame carries "v":1 and an optional "ref". Every frame that has
+// a ref gets exactly one reply:
+//
+//	{"v":1,"type":"reply","ref":"42","status":"ok","payload":{...}}
+//	{"v":1,"type":"reply","ref":"42","status":"error","payload":{"code":"forbidden","message":"..."}}
+const (
+	ProtocolVersion = 1
+
+	SubprotocolJSON    = "superaib.v1.json"
+	SubprotocolMsgpack = "superaib.v1.msgpack"
+
+	ReplyOK    = "ok"
+	ReplyError = "error"
+)
+
+// Error codes returned in reply and error frames.
+const (
+	CodeInvalidFrame   = "invalid_frame"
+	CodeUnknownAction  = "unknown_action"
+	CodeInvalidChannel = "invalid_channel"
+	CodeUnsupportedV   = "unsupported_version"
+
+	CodeRateLimited          = "rate_limited"
+	CodeTooManySubscriptions = "too_many_subscriptions"
+)
+
+// Subprotocols is the server preference order offered during the handshake.
+var Subprotocols = []string{SubprotocolMsgpack, SubprotocolJSON}